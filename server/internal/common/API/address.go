@@ -0,0 +1,22 @@
+package API
+
+import (
+	"github.com/alsey89/people-matter/internal/schema"
+)
+
+// Address of a company or location in a request.
+type AddressRequest struct {
+	Street     string `json:"street"     validate:"required,max=1000"`
+	City       string `json:"city"       validate:"required,max=255"`
+	Country    string `json:"country"    validate:"required,max=255"`
+	PostalCode string `json:"postalCode" validate:"required,max=255"`
+}
+
+func (r AddressRequest) ToSchema() schema.Address {
+	return schema.Address{
+		Street:     r.Street,
+		City:       r.City,
+		Country:    r.Country,
+		PostalCode: r.PostalCode,
+	}
+}
@@ -6,3 +6,8 @@ const (
 	ContextTenantID  = "tenantID"
 	ContextCompanyID = "companyID"
 )
+
+// Constants for caching permissions per request
+const (
+	ContextPermissions = "permissions"
+)
@@ -0,0 +1,50 @@
+package API
+
+// Defaults for list endpoints that page their results.
+const (
+	DefaultPerPage = 20
+	MaxPerPage     = 100
+)
+
+/*
+Page and page size of a list request, bound from the "page" and "perPage" query parameters.
+Pages start at 1. Missing values fall back to the first page and DefaultPerPage,
+and perPage is capped at MaxPerPage.
+*/
+type PageQuery struct {
+	Page    int `query:"page"    validate:"omitempty,min=1"`
+	PerPage int `query:"perPage" validate:"omitempty,min=1"`
+}
+
+func (q PageQuery) page() int {
+	if q.Page < 1 {
+		return 1
+	}
+	return q.Page
+}
+
+// Returns the page size, to be used as the query limit.
+func (q PageQuery) Limit() int {
+	switch {
+	case q.PerPage < 1:
+		return DefaultPerPage
+	case q.PerPage > MaxPerPage:
+		return MaxPerPage
+	default:
+		return q.PerPage
+	}
+}
+
+// Returns the number of rows before the page, to be used as the query offset.
+func (q PageQuery) Offset() int {
+	return (q.page() - 1) * q.Limit()
+}
+
+// Returns the Pagination of the page, for a list with total rows.
+func (q PageQuery) Pagination(total int64) *Pagination {
+	return &Pagination{
+		Page:    q.page(),
+		PerPage: q.Limit(),
+		Total:   int(total),
+	}
+}
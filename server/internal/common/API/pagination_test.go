@@ -0,0 +1,22 @@
+package API
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPageQuery(t *testing.T) {
+	q := PageQuery{}
+	assert.Equal(t, DefaultPerPage, q.Limit())
+	assert.Equal(t, 0, q.Offset())
+	assert.Equal(t, &Pagination{Page: 1, PerPage: DefaultPerPage, Total: 5}, q.Pagination(5))
+
+	q = PageQuery{Page: 3, PerPage: 10}
+	assert.Equal(t, 10, q.Limit())
+	assert.Equal(t, 20, q.Offset())
+
+	q = PageQuery{Page: 2, PerPage: 1000}
+	assert.Equal(t, MaxPerPage, q.Limit())
+	assert.Equal(t, MaxPerPage, q.Offset())
+}
@@ -0,0 +1,21 @@
+package API
+
+import (
+	"github.com/alsey89/people-matter/internal/common/errmgr"
+
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+// Logs and translates the error, then writes it to the client as a Response.
+// The request ID is used as the trace ID so that client reports can be matched to logs.
+func RespondWithError(c echo.Context, logger *zap.Logger, err error) error {
+	traceID := c.Response().Header().Get(echo.HeaderXRequestID)
+
+	message, status, apiError := errmgr.LogAndTranslateError(logger, traceID, err)
+
+	return c.JSON(status, Response{
+		Message: message,
+		Error:   apiError,
+	})
+}
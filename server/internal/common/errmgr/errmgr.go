@@ -13,10 +13,56 @@ var (
 	ErrTenant         = errors.New("tenant error")
 	ErrPayload        = errors.New("payload binding or validation error")
 	ErrPermission     = errors.New("invalid or insufficient permissions")
+	ErrQuotaExceeded  = errors.New("company quota exceeded")
 
 	ErrUserNotFound       = errors.New("user not found")
 	ErrInvalidCredentials = errors.New("invalid credentials")
 	ErrEmailUnverified    = errors.New("email not verified")
+	ErrEmailTaken         = errors.New("email already in use")
+	ErrInvalidToken       = errors.New("invalid or expired token")
+
+	ErrAlreadyClockedIn = errors.New("already clocked in")
+	ErrNotClockedIn     = errors.New("not clocked in")
+	ErrPositionNotHeld  = errors.New("position not held at location")
+	ErrTimeLogNotFound  = errors.New("time log not found")
+	ErrTimeLogOverlap   = errors.New("time log overlaps another time log")
+
+	ErrTimesheetNotFound = errors.New("timesheet not found")
+	ErrTimesheetOverlap  = errors.New("timesheet overlaps another timesheet")
+	ErrTimesheetStatus   = errors.New("invalid timesheet status transition")
+	ErrTimesheetLocked   = errors.New("timesheet is submitted or approved")
+
+	ErrTimeRuleNotFound = errors.New("time rule not found")
+	ErrTimeRuleConflict = errors.New("time rule already exists for location and position")
+
+	ErrLocationNotFound = errors.New("location not found")
+	ErrLocationInUse    = errors.New("location has active positions")
+
+	ErrAssignmentNotFound = errors.New("position assignment not found")
+	ErrAssignmentOverlap  = errors.New("position assignment overlaps another assignment of the same position")
+	ErrAssignmentEnded    = errors.New("position assignment has already ended")
+	ErrOffboarded         = errors.New("employee has already been offboarded")
+
+	ErrCompensationNotFound = errors.New("compensation not found")
+	ErrCompensationOverlap  = errors.New("compensation does not start after the latest compensation")
+	ErrCompensationStarted  = errors.New("compensation has already started")
+	ErrSalaryOutOfBand      = errors.New("compensation is outside the salary band of the position")
+	ErrExchangeRateNotFound = errors.New("exchange rate not found")
+
+	ErrPayrollRunNotFound  = errors.New("payroll run not found")
+	ErrPayrollRunOverlap   = errors.New("payroll run overlaps another payroll run of the same interval")
+	ErrPayrollRunLocked    = errors.New("payroll run has already been approved")
+	ErrPayrollRunStatus    = errors.New("payroll run is not in a status allowing this")
+	ErrSecondApprover      = errors.New("payroll run needs a second, different approver")
+	ErrPayrollItemNotFound = errors.New("payroll item not found")
+	ErrPayrollItemLocked   = errors.New("payroll item belongs to an approved payroll run")
+
+	ErrInvitationNotFound = errors.New("invitation not found")
+	ErrInvitationPending  = errors.New("user already has a pending invitation")
+	ErrInvitationStatus   = errors.New("invitation is no longer pending")
+
+	ErrTenantIDInvalid = errors.New("tenant id is invalid or reserved")
+	ErrTenantIDTaken   = errors.New("tenant id already in use")
 )
 
 // Logs the error and returns an APIError that can be returned to the client.
@@ -57,6 +103,14 @@ func LogAndTranslateError(logger *zap.Logger, traceID string, err error) (string
 				Code:    "ERR_CODE_PERMISSION",
 				Status:  http.StatusForbidden,
 			}
+	case errors.Is(err, ErrQuotaExceeded):
+		return "Company quota exceeded",
+			http.StatusForbidden,
+			APIError{
+				TraceID: traceID,
+				Code:    "ERR_CODE_QUOTA_EXCEEDED",
+				Status:  http.StatusForbidden,
+			}
 
 	// ======================
 	// IDENTITY DOMAIN ERRORS
@@ -87,6 +141,327 @@ func LogAndTranslateError(logger *zap.Logger, traceID string, err error) (string
 				Status:  http.StatusUnauthorized,
 			}
 
+	case errors.Is(err, ErrEmailTaken):
+		return "Email already in use",
+			http.StatusConflict,
+			APIError{
+				TraceID: traceID,
+				Code:    "ERR_CODE_EMAIL_TAKEN",
+				Status:  http.StatusConflict,
+			}
+	case errors.Is(err, ErrInvalidToken):
+		return "Invalid or expired token",
+			http.StatusUnauthorized,
+			APIError{
+				TraceID: traceID,
+				Code:    "ERR_CODE_INVALID_TOKEN",
+				Status:  http.StatusUnauthorized,
+			}
+
+	// ======================
+	// TIMEKEEPING DOMAIN ERRORS
+	// ======================
+
+	case errors.Is(err, ErrAlreadyClockedIn):
+		return "Already clocked in",
+			http.StatusConflict,
+			APIError{
+				TraceID: traceID,
+				Code:    "ERR_CODE_ALREADY_CLOCKED_IN",
+				Status:  http.StatusConflict,
+			}
+	case errors.Is(err, ErrNotClockedIn):
+		return "Not clocked in",
+			http.StatusConflict,
+			APIError{
+				TraceID: traceID,
+				Code:    "ERR_CODE_NOT_CLOCKED_IN",
+				Status:  http.StatusConflict,
+			}
+	case errors.Is(err, ErrPositionNotHeld):
+		return "Position not held at location",
+			http.StatusForbidden,
+			APIError{
+				TraceID: traceID,
+				Code:    "ERR_CODE_POSITION_NOT_HELD",
+				Status:  http.StatusForbidden,
+			}
+
+	case errors.Is(err, ErrTimeLogNotFound):
+		return "Time log not found",
+			http.StatusNotFound,
+			APIError{
+				TraceID: traceID,
+				Code:    "ERR_CODE_TIME_LOG_NOT_FOUND",
+				Status:  http.StatusNotFound,
+			}
+	case errors.Is(err, ErrTimeLogOverlap):
+		return "Time log overlaps another time log",
+			http.StatusConflict,
+			APIError{
+				TraceID: traceID,
+				Code:    "ERR_CODE_TIME_LOG_OVERLAP",
+				Status:  http.StatusConflict,
+			}
+	case errors.Is(err, ErrTimesheetNotFound):
+		return "Timesheet not found",
+			http.StatusNotFound,
+			APIError{
+				TraceID: traceID,
+				Code:    "ERR_CODE_TIMESHEET_NOT_FOUND",
+				Status:  http.StatusNotFound,
+			}
+	case errors.Is(err, ErrTimesheetOverlap):
+		return "Timesheet overlaps another timesheet",
+			http.StatusConflict,
+			APIError{
+				TraceID: traceID,
+				Code:    "ERR_CODE_TIMESHEET_OVERLAP",
+				Status:  http.StatusConflict,
+			}
+	case errors.Is(err, ErrTimesheetStatus):
+		return "Invalid timesheet status transition",
+			http.StatusConflict,
+			APIError{
+				TraceID: traceID,
+				Code:    "ERR_CODE_TIMESHEET_STATUS",
+				Status:  http.StatusConflict,
+			}
+	case errors.Is(err, ErrTimesheetLocked):
+		return "Timesheet is locked, reopen it first",
+			http.StatusConflict,
+			APIError{
+				TraceID: traceID,
+				Code:    "ERR_CODE_TIMESHEET_LOCKED",
+				Status:  http.StatusConflict,
+			}
+	case errors.Is(err, ErrTimeRuleNotFound):
+		return "Time rule not found",
+			http.StatusNotFound,
+			APIError{
+				TraceID: traceID,
+				Code:    "ERR_CODE_TIME_RULE_NOT_FOUND",
+				Status:  http.StatusNotFound,
+			}
+	case errors.Is(err, ErrTimeRuleConflict):
+		return "Time rule already exists for location and position",
+			http.StatusConflict,
+			APIError{
+				TraceID: traceID,
+				Code:    "ERR_CODE_TIME_RULE_CONFLICT",
+				Status:  http.StatusConflict,
+			}
+
+	// ======================
+	// LOCATION DOMAIN ERRORS
+	// ======================
+
+	case errors.Is(err, ErrLocationNotFound):
+		return "Location not found",
+			http.StatusNotFound,
+			APIError{
+				TraceID: traceID,
+				Code:    "ERR_CODE_LOCATION_NOT_FOUND",
+				Status:  http.StatusNotFound,
+			}
+	case errors.Is(err, ErrLocationInUse):
+		return "Location still has employees with active positions",
+			http.StatusConflict,
+			APIError{
+				TraceID: traceID,
+				Code:    "ERR_CODE_LOCATION_IN_USE",
+				Status:  http.StatusConflict,
+			}
+
+	// ======================
+	// EMPLOYEE DOMAIN ERRORS
+	// ======================
+
+	case errors.Is(err, ErrAssignmentNotFound):
+		return "Position assignment not found",
+			http.StatusNotFound,
+			APIError{
+				TraceID: traceID,
+				Code:    "ERR_CODE_ASSIGNMENT_NOT_FOUND",
+				Status:  http.StatusNotFound,
+			}
+	case errors.Is(err, ErrAssignmentOverlap):
+		return "Position assignment overlaps another assignment of the same position",
+			http.StatusConflict,
+			APIError{
+				TraceID: traceID,
+				Code:    "ERR_CODE_ASSIGNMENT_OVERLAP",
+				Status:  http.StatusConflict,
+			}
+	case errors.Is(err, ErrAssignmentEnded):
+		return "Position assignment has already ended",
+			http.StatusConflict,
+			APIError{
+				TraceID: traceID,
+				Code:    "ERR_CODE_ASSIGNMENT_ENDED",
+				Status:  http.StatusConflict,
+			}
+	case errors.Is(err, ErrOffboarded):
+		return "Employee has already been offboarded",
+			http.StatusConflict,
+			APIError{
+				TraceID: traceID,
+				Code:    "ERR_CODE_OFFBOARDED",
+				Status:  http.StatusConflict,
+			}
+
+	case errors.Is(err, ErrInvitationNotFound):
+		return "Invitation not found",
+			http.StatusNotFound,
+			APIError{
+				TraceID: traceID,
+				Code:    "ERR_CODE_INVITATION_NOT_FOUND",
+				Status:  http.StatusNotFound,
+			}
+	case errors.Is(err, ErrInvitationPending):
+		return "User already has a pending invitation, resend it instead",
+			http.StatusConflict,
+			APIError{
+				TraceID: traceID,
+				Code:    "ERR_CODE_INVITATION_PENDING",
+				Status:  http.StatusConflict,
+			}
+	case errors.Is(err, ErrInvitationStatus):
+		return "Invitation is no longer pending",
+			http.StatusConflict,
+			APIError{
+				TraceID: traceID,
+				Code:    "ERR_CODE_INVITATION_STATUS",
+				Status:  http.StatusConflict,
+			}
+
+	// ======================
+	// COMPENSATION DOMAIN ERRORS
+	// ======================
+
+	case errors.Is(err, ErrCompensationNotFound):
+		return "Compensation not found",
+			http.StatusNotFound,
+			APIError{
+				TraceID: traceID,
+				Code:    "ERR_CODE_COMPENSATION_NOT_FOUND",
+				Status:  http.StatusNotFound,
+			}
+	case errors.Is(err, ErrCompensationOverlap):
+		return "Compensation must start after the latest compensation",
+			http.StatusConflict,
+			APIError{
+				TraceID: traceID,
+				Code:    "ERR_CODE_COMPENSATION_OVERLAP",
+				Status:  http.StatusConflict,
+			}
+	case errors.Is(err, ErrCompensationStarted):
+		return "Compensation has already started",
+			http.StatusConflict,
+			APIError{
+				TraceID: traceID,
+				Code:    "ERR_CODE_COMPENSATION_STARTED",
+				Status:  http.StatusConflict,
+			}
+	case errors.Is(err, ErrSalaryOutOfBand):
+		return "Compensation is outside the salary band of the position",
+			http.StatusUnprocessableEntity,
+			APIError{
+				TraceID: traceID,
+				Code:    "ERR_CODE_SALARY_OUT_OF_BAND",
+				Status:  http.StatusUnprocessableEntity,
+			}
+	case errors.Is(err, ErrExchangeRateNotFound):
+		return "Exchange rate not found",
+			http.StatusNotFound,
+			APIError{
+				TraceID: traceID,
+				Code:    "ERR_CODE_EXCHANGE_RATE_NOT_FOUND",
+				Status:  http.StatusNotFound,
+			}
+
+	// ======================
+	// PAYROLL DOMAIN ERRORS
+	// ======================
+
+	case errors.Is(err, ErrPayrollRunNotFound):
+		return "Payroll run not found",
+			http.StatusNotFound,
+			APIError{
+				TraceID: traceID,
+				Code:    "ERR_CODE_PAYROLL_RUN_NOT_FOUND",
+				Status:  http.StatusNotFound,
+			}
+	case errors.Is(err, ErrPayrollRunOverlap):
+		return "Payroll run overlaps another payroll run of the same interval",
+			http.StatusConflict,
+			APIError{
+				TraceID: traceID,
+				Code:    "ERR_CODE_PAYROLL_RUN_OVERLAP",
+				Status:  http.StatusConflict,
+			}
+	case errors.Is(err, ErrPayrollRunLocked):
+		return "Payroll run has already been approved",
+			http.StatusConflict,
+			APIError{
+				TraceID: traceID,
+				Code:    "ERR_CODE_PAYROLL_RUN_LOCKED",
+				Status:  http.StatusConflict,
+			}
+	case errors.Is(err, ErrPayrollRunStatus):
+		return "Payroll run is not in a status allowing this",
+			http.StatusConflict,
+			APIError{
+				TraceID: traceID,
+				Code:    "ERR_CODE_PAYROLL_RUN_STATUS",
+				Status:  http.StatusConflict,
+			}
+	case errors.Is(err, ErrSecondApprover):
+		return "Payroll run needs a second, different approver",
+			http.StatusForbidden,
+			APIError{
+				TraceID: traceID,
+				Code:    "ERR_CODE_SECOND_APPROVER",
+				Status:  http.StatusForbidden,
+			}
+	case errors.Is(err, ErrPayrollItemNotFound):
+		return "Payroll item not found",
+			http.StatusNotFound,
+			APIError{
+				TraceID: traceID,
+				Code:    "ERR_CODE_PAYROLL_ITEM_NOT_FOUND",
+				Status:  http.StatusNotFound,
+			}
+	case errors.Is(err, ErrPayrollItemLocked):
+		return "Payroll item belongs to an approved payroll run",
+			http.StatusConflict,
+			APIError{
+				TraceID: traceID,
+				Code:    "ERR_CODE_PAYROLL_ITEM_LOCKED",
+				Status:  http.StatusConflict,
+			}
+
+	// ======================
+	// ONBOARDING DOMAIN ERRORS
+	// ======================
+
+	case errors.Is(err, ErrTenantIDInvalid):
+		return "Tenant ID is invalid or reserved",
+			http.StatusBadRequest,
+			APIError{
+				TraceID: traceID,
+				Code:    "ERR_CODE_TENANT_ID_INVALID",
+				Status:  http.StatusBadRequest,
+			}
+	case errors.Is(err, ErrTenantIDTaken):
+		return "Tenant ID already in use",
+			http.StatusConflict,
+			APIError{
+				TraceID: traceID,
+				Code:    "ERR_CODE_TENANT_ID_TAKEN",
+				Status:  http.StatusConflict,
+			}
+
 	// ======================
 	// DEFAULT FALLBACK
 	// ======================
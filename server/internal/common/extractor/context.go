@@ -21,6 +21,18 @@ func ExtractTenantIdentifierFromContext(c echo.Context) (*string, error) {
 	return &tenantIdentifier, nil
 }
 
+func ExtractCompanyIDFromContext(c echo.Context) (*uint, error) {
+	companyID, ok := c.Get(API.ContextCompanyID).(uint)
+	if !ok {
+		return nil, fmt.Errorf("ExtractCompanyIDFromContext: %s", "error extracting company id from context")
+	}
+	if companyID == 0 {
+		return nil, fmt.Errorf("ExtractCompanyIDFromContext: %s", "no company id in context")
+	}
+
+	return &companyID, nil
+}
+
 func ExtractTokenAndClaimsFromContext(c echo.Context) (*jwt.Token, jwt.MapClaims, error) {
 	user, ok := c.Get("user").(*jwt.Token)
 	if !ok {
@@ -34,3 +46,23 @@ func ExtractTokenAndClaimsFromContext(c echo.Context) (*jwt.Token, jwt.MapClaims
 
 	return user, claims, nil
 }
+
+// Extracts the user ID and company ID from the JWT claims stored in context.
+// JSON numbers are decoded as float64, so the claims are converted back to uint.
+func ExtractUserAndCompanyIDFromContext(c echo.Context) (userID uint, companyID uint, err error) {
+	_, claims, err := ExtractTokenAndClaimsFromContext(c)
+	if err != nil {
+		return 0, 0, fmt.Errorf("ExtractUserAndCompanyIDFromContext: %w", err)
+	}
+
+	id, ok := claims["id"].(float64)
+	if !ok || id == 0 {
+		return 0, 0, fmt.Errorf("ExtractUserAndCompanyIDFromContext: %s", "no user id in claims")
+	}
+	cid, ok := claims["companyId"].(float64)
+	if !ok || cid == 0 {
+		return 0, 0, fmt.Errorf("ExtractUserAndCompanyIDFromContext: %s", "no company id in claims")
+	}
+
+	return uint(id), uint(cid), nil
+}
@@ -39,3 +39,12 @@ func ExtractFromQueryParamAs(c echo.Context, key string, output interface{}) (in
 
 	return output, nil
 }
+
+// Extract the token of an emailed link, e.g. an email confirmation. The links carry it as the "token"
+// query parameter, clients may also send it in the "token" header. Returns "" if there is none.
+func ExtractLinkToken(c echo.Context) string {
+	if token := c.QueryParam("token"); token != "" {
+		return token
+	}
+	return c.Request().Header.Get("token")
+}
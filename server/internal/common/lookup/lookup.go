@@ -0,0 +1,34 @@
+// Package lookup holds the lookups shared by the domains, failing with the errmgr sentinels when nothing is found.
+package lookup
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/alsey89/people-matter/internal/common/errmgr"
+	"github.com/alsey89/people-matter/internal/schema"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+/*
+Returns the employee within the scopes, failing with errmgr.ErrUserNotFound otherwise.
+If lock is set the row is locked, so that changes to the records of one employee run one after another.
+*/
+func FindEmployee(db *gorm.DB, userID uint, lock bool, scopes ...func(*gorm.DB) *gorm.DB) (*schema.User, error) {
+	query := db.Scopes(scopes...).Where("users.id = ?", userID)
+	if lock {
+		query = query.Clauses(clause.Locking{Strength: "UPDATE", Table: clause.Table{Name: "users"}})
+	}
+
+	var user schema.User
+	err := query.First(&user).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("FindEmployee: %w", errmgr.ErrUserNotFound)
+		}
+		return nil, fmt.Errorf("FindEmployee: %w", err)
+	}
+	return &user, nil
+}
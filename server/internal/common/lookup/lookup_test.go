@@ -0,0 +1,26 @@
+package lookup
+
+import (
+	"testing"
+
+	"github.com/alsey89/people-matter/pkg/pgconn/pgtest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func TestFindEmployee(t *testing.T) {
+	db := pgtest.NewDryRunDB(t)
+	statements := pgtest.Capture(t, db)
+	scope := func(db *gorm.DB) *gorm.DB { return db.Where("users.location_id = ?", 3) }
+
+	_, err := FindEmployee(db, 7, false, scope)
+	require.NoError(t, err)
+	assert.Contains(t, statements.Last(), "users.id = 7 AND users.location_id = 3")
+	assert.NotContains(t, statements.Last(), "FOR UPDATE")
+
+	_, err = FindEmployee(db, 7, true)
+	require.NoError(t, err)
+	assert.Contains(t, statements.Last(), `FOR UPDATE OF "users"`)
+}
@@ -0,0 +1,85 @@
+package middleware
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/alsey89/people-matter/internal/common/API"
+	"github.com/alsey89/people-matter/internal/common/errmgr"
+	"github.com/alsey89/people-matter/internal/common/extractor"
+	"github.com/alsey89/people-matter/internal/schema"
+	"github.com/alsey89/people-matter/pkg/pgconn"
+
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+/*
+Returns an echo middleware that rejects requests whose JWT was issued for a different company
+than the resolved tenant, so that a token issued by one tenant is never accepted by another.
+Must run after ResolveTenant and the JWT middleware.
+*/
+func RequireSameTenant(logger *zap.Logger) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			_, claimsCompanyID, err := extractor.ExtractUserAndCompanyIDFromContext(c)
+			if err != nil {
+				return API.RespondWithError(c, logger, fmt.Errorf("RequireSameTenant: %w: %v", errmgr.ErrInvalidToken, err))
+			}
+			tenantCompanyID, err := extractor.ExtractCompanyIDFromContext(c)
+			if err != nil {
+				return API.RespondWithError(c, logger, fmt.Errorf("RequireSameTenant: %w: %v", errmgr.ErrTenant, err))
+			}
+			if claimsCompanyID != *tenantCompanyID {
+				return API.RespondWithError(c, logger, fmt.Errorf("RequireSameTenant: %w: token company does not match tenant", errmgr.ErrTenant))
+			}
+
+			return next(c)
+		}
+	}
+}
+
+/*
+Returns an echo middleware that rejects JWTs of deleted users and JWTs issued before the user's sessions
+were revoked, e.g. on offboarding. Tokens without an "iat" claim are rejected once sessions were revoked.
+Must run after RequireSameTenant.
+*/
+func RequireActiveSession(pg *pgconn.Module, logger *zap.Logger) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			_, claims, err := extractor.ExtractTokenAndClaimsFromContext(c)
+			if err != nil {
+				return API.RespondWithError(c, logger, fmt.Errorf("RequireActiveSession: %w: %v", errmgr.ErrInvalidToken, err))
+			}
+			userID, _, err := extractor.ExtractUserAndCompanyIDFromContext(c)
+			if err != nil {
+				return API.RespondWithError(c, logger, fmt.Errorf("RequireActiveSession: %w: %v", errmgr.ErrInvalidToken, err))
+			}
+
+			db, err := pg.GetScopedDB(c.Request().Context())
+			if err != nil {
+				return API.RespondWithError(c, logger, fmt.Errorf("RequireActiveSession: %w: %v", errmgr.ErrTenant, err))
+			}
+
+			var user schema.User
+			err = db.Select("id", "sessions_revoked_at").Where("id = ?", userID).First(&user).Error
+			if err != nil {
+				if errors.Is(err, gorm.ErrRecordNotFound) {
+					return API.RespondWithError(c, logger, fmt.Errorf("RequireActiveSession: %w: user no longer exists", errmgr.ErrInvalidToken))
+				}
+				return API.RespondWithError(c, logger, fmt.Errorf("RequireActiveSession: %w", err))
+			}
+
+			if user.SessionsRevokedAt != nil {
+				// iat has second precision, so tokens issued in the second of the revocation are rejected as well
+				issuedAt, ok := claims["iat"].(float64)
+				if !ok || int64(issuedAt) <= user.SessionsRevokedAt.Unix() {
+					return API.RespondWithError(c, logger, fmt.Errorf("RequireActiveSession: %w: session revoked", errmgr.ErrInvalidToken))
+				}
+			}
+
+			return next(c)
+		}
+	}
+}
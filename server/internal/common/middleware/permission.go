@@ -0,0 +1,126 @@
+package middleware
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/alsey89/people-matter/internal/common/API"
+	"github.com/alsey89/people-matter/internal/common/errmgr"
+	"github.com/alsey89/people-matter/internal/common/extractor"
+	"github.com/alsey89/people-matter/internal/common/rbac"
+	"github.com/alsey89/people-matter/internal/schema"
+	"github.com/alsey89/people-matter/pkg/pgconn"
+
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+// Name of the path or query parameter that puts a request in the context of a location.
+const LocationParam = "locationId"
+
+/*
+Returns an echo middleware that rejects the request with errmgr.ErrPermission unless one of the
+caller's active positions grants the permission.
+If the request carries a location in the locationId path or query parameter, the permission must be
+granted at that location. Otherwise any grant passes, and handlers listing data are expected to
+restrict their queries with rbac.PermissionSet.ScopeLocations or ScopeUsers.
+Must run after ResolveTenant, the JWT middleware and RequireSameTenant.
+*/
+func RequirePermission(pg *pgconn.Module, logger *zap.Logger, permission string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			permissions, err := LoadPermissions(c, pg)
+			if err != nil {
+				return API.RespondWithError(c, logger, fmt.Errorf("RequirePermission: %w", err))
+			}
+
+			locationID, err := locationFromRequest(c)
+			if err != nil {
+				return API.RespondWithError(c, logger, fmt.Errorf("RequirePermission: %w: %v", errmgr.ErrPayload, err))
+			}
+
+			if locationID != nil {
+				if !permissions.HasAt(permission, *locationID) {
+					return API.RespondWithError(c, logger, fmt.Errorf("RequirePermission: %w: missing %s at location %d", errmgr.ErrPermission, permission, *locationID))
+				}
+			} else if !permissions.Has(permission) {
+				return API.RespondWithError(c, logger, fmt.Errorf("RequirePermission: %w: missing %s", errmgr.ErrPermission, permission))
+			}
+
+			return next(c)
+		}
+	}
+}
+
+/*
+Returns the permissions granted to the caller by the positions they currently hold, i.e. UserPosition
+rows that have started and have no EndedAt. Each permission applies at the location of the assignment,
+or at every location if the position is company wide.
+The result is cached in context under API.ContextPermissions, so stacked middlewares and handlers load
+it once per request.
+*/
+func LoadPermissions(c echo.Context, pg *pgconn.Module) (rbac.PermissionSet, error) {
+	if permissions, ok := c.Get(API.ContextPermissions).(rbac.PermissionSet); ok {
+		return permissions, nil
+	}
+
+	userID, _, err := extractor.ExtractUserAndCompanyIDFromContext(c)
+	if err != nil {
+		return nil, fmt.Errorf("LoadPermissions: %w: %v", errmgr.ErrPermission, err)
+	}
+
+	db, err := pg.GetScopedDB(c.Request().Context())
+	if err != nil {
+		return nil, fmt.Errorf("LoadPermissions: %w: %v", errmgr.ErrTenant, err)
+	}
+
+	var grants []struct {
+		Name        string
+		LocationID  uint
+		CompanyWide bool
+	}
+	err = db.Model(&schema.UserPosition{}).
+		Select("DISTINCT permissions.name, user_positions.location_id, positions.company_wide").
+		Joins("JOIN positions ON positions.id = user_positions.position_id"+
+			" AND positions.company_id = user_positions.company_id"+
+			" AND positions.deleted_at IS NULL").
+		Joins("JOIN position_permissions ON position_permissions.position_id = user_positions.position_id"+
+			" AND position_permissions.company_id = user_positions.company_id"+
+			" AND position_permissions.deleted_at IS NULL").
+		Joins("JOIN permissions ON permissions.id = position_permissions.permission_id"+
+			" AND permissions.company_id = user_positions.company_id"+
+			" AND permissions.deleted_at IS NULL").
+		Where("user_positions.user_id = ? AND ?", userID, schema.ActiveUserPositions("user_positions", time.Now())).
+		Scan(&grants).Error
+	if err != nil {
+		return nil, fmt.Errorf("LoadPermissions: %w", err)
+	}
+
+	permissions := make(rbac.PermissionSet, len(grants))
+	for _, grant := range grants {
+		permissions.Add(grant.Name, grant.LocationID, grant.CompanyWide)
+	}
+	c.Set(API.ContextPermissions, permissions)
+
+	return permissions, nil
+}
+
+// Returns the location the request is about, from the locationId path parameter or query parameter.
+func locationFromRequest(c echo.Context) (*uint, error) {
+	value := c.Param(LocationParam)
+	if value == "" {
+		value = c.QueryParam(LocationParam)
+	}
+	if value == "" {
+		return nil, nil
+	}
+
+	locationID, err := strconv.ParseUint(value, 10, 64)
+	if err != nil || locationID == 0 {
+		return nil, fmt.Errorf("locationFromRequest: invalid %s %q", LocationParam, value)
+	}
+	id := uint(locationID)
+
+	return &id, nil
+}
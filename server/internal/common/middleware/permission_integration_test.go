@@ -0,0 +1,92 @@
+//go:build integration
+
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/alsey89/people-matter/internal/common/rbac"
+	"github.com/alsey89/people-matter/internal/schema"
+	"github.com/alsey89/people-matter/pkg/pgconn"
+	"github.com/alsey89/people-matter/pkg/pgconn/pgtest"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+/*
+Runs against an ephemeral postgres, see pgtest.StartPostgres.
+Run with: go test -tags integration ./internal/common/middleware/
+*/
+
+const integrationScope = "middleware_integration"
+
+func TestLoadPermissionsIntegration(t *testing.T) {
+	pgtest.StartPostgres(t, 54331, integrationScope)
+	m := pgconn.NewPGConn(integrationScope, zap.NewNop())
+	migrations, err := pgconn.LoadMigrations(schema.Migrations, schema.MigrationsDir)
+	require.NoError(t, err)
+	_, err = m.MigrateUp(migrations, false)
+	require.NoError(t, err)
+
+	company := schema.Company{TenantID: "acme", Name: "Acme", Email: "owner@acme.test"}
+	require.NoError(t, m.GetDB().Create(&company).Error)
+	ctx := pgconn.WithCompanyID(context.Background(), company.ID)
+	db, err := m.GetScopedDB(ctx)
+	require.NoError(t, err)
+
+	location := schema.Location{Name: "Head office"}
+	require.NoError(t, db.Create(&location).Error)
+	position := schema.Position{Name: "Clerk"}
+	require.NoError(t, db.Create(&position).Error)
+	permission := schema.Permission{Name: rbac.EmployeesRead}
+	require.NoError(t, db.Create(&permission).Error)
+	require.NoError(t, db.Create(&schema.PositionPermission{PositionID: position.ID, PermissionID: permission.ID}).Error)
+
+	// Returns the permissions loaded for a user whose only assignment runs from startedAt until endedAt.
+	load := func(email string, startedAt time.Time, endedAt *time.Time) rbac.PermissionSet {
+		user := schema.User{Name: email, Email: email}
+		require.NoError(t, db.Create(&user).Error)
+		require.NoError(t, db.Create(&schema.UserPosition{
+			UserID:     user.ID,
+			PositionID: position.ID,
+			LocationID: location.ID,
+			StartedAt:  startedAt,
+			EndedAt:    endedAt,
+		}).Error)
+
+		c := echo.New().NewContext(httptest.NewRequest(http.MethodGet, "/", nil).WithContext(ctx), httptest.NewRecorder())
+		c.Set("user", &jwt.Token{Claims: jwt.MapClaims{"id": float64(user.ID), "companyId": float64(company.ID)}})
+		permissions, err := LoadPermissions(c, m)
+		require.NoError(t, err)
+		return permissions
+	}
+
+	lastMonth := time.Now().AddDate(0, -1, 0)
+	nextMonth := time.Now().AddDate(0, 1, 0)
+	yesterday := time.Now().AddDate(0, 0, -1)
+
+	t.Run("Open", func(t *testing.T) {
+		assert.True(t, load("open@acme.test", lastMonth, nil).HasAt(rbac.EmployeesRead, location.ID))
+	})
+
+	t.Run("EndingInTheFuture", func(t *testing.T) {
+		assert.True(t, load("ending@acme.test", lastMonth, &nextMonth).HasAt(rbac.EmployeesRead, location.ID),
+			"positions keep granting their permissions until they end")
+	})
+
+	t.Run("Ended", func(t *testing.T) {
+		assert.False(t, load("ended@acme.test", lastMonth, &yesterday).Has(rbac.EmployeesRead))
+	})
+
+	t.Run("StartingInTheFuture", func(t *testing.T) {
+		assert.False(t, load("starting@acme.test", nextMonth, nil).Has(rbac.EmployeesRead))
+	})
+}
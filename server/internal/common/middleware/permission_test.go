@@ -0,0 +1,104 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/alsey89/people-matter/internal/common/API"
+	"github.com/alsey89/people-matter/internal/common/errmgr"
+	"github.com/alsey89/people-matter/internal/common/rbac"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// Returns a context of a request to target whose caller holds the permissions, as cached by LoadPermissions.
+func newPermissionContext(target string, permissions rbac.PermissionSet) (echo.Context, *httptest.ResponseRecorder) {
+	rec := httptest.NewRecorder()
+	c := echo.New().NewContext(httptest.NewRequest(http.MethodGet, target, nil), rec)
+	c.Set(API.ContextPermissions, permissions)
+	return c, rec
+}
+
+// Runs RequirePermission on the context, reporting whether the request got through to the handler.
+func requirePermission(t *testing.T, c echo.Context, permission string) bool {
+	called := false
+	next := func(c echo.Context) error {
+		called = true
+		return c.NoContent(http.StatusOK)
+	}
+	require.NoError(t, RequirePermission(nil, zap.NewNop(), permission)(next)(c))
+	return called
+}
+
+func TestRequirePermissionCompanyWide(t *testing.T) {
+	permissions := rbac.PermissionSet{}
+	permissions.Add(rbac.PayrollRead, 1, true)
+
+	c, _ := newPermissionContext("/", permissions)
+	assert.True(t, requirePermission(t, c, rbac.PayrollRead))
+
+	c, _ = newPermissionContext("/?locationId=7", permissions)
+	assert.True(t, requirePermission(t, c, rbac.PayrollRead), "company wide grants apply at every location")
+}
+
+func TestRequirePermissionLocation(t *testing.T) {
+	permissions := rbac.PermissionSet{}
+	permissions.Add(rbac.EmployeesRead, 1, false)
+
+	c, _ := newPermissionContext("/", permissions)
+	assert.True(t, requirePermission(t, c, rbac.EmployeesRead), "without a location any grant passes")
+
+	c, _ = newPermissionContext("/?locationId=1", permissions)
+	assert.True(t, requirePermission(t, c, rbac.EmployeesRead))
+
+	c, rec := newPermissionContext("/?locationId=2", permissions)
+	assert.False(t, requirePermission(t, c, rbac.EmployeesRead))
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+
+	c, _ = newPermissionContext("/", permissions)
+	c.SetParamNames(LocationParam)
+	c.SetParamValues("1")
+	assert.True(t, requirePermission(t, c, rbac.EmployeesRead))
+
+	c, rec = newPermissionContext("/?locationId=1", permissions)
+	c.SetParamNames(LocationParam)
+	c.SetParamValues("2")
+	assert.False(t, requirePermission(t, c, rbac.EmployeesRead), "the path parameter takes precedence")
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+
+	c, rec = newPermissionContext("/?locationId=abc", permissions)
+	assert.False(t, requirePermission(t, c, rbac.EmployeesRead))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestRequirePermissionMissing(t *testing.T) {
+	permissions := rbac.PermissionSet{}
+	permissions.Add(rbac.EmployeesRead, 1, true)
+
+	c, rec := newPermissionContext("/", permissions)
+	assert.False(t, requirePermission(t, c, rbac.EmployeesWrite))
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+
+	c, rec = newPermissionContext("/", rbac.PermissionSet{})
+	assert.False(t, requirePermission(t, c, rbac.EmployeesRead))
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+}
+
+func TestLoadPermissions(t *testing.T) {
+	permissions := rbac.PermissionSet{}
+	permissions.Add(rbac.EmployeesRead, 1, false)
+
+	c, _ := newPermissionContext("/", permissions)
+	loaded, err := LoadPermissions(c, nil)
+	require.NoError(t, err)
+	assert.Equal(t, permissions, loaded, "cached for the request")
+
+	// without a token there is no caller to load permissions for
+	c = echo.New().NewContext(httptest.NewRequest(http.MethodGet, "/", nil), httptest.NewRecorder())
+	_, err = LoadPermissions(c, nil)
+	assert.ErrorIs(t, err, errmgr.ErrPermission)
+}
@@ -0,0 +1,160 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/alsey89/people-matter/internal/common/API"
+	"github.com/alsey89/people-matter/internal/common/errmgr"
+	"github.com/alsey89/people-matter/internal/common/util"
+	"github.com/alsey89/people-matter/internal/schema"
+	"github.com/alsey89/people-matter/pkg/pgconn"
+
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+/*
+Returns an echo middleware that resolves the tenant of the request and loads its company.
+The tenant identifier is read from the X-Tenant header, falling back to the subdomain of the
+request host under clientDomain, i.e. the <tenant>.<domain> form built by util.PathToFullURL.
+On success the tenant identifier and company ID are stored in context under
+API.ContextTenantID and API.ContextCompanyID, and the company ID is attached to the
+request context for pgconn.Module.GetScopedDB. Requests that cannot be resolved are rejected
+with errmgr.ErrTenant.
+The rest of the chain runs inside pgconn.Module.WithCompanyTransaction, which is rolled back
+if the handler fails or responds with an error status. The response is buffered and only sent once
+the transaction has committed, so that a failed commit is reported with an error instead of the
+response of the rolled back handler.
+*/
+func ResolveTenant(pg *pgconn.Module, logger *zap.Logger, clientDomain string) echo.MiddlewareFunc {
+	db := pg.GetDB()
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			tenantID, err := tenantIdentifierFromRequest(c, clientDomain)
+			if err != nil {
+				return API.RespondWithError(c, logger, fmt.Errorf("ResolveTenant: %w: %v", errmgr.ErrTenant, err))
+			}
+
+			var company schema.Company
+			err = db.Where("tenant_id = ?", tenantID).First(&company).Error
+			if err != nil {
+				if errors.Is(err, gorm.ErrRecordNotFound) {
+					return API.RespondWithError(c, logger, fmt.Errorf("ResolveTenant: %w: unknown tenant %s", errmgr.ErrTenant, tenantID))
+				}
+				return API.RespondWithError(c, logger, fmt.Errorf("ResolveTenant: %w", err))
+			}
+
+			c.Set(API.ContextTenantID, company.TenantID)
+			c.Set(API.ContextCompanyID, company.ID)
+			ctx := pgconn.WithCompanyID(c.Request().Context(), company.ID)
+
+			response := c.Response()
+			writer := response.Writer
+			buffer := newBufferedResponseWriter(writer.Header())
+			response.Writer = buffer
+
+			var handlerErr error
+			err = pg.WithCompanyTransaction(ctx, func(ctx context.Context) error {
+				c.SetRequest(c.Request().WithContext(ctx))
+
+				handlerErr = next(c)
+				if handlerErr != nil || response.Status >= http.StatusBadRequest {
+					return errRollback
+				}
+				return nil
+			})
+			response.Writer = writer
+			if err != nil && !errors.Is(err, errRollback) {
+				// the buffered response is dropped, the client never sees the rolled back result
+				response.Status, response.Size, response.Committed = http.StatusOK, 0, false
+				return API.RespondWithError(c, logger, fmt.Errorf("ResolveTenant: %w", err))
+			}
+
+			err = buffer.flushTo(writer)
+			if err != nil {
+				logger.Error("ResolveTenant: error writing response", zap.Error(err))
+			}
+
+			return handlerErr
+		}
+	}
+}
+
+// Signals WithCompanyTransaction to roll back without surfacing an error to the client.
+var errRollback = errors.New("rollback")
+
+// Holds a response in memory until it is flushed to the client, see ResolveTenant.
+type bufferedResponseWriter struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+// Returns a buffer starting out with a copy of header, e.g. the CORS headers set by earlier middlewares.
+func newBufferedResponseWriter(header http.Header) *bufferedResponseWriter {
+	return &bufferedResponseWriter{header: header.Clone()}
+}
+
+func (w *bufferedResponseWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *bufferedResponseWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+}
+
+func (w *bufferedResponseWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	return w.body.Write(b)
+}
+
+// Streaming handlers flush as they go, their response is still only sent once flushTo is called.
+func (w *bufferedResponseWriter) Flush() {}
+
+// Writes the buffered response to w, if one was written at all.
+func (w *bufferedResponseWriter) flushTo(writer http.ResponseWriter) error {
+	if w.status == 0 {
+		return nil
+	}
+
+	header := writer.Header()
+	for key := range header {
+		delete(header, key)
+	}
+	for key, values := range w.header {
+		header[key] = values
+	}
+
+	writer.WriteHeader(w.status)
+	_, err := writer.Write(w.body.Bytes())
+	if err != nil {
+		return fmt.Errorf("flushTo: %w", err)
+	}
+	return nil
+}
+
+func tenantIdentifierFromRequest(c echo.Context, clientDomain string) (string, error) {
+	if header := c.Request().Header.Get(API.HeaderTenantID); header != "" {
+		if !util.IsValidSubdomain(header) {
+			return "", fmt.Errorf("tenantIdentifierFromRequest: invalid %s header %s", API.HeaderTenantID, header)
+		}
+		return header, nil
+	}
+
+	subdomain, err := util.SubdomainFromHost(c.Request().Host, clientDomain)
+	if err != nil {
+		return "", fmt.Errorf("tenantIdentifierFromRequest: %w", err)
+	}
+
+	return *subdomain, nil
+}
@@ -0,0 +1,39 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBufferedResponseWriter(t *testing.T) {
+	rec := httptest.NewRecorder()
+	rec.Header().Set("Vary", "Origin")
+	c := echo.New().NewContext(httptest.NewRequest(http.MethodPost, "/", nil), rec)
+
+	buffer := newBufferedResponseWriter(rec.Header())
+	c.Response().Writer = buffer
+	require.NoError(t, c.JSON(http.StatusCreated, map[string]string{"message": "created"}))
+	assert.Empty(t, rec.Body.String(), "held back until flushed")
+	assert.Empty(t, rec.Header().Get(echo.HeaderContentType))
+
+	require.NoError(t, buffer.flushTo(rec))
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	assert.JSONEq(t, `{"message": "created"}`, rec.Body.String())
+	assert.Equal(t, echo.MIMEApplicationJSON, rec.Header().Get(echo.HeaderContentType))
+	assert.Equal(t, "Origin", rec.Header().Get("Vary"), "headers of earlier middlewares are kept")
+}
+
+func TestBufferedResponseWriterEmpty(t *testing.T) {
+	rec := httptest.NewRecorder()
+	rec.Header().Set("Vary", "Origin")
+
+	// handlers returning an error leave the response to the error handler
+	require.NoError(t, newBufferedResponseWriter(rec.Header()).flushTo(rec))
+	assert.Empty(t, rec.Body.String())
+	assert.Equal(t, "Origin", rec.Header().Get("Vary"))
+}
@@ -0,0 +1,138 @@
+package money
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+/*
+Money fields name the field holding their currency with the "currency" tag setting:
+
+	Amount   money.Money `gorm:"not null;currency:Currency"`
+	Currency string      `gorm:"not null"`
+
+The currency field may be a string or a *string, and Money fields may be pointers.
+*/
+const currencyTag = "CURRENCY"
+
+var moneyType = reflect.TypeOf(Money{})
+
+type currencyPair struct {
+	money    *schema.Field
+	currency *schema.Field
+}
+
+/*
+Registers callbacks on db that keep the currency of Money fields in line with the currency field they name:
+after queries the currency is copied into the Money, before creates a Money in another currency is rejected
+with ErrCurrencyMismatch. Must be called once on the root connection.
+*/
+func RegisterCallbacks(db *gorm.DB) error {
+	err := db.Callback().Query().After("gorm:query").Register("money:fill_currency", fillCurrencies)
+	if err != nil {
+		return fmt.Errorf("RegisterCallbacks: %w", err)
+	}
+	err = db.Callback().Create().Before("gorm:create").Register("money:check_currency", checkCurrencies)
+	if err != nil {
+		return fmt.Errorf("RegisterCallbacks: %w", err)
+	}
+	return nil
+}
+
+func fillCurrencies(db *gorm.DB) {
+	if db.Error != nil {
+		return
+	}
+	eachPair(db, func(ctx context.Context, pair currencyPair, model reflect.Value, money *Money) {
+		money.Currency = currencyOf(ctx, pair.currency, model)
+	})
+}
+
+func checkCurrencies(db *gorm.DB) {
+	if db.Error != nil {
+		return
+	}
+	eachPair(db, func(ctx context.Context, pair currencyPair, model reflect.Value, money *Money) {
+		currency := currencyOf(ctx, pair.currency, model)
+		if money.Currency != "" && money.Currency != currency {
+			_ = db.AddError(fmt.Errorf("%w: %s is %s, %s is %s", ErrCurrencyMismatch, pair.money.Name, money.Currency, pair.currency.Name, currency))
+		}
+	})
+}
+
+// Calls fn for every set Money field with a currency field of every model in the statement.
+func eachPair(db *gorm.DB, fn func(ctx context.Context, pair currencyPair, model reflect.Value, money *Money)) {
+	if db.Statement.Schema == nil {
+		return
+	}
+	pairs := currencyPairs(db.Statement.Schema)
+	if len(pairs) == 0 {
+		return
+	}
+
+	ctx := db.Statement.Context
+	apply := func(model reflect.Value) {
+		model = reflect.Indirect(model)
+		if model.Kind() != reflect.Struct {
+			return
+		}
+		for _, pair := range pairs {
+			value := pair.money.ReflectValueOf(ctx, model)
+			if value.Kind() == reflect.Ptr {
+				if value.IsNil() {
+					continue
+				}
+				value = value.Elem()
+			}
+			fn(ctx, pair, model, value.Addr().Interface().(*Money))
+		}
+	}
+
+	switch value := db.Statement.ReflectValue; value.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < value.Len(); i++ {
+			apply(value.Index(i))
+		}
+	case reflect.Struct, reflect.Ptr:
+		apply(value)
+	}
+}
+
+func currencyPairs(s *schema.Schema) []currencyPair {
+	var pairs []currencyPair
+	for _, field := range s.Fields {
+		fieldType := field.FieldType
+		if fieldType.Kind() == reflect.Ptr {
+			fieldType = fieldType.Elem()
+		}
+		if fieldType != moneyType {
+			continue
+		}
+		name, ok := field.TagSettings[currencyTag]
+		if !ok {
+			continue
+		}
+		if currency := s.LookUpField(name); currency != nil {
+			pairs = append(pairs, currencyPair{money: field, currency: currency})
+		}
+	}
+	return pairs
+}
+
+func currencyOf(ctx context.Context, field *schema.Field, model reflect.Value) string {
+	value, zero := field.ValueOf(ctx, model)
+	if zero {
+		return ""
+	}
+	switch v := value.(type) {
+	case string:
+		return v
+	case *string:
+		return *v
+	}
+	return ""
+}
@@ -0,0 +1,53 @@
+package money
+
+import (
+	"testing"
+
+	"github.com/alsey89/people-matter/pkg/pgconn/pgtest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+type record struct {
+	ID       uint
+	Amount   Money  `gorm:"currency:Currency"`
+	Limit    *Money `gorm:"currency:LimitCurrency"`
+	Currency string
+	// optional like Position.SalaryCurrency
+	LimitCurrency *string
+}
+
+// Opens a dry run DB with the currency callbacks, see pgtest.NewDryRunDB.
+func newDryRunDB(t *testing.T) *gorm.DB {
+	db := pgtest.NewDryRunDB(t)
+	require.NoError(t, RegisterCallbacks(db))
+	return db
+}
+
+func TestFillCurrencies(t *testing.T) {
+	db := newDryRunDB(t)
+	eur := "EUR"
+	limit := New(5, "")
+
+	// dry run does not scan rows, so the callback sees the records as they are
+	records := []record{
+		{Amount: New(1050, ""), Currency: "USD", Limit: &limit, LimitCurrency: &eur},
+		{Amount: New(1, ""), Currency: "JPY"},
+	}
+	require.NoError(t, db.Find(&records).Error)
+
+	assert.Equal(t, New(1050, "USD"), records[0].Amount)
+	assert.Equal(t, New(5, "EUR"), *records[0].Limit)
+	assert.Equal(t, New(1, "JPY"), records[1].Amount)
+	assert.Nil(t, records[1].Limit)
+}
+
+func TestCheckCurrencies(t *testing.T) {
+	db := newDryRunDB(t)
+
+	assert.NoError(t, db.Create(&record{Amount: New(1, "USD"), Currency: "USD"}).Error)
+	assert.NoError(t, db.Create(&record{Amount: New(1, ""), Currency: "USD"}).Error)
+	assert.ErrorIs(t, db.Create(&record{Amount: New(1, "EUR"), Currency: "USD"}).Error, ErrCurrencyMismatch)
+}
@@ -0,0 +1,253 @@
+/*
+Package money represents amounts exactly, as an integer number of minor units of an ISO 4217 currency,
+e.g. 1050 USD cents for $10.50. Amounts never go through float64.
+
+Results of arithmetic that does not fit the minor unit, e.g. prorating or converting, are rounded to the
+minor unit of the currency, half away from zero: 0.125 USD becomes 0.13 USD, 0.5 JPY becomes 1 JPY.
+*/
+package money
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrCurrency         = errors.New("unknown currency")
+	ErrCurrencyMismatch = errors.New("currencies do not match")
+	ErrFormat           = errors.New("invalid amount")
+	ErrPrecision        = errors.New("amount has more decimals than its currency")
+	ErrOverflow         = errors.New("amount out of range")
+)
+
+var decimalPattern = regexp.MustCompile(`^-?[0-9]+(\.[0-9]+)?$`)
+
+// An amount in minor units of an ISO 4217 currency.
+// In the database only Minor is stored, see Value. Currency is filled from the model's currency column, see RegisterCallbacks.
+type Money struct {
+	Minor    int64
+	Currency string
+}
+
+func New(minor int64, currency string) Money {
+	return Money{Minor: minor, Currency: currency}
+}
+
+// Parses a decimal amount in major units, e.g. "10.50", failing with ErrPrecision if it has more decimals than the currency.
+func Parse(amount string, currency string) (Money, error) {
+	exponent, ok := Exponent(currency)
+	if !ok {
+		return Money{}, fmt.Errorf("Parse: %w: %q", ErrCurrency, currency)
+	}
+	amount = strings.TrimSpace(amount)
+	if !decimalPattern.MatchString(amount) {
+		return Money{}, fmt.Errorf("Parse: %w: %q", ErrFormat, amount)
+	}
+	if i := strings.IndexByte(amount, '.'); i >= 0 && len(amount)-i-1 > exponent {
+		return Money{}, fmt.Errorf("Parse: %w: %q in %s", ErrPrecision, amount, currency)
+	}
+
+	value, _ := new(big.Rat).SetString(amount)
+	m, err := FromRat(value, currency)
+	if err != nil {
+		return Money{}, fmt.Errorf("Parse: %w", err)
+	}
+	return m, nil
+}
+
+// Returns the amount in major units, rounded to the minor unit of the currency.
+func FromRat(value *big.Rat, currency string) (Money, error) {
+	minor := new(big.Rat).Mul(value, scale(exponentOf(currency)))
+	rounded := roundHalfAwayFromZero(minor)
+	if !rounded.IsInt64() {
+		return Money{}, fmt.Errorf("FromRat: %w: %s", ErrOverflow, value.FloatString(exponentOf(currency)))
+	}
+	return Money{Minor: rounded.Int64(), Currency: currency}, nil
+}
+
+// Returns the amount in major units.
+func (m Money) Rat() *big.Rat {
+	return new(big.Rat).Quo(new(big.Rat).SetInt64(m.Minor), scale(exponentOf(m.Currency)))
+}
+
+func (m Money) IsZero() bool {
+	return m.Minor == 0
+}
+
+func (m Money) IsNegative() bool {
+	return m.Minor < 0
+}
+
+func (m Money) Neg() Money {
+	return Money{Minor: -m.Minor, Currency: m.Currency}
+}
+
+func (m Money) Add(other Money) (Money, error) {
+	if m.Currency != other.Currency {
+		return Money{}, fmt.Errorf("Add: %w: %s and %s", ErrCurrencyMismatch, m.Currency, other.Currency)
+	}
+	sum := m.Minor + other.Minor
+	if (other.Minor > 0 && sum < m.Minor) || (other.Minor < 0 && sum > m.Minor) {
+		return Money{}, fmt.Errorf("Add: %w", ErrOverflow)
+	}
+	return Money{Minor: sum, Currency: m.Currency}, nil
+}
+
+func (m Money) Sub(other Money) (Money, error) {
+	return m.Add(other.Neg())
+}
+
+// Compares the amounts, -1 if m is less than other, 0 if equal and 1 if greater.
+func (m Money) Cmp(other Money) (int, error) {
+	if m.Currency != other.Currency {
+		return 0, fmt.Errorf("Cmp: %w: %s and %s", ErrCurrencyMismatch, m.Currency, other.Currency)
+	}
+	switch {
+	case m.Minor < other.Minor:
+		return -1, nil
+	case m.Minor > other.Minor:
+		return 1, nil
+	default:
+		return 0, nil
+	}
+}
+
+// Returns m * num / den rounded to the minor unit, e.g. to prorate an amount.
+func (m Money) Scale(num int64, den int64) (Money, error) {
+	if den == 0 {
+		return Money{}, fmt.Errorf("Scale: %w: zero denominator", ErrFormat)
+	}
+	return m.Mul(big.NewRat(num, den))
+}
+
+// Returns m * factor rounded to the minor unit.
+func (m Money) Mul(factor *big.Rat) (Money, error) {
+	minor := new(big.Rat).Mul(new(big.Rat).SetInt64(m.Minor), factor)
+	rounded := roundHalfAwayFromZero(minor)
+	if !rounded.IsInt64() {
+		return Money{}, fmt.Errorf("Mul: %w", ErrOverflow)
+	}
+	return Money{Minor: rounded.Int64(), Currency: m.Currency}, nil
+}
+
+// Converts the amount to another currency at rate units of the currency per unit of m's currency.
+func (m Money) Convert(rate *big.Rat, currency string) (Money, error) {
+	value := new(big.Rat).Mul(m.Rat(), rate)
+	converted, err := FromRat(value, currency)
+	if err != nil {
+		return Money{}, fmt.Errorf("Convert: %w", err)
+	}
+	return converted, nil
+}
+
+// Formats the amount in major units without the currency, e.g. "10.50".
+func (m Money) Decimal() string {
+	exponent := exponentOf(m.Currency)
+	if exponent == 0 {
+		return strconv.FormatInt(m.Minor, 10)
+	}
+
+	digits := strconv.FormatInt(m.Minor, 10)
+	sign := ""
+	if m.Minor < 0 {
+		sign, digits = "-", digits[1:]
+	}
+	if len(digits) <= exponent {
+		digits = strings.Repeat("0", exponent-len(digits)+1) + digits
+	}
+	return sign + digits[:len(digits)-exponent] + "." + digits[len(digits)-exponent:]
+}
+
+// Formats the amount with its currency, e.g. "10.50 USD".
+func (m Money) String() string {
+	return m.Decimal() + " " + m.Currency
+}
+
+// ! JSON ---------------------------------------------------------------
+
+type jsonMoney struct {
+	Amount   json.Number `json:"amount"`
+	Currency string      `json:"currency"`
+}
+
+// Encodes the amount as a decimal string, so that clients do not lose precision parsing it as a float.
+func (m Money) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Amount   string `json:"amount"`
+		Currency string `json:"currency"`
+	}{m.Decimal(), m.Currency})
+}
+
+// Decodes {"amount": "10.50", "currency": "USD"}. The amount may also be a JSON number, it is parsed exactly.
+func (m *Money) UnmarshalJSON(data []byte) error {
+	var decoded jsonMoney
+	err := json.Unmarshal(data, &decoded)
+	if err != nil {
+		return fmt.Errorf("UnmarshalJSON: %w: %v", ErrFormat, err)
+	}
+
+	parsed, err := Parse(decoded.Amount.String(), decoded.Currency)
+	if err != nil {
+		return fmt.Errorf("UnmarshalJSON: %w", err)
+	}
+	*m = parsed
+	return nil
+}
+
+// ! Database ---------------------------------------------------------------
+
+func (Money) GormDataType() string {
+	return "bigint"
+}
+
+// Stores the minor units, the currency is stored in a separate column of the model.
+func (m Money) Value() (driver.Value, error) {
+	return m.Minor, nil
+}
+
+// Scans the minor units. The currency is set by the query callback, see RegisterCallbacks.
+func (m *Money) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		m.Minor = 0
+	case int64:
+		m.Minor = v
+	case []byte:
+		return m.Scan(string(v))
+	case string:
+		minor, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return fmt.Errorf("Scan: %w: %v", ErrFormat, err)
+		}
+		m.Minor = minor
+	default:
+		return fmt.Errorf("Scan: %w: unsupported type %T", ErrFormat, value)
+	}
+	return nil
+}
+
+// ! Helpers ---------------------------------------------------------------
+
+// 10^exponent
+func scale(exponent int) *big.Rat {
+	return new(big.Rat).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(exponent)), nil))
+}
+
+func roundHalfAwayFromZero(value *big.Rat) *big.Int {
+	num := new(big.Int).Abs(value.Num())
+	den := value.Denom()
+	quotient, remainder := new(big.Int).QuoRem(num, den, new(big.Int))
+	if new(big.Int).Mul(remainder, big.NewInt(2)).Cmp(den) >= 0 {
+		quotient.Add(quotient, big.NewInt(1))
+	}
+	if value.Sign() < 0 {
+		quotient.Neg(quotient)
+	}
+	return quotient
+}
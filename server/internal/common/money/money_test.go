@@ -0,0 +1,118 @@
+package money
+
+import (
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		amount   string
+		currency string
+		minor    int64
+		err      error
+	}{
+		{"10.50", "USD", 1050, nil},
+		{"10.5", "USD", 1050, nil},
+		{"-0.01", "USD", -1, nil},
+		{"1000", "JPY", 1000, nil},
+		{"1.234", "KWD", 1234, nil},
+		{"10.505", "USD", 0, ErrPrecision},
+		{"1.5", "JPY", 0, ErrPrecision},
+		{"1e3", "USD", 0, ErrFormat},
+		{"1/2", "USD", 0, ErrFormat},
+		{"10", "XXX", 0, ErrCurrency},
+		{"92233720368547758.08", "USD", 0, ErrOverflow},
+	}
+	for _, tt := range tests {
+		t.Run(tt.amount+" "+tt.currency, func(t *testing.T) {
+			m, err := Parse(tt.amount, tt.currency)
+			if tt.err != nil {
+				assert.ErrorIs(t, err, tt.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, New(tt.minor, tt.currency), m)
+		})
+	}
+}
+
+func TestDecimal(t *testing.T) {
+	assert.Equal(t, "10.50", New(1050, "USD").Decimal())
+	assert.Equal(t, "0.05", New(5, "USD").Decimal())
+	assert.Equal(t, "-0.05", New(-5, "USD").Decimal())
+	assert.Equal(t, "1000", New(1000, "JPY").Decimal())
+	assert.Equal(t, "1.234", New(1234, "KWD").Decimal())
+	assert.Equal(t, "10.50 USD", New(1050, "USD").String())
+}
+
+func TestRounding(t *testing.T) {
+	// 0.125 USD rounds half away from zero
+	m, err := New(25, "USD").Scale(1, 2)
+	require.NoError(t, err)
+	assert.Equal(t, int64(13), m.Minor)
+
+	m, err = New(-25, "USD").Scale(1, 2)
+	require.NoError(t, err)
+	assert.Equal(t, int64(-13), m.Minor)
+
+	// prorating a monthly salary of 3000.00 by 10 of 31 days
+	m, err = New(300000, "USD").Scale(10, 31)
+	require.NoError(t, err)
+	assert.Equal(t, int64(96774), m.Minor)
+
+	m, err = FromRat(big.NewRat(1, 2), "JPY")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), m.Minor)
+
+	_, err = New(1, "USD").Scale(1, 0)
+	assert.ErrorIs(t, err, ErrFormat)
+}
+
+func TestArithmetic(t *testing.T) {
+	sum, err := New(1050, "USD").Add(New(-50, "USD"))
+	require.NoError(t, err)
+	assert.Equal(t, New(1000, "USD"), sum)
+
+	_, err = New(1050, "USD").Add(New(1, "EUR"))
+	assert.ErrorIs(t, err, ErrCurrencyMismatch)
+
+	cmp, err := New(1, "USD").Cmp(New(2, "USD"))
+	require.NoError(t, err)
+	assert.Equal(t, -1, cmp)
+
+	converted, err := New(1000, "EUR").Convert(big.NewRat(3, 2), "JPY")
+	require.NoError(t, err)
+	assert.Equal(t, New(15, "JPY"), converted)
+}
+
+func TestJSON(t *testing.T) {
+	data, err := json.Marshal(New(1050, "USD"))
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"amount":"10.50","currency":"USD"}`, string(data))
+
+	var m Money
+	require.NoError(t, json.Unmarshal([]byte(`{"amount":"0.10","currency":"USD"}`), &m))
+	assert.Equal(t, New(10, "USD"), m)
+
+	require.NoError(t, json.Unmarshal([]byte(`{"amount":12345678901234.56,"currency":"USD"}`), &m))
+	assert.Equal(t, New(1234567890123456, "USD"), m, "numbers are parsed exactly")
+
+	assert.Error(t, json.Unmarshal([]byte(`{"amount":"0.001","currency":"USD"}`), &m))
+}
+
+func TestScanValue(t *testing.T) {
+	var m Money
+	require.NoError(t, m.Scan(int64(1050)))
+	assert.Equal(t, int64(1050), m.Minor)
+	require.NoError(t, m.Scan([]byte("-7")))
+	assert.Equal(t, int64(-7), m.Minor)
+
+	value, err := New(1050, "USD").Value()
+	require.NoError(t, err)
+	assert.Equal(t, int64(1050), value)
+}
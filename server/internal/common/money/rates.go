@@ -0,0 +1,69 @@
+package money
+
+import (
+	"fmt"
+	"math/big"
+	"strconv"
+
+	"gorm.io/gorm"
+)
+
+// Exchange rates by currency pair [base, quote], each the amount of the quote currency one unit of the base currency buys.
+type Rates map[[2]string]*big.Rat
+
+/*
+Sets the rate of the pair from a float, kept as the exact decimal it was entered as rather than
+the nearest binary fraction, e.g. 1.1 and not 1.100000000000000088817841970012523. Rates that are
+not positive are ignored.
+*/
+func (r Rates) SetFloat(base string, quote string, rate float64) {
+	value, ok := new(big.Rat).SetString(strconv.FormatFloat(rate, 'f', -1, 64))
+	if ok && value.Sign() > 0 {
+		r[[2]string{base, quote}] = value
+	}
+}
+
+// Returns the rate between the currencies, direct or inverse. Reports false if neither is defined.
+func (r Rates) Rate(from string, to string) (*big.Rat, bool) {
+	if from == to {
+		return big.NewRat(1, 1), true
+	}
+	if rate, ok := r[[2]string{from, to}]; ok {
+		return rate, true
+	}
+	if rate, ok := r[[2]string{to, from}]; ok {
+		return new(big.Rat).Inv(rate), true
+	}
+	return nil, false
+}
+
+/*
+Row of the exchange_rates table, mirroring schema.ExchangeRate which cannot be imported here as schema
+depends on money. It keeps CompanyID and DeletedAt, so that tenant scoping and soft deletes still apply.
+*/
+type exchangeRate struct {
+	CompanyID     uint
+	BaseCurrency  string
+	QuoteCurrency string
+	Rate          float64
+	DeletedAt     gorm.DeletedAt
+}
+
+func (exchangeRate) TableName() string {
+	return "exchange_rates"
+}
+
+// Returns the exchange rates visible to db, i.e. those of the company in its context.
+func LoadRates(db *gorm.DB) (Rates, error) {
+	var rows []exchangeRate
+	err := db.Find(&rows).Error
+	if err != nil {
+		return nil, fmt.Errorf("LoadRates: %w", err)
+	}
+
+	rates := Rates{}
+	for _, row := range rows {
+		rates.SetFloat(row.BaseCurrency, row.QuoteCurrency, row.Rate)
+	}
+	return rates, nil
+}
@@ -0,0 +1,37 @@
+package money
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/alsey89/people-matter/pkg/pgconn/pgtest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRates(t *testing.T) {
+	rates := Rates{}
+	rates.SetFloat("EUR", "USD", 1.1)
+	rates.SetFloat("USD", "JPY", 0)
+
+	rate, ok := rates.Rate("EUR", "USD")
+	assert.True(t, ok)
+	assert.Equal(t, big.NewRat(11, 10), rate, "exact decimal")
+
+	rate, ok = rates.Rate("USD", "EUR")
+	assert.True(t, ok, "inverse rate")
+	assert.Equal(t, big.NewRat(10, 11), rate)
+
+	_, ok = rates.Rate("USD", "JPY")
+	assert.False(t, ok, "rates that are not positive are ignored")
+}
+
+func TestLoadRates(t *testing.T) {
+	db := pgtest.NewDryRunDB(t)
+	statements := pgtest.Capture(t, db)
+
+	_, err := LoadRates(db)
+	require.NoError(t, err)
+	assert.Equal(t, `SELECT * FROM "exchange_rates" WHERE "exchange_rates"."deleted_at" IS NULL`, statements.Last())
+}
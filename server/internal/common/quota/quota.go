@@ -0,0 +1,184 @@
+package quota
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/alsey89/people-matter/internal/common/errmgr"
+	"github.com/alsey89/people-matter/internal/schema"
+	"github.com/alsey89/people-matter/pkg/pgconn"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Resources limited by the company quotas.
+const (
+	ResourceLocations = "locations"
+	ResourceEmployees = "employees"
+)
+
+// Consumption of a single quota.
+type Consumption struct {
+	Used      int64 `json:"used"`
+	Quota     int   `json:"quota"`
+	Remaining int64 `json:"remaining"`
+}
+
+func newConsumption(used int64, quota int) Consumption {
+	remaining := int64(quota) - used
+	if remaining < 0 {
+		remaining = 0
+	}
+	return Consumption{Used: used, Quota: quota, Remaining: remaining}
+}
+
+type Usage struct {
+	Locations Consumption `json:"locations"`
+	Employees Consumption `json:"employees"`
+}
+
+/*
+Scope selecting the users that count against the employee quota: users holding a position that has not
+ended, see schema.OpenUserPositions, and users that never held one yet, e.g. accounts created on sign up that
+still await an assignment. Users whose positions all ended, e.g. offboarded employees, do not count.
+*/
+func ActiveEmployees(db *gorm.DB) *gorm.DB {
+	return db.Where(`(EXISTS (
+		SELECT 1 FROM user_positions up
+		WHERE up.user_id = users.id AND up.deleted_at IS NULL AND ?
+	) OR NOT EXISTS (
+		SELECT 1 FROM user_positions up
+		WHERE up.user_id = users.id AND up.deleted_at IS NULL
+	))`, schema.OpenUserPositions("up", time.Now()))
+}
+
+// Returns the current consumption of every quota of the company db is scoped to.
+func GetUsage(db *gorm.DB) (*Usage, error) {
+	company, err := findCompany(db, false)
+	if err != nil {
+		return nil, fmt.Errorf("GetUsage: %w", err)
+	}
+
+	locations, err := countLocations(db)
+	if err != nil {
+		return nil, fmt.Errorf("GetUsage: %w", err)
+	}
+	employees, err := countEmployees(db)
+	if err != nil {
+		return nil, fmt.Errorf("GetUsage: %w", err)
+	}
+
+	return &Usage{
+		Locations: newConsumption(locations, company.LocationQuota),
+		Employees: newConsumption(employees, company.EmployeeQuota),
+	}, nil
+}
+
+/*
+Fails with errmgr.ErrQuotaExceeded if the company tx is scoped to cannot have another location.
+Must be called in the transaction that creates the location: the company row stays locked until
+it ends, so concurrent reservations for the same company are counted one after another.
+*/
+func ReserveLocation(tx *gorm.DB) error {
+	company, err := findCompany(tx, true)
+	if err != nil {
+		return fmt.Errorf("ReserveLocation: %w", err)
+	}
+
+	used, err := countLocations(tx)
+	if err != nil {
+		return fmt.Errorf("ReserveLocation: %w", err)
+	}
+	if used >= int64(company.LocationQuota) {
+		return fmt.Errorf("ReserveLocation: %w: %s quota of %d reached", errmgr.ErrQuotaExceeded, ResourceLocations, company.LocationQuota)
+	}
+
+	return nil
+}
+
+/*
+Fails with errmgr.ErrQuotaExceeded if the company tx is scoped to cannot have another active employee.
+Must be called in the transaction that creates the user, see ReserveLocation.
+*/
+func ReserveEmployee(tx *gorm.DB) error {
+	company, err := findCompany(tx, true)
+	if err != nil {
+		return fmt.Errorf("ReserveEmployee: %w", err)
+	}
+
+	used, err := countEmployees(tx)
+	if err != nil {
+		return fmt.Errorf("ReserveEmployee: %w", err)
+	}
+	if used >= int64(company.EmployeeQuota) {
+		return fmt.Errorf("ReserveEmployee: %w: %s quota of %d reached", errmgr.ErrQuotaExceeded, ResourceEmployees, company.EmployeeQuota)
+	}
+
+	return nil
+}
+
+/*
+Like ReserveEmployee, for giving a user a position. Users that already count as active employees
+need no reservation, so only reactivating a user whose positions all ended can exceed the quota.
+Must be called in the transaction that creates the position assignment.
+*/
+func ReserveReactivation(tx *gorm.DB, userID uint) error {
+	// lock first, so that the user cannot be counted by a concurrent reservation in between
+	_, err := findCompany(tx, true)
+	if err != nil {
+		return fmt.Errorf("ReserveReactivation: %w", err)
+	}
+
+	var active int64
+	err = tx.Model(&schema.User{}).Scopes(ActiveEmployees).Where("id = ?", userID).Count(&active).Error
+	if err != nil {
+		return fmt.Errorf("ReserveReactivation: %w", err)
+	}
+	if active > 0 {
+		return nil
+	}
+
+	err = ReserveEmployee(tx)
+	if err != nil {
+		return fmt.Errorf("ReserveReactivation: %w", err)
+	}
+
+	return nil
+}
+
+func findCompany(db *gorm.DB, lock bool) (*schema.Company, error) {
+	companyID, ok := pgconn.CompanyIDFromContext(db.Statement.Context)
+	if !ok {
+		return nil, fmt.Errorf("findCompany: %w", pgconn.ErrNoCompanyInContext)
+	}
+
+	query := db
+	if lock {
+		query = query.Clauses(clause.Locking{Strength: "UPDATE"})
+	}
+
+	var company schema.Company
+	err := query.Where("id = ?", companyID).First(&company).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("findCompany: %w", errmgr.ErrTenant)
+		}
+		return nil, fmt.Errorf("findCompany: %w", err)
+	}
+
+	return &company, nil
+}
+
+func countLocations(db *gorm.DB) (int64, error) {
+	var count int64
+	err := db.Model(&schema.Location{}).Count(&count).Error
+	return count, err
+}
+
+func countEmployees(db *gorm.DB) (int64, error) {
+	var count int64
+	err := db.Model(&schema.User{}).Scopes(ActiveEmployees).Count(&count).Error
+	return count, err
+}
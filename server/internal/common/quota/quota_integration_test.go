@@ -0,0 +1,72 @@
+//go:build integration
+
+package quota
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alsey89/people-matter/internal/common/errmgr"
+	"github.com/alsey89/people-matter/internal/schema"
+	"github.com/alsey89/people-matter/pkg/pgconn"
+	"github.com/alsey89/people-matter/pkg/pgconn/pgtest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+/*
+Runs against an ephemeral postgres, see pgtest.StartPostgres.
+Run with: go test -tags integration ./internal/common/quota/
+*/
+
+const integrationScope = "quota_integration"
+
+func TestEmployeeQuotaIntegration(t *testing.T) {
+	pgtest.StartPostgres(t, 54332, integrationScope)
+	m := pgconn.NewPGConn(integrationScope, zap.NewNop())
+	migrations, err := pgconn.LoadMigrations(schema.Migrations, schema.MigrationsDir)
+	require.NoError(t, err)
+	_, err = m.MigrateUp(migrations, false)
+	require.NoError(t, err)
+
+	company := schema.Company{TenantID: "acme", Name: "Acme", Email: "owner@acme.test", EmployeeQuota: 2}
+	require.NoError(t, m.GetDB().Create(&company).Error)
+	db, err := m.GetScopedDB(pgconn.WithCompanyID(context.Background(), company.ID))
+	require.NoError(t, err)
+
+	location := schema.Location{Name: "Head office"}
+	require.NoError(t, db.Create(&location).Error)
+	position := schema.Position{Name: "Clerk"}
+	require.NoError(t, db.Create(&position).Error)
+
+	// Creates a user whose only assignment runs from startedAt until endedAt.
+	employ := func(email string, startedAt time.Time, endedAt *time.Time) {
+		user := schema.User{Name: email, Email: email}
+		require.NoError(t, db.Create(&user).Error)
+		require.NoError(t, db.Create(&schema.UserPosition{
+			UserID:     user.ID,
+			PositionID: position.ID,
+			LocationID: location.ID,
+			StartedAt:  startedAt,
+			EndedAt:    endedAt,
+		}).Error)
+	}
+
+	lastMonth := time.Now().AddDate(0, -1, 0)
+	nextMonth := time.Now().AddDate(0, 1, 0)
+	yesterday := time.Now().AddDate(0, 0, -1)
+
+	employ("ended@acme.test", lastMonth, &yesterday)
+	employ("ending@acme.test", lastMonth, &nextMonth)
+	employ("starting@acme.test", nextMonth, nil)
+
+	usage, err := GetUsage(db)
+	require.NoError(t, err)
+	assert.Equal(t, Consumption{Used: 2, Quota: 2, Remaining: 0}, usage.Employees,
+		"positions ending or starting in the future count, ended ones do not")
+
+	assert.ErrorIs(t, db.Transaction(ReserveEmployee), errmgr.ErrQuotaExceeded)
+}
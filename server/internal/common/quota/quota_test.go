@@ -0,0 +1,30 @@
+package quota
+
+import (
+	"testing"
+
+	"github.com/alsey89/people-matter/internal/schema"
+	"github.com/alsey89/people-matter/pkg/pgconn/pgtest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewConsumption(t *testing.T) {
+	assert.Equal(t, Consumption{Used: 3, Quota: 10, Remaining: 7}, newConsumption(3, 10))
+	assert.Equal(t, Consumption{Used: 10, Quota: 10, Remaining: 0}, newConsumption(10, 10))
+	// quotas lowered below the current usage
+	assert.Equal(t, Consumption{Used: 12, Quota: 10, Remaining: 0}, newConsumption(12, 10))
+}
+
+func TestActiveEmployees(t *testing.T) {
+	db := pgtest.NewDryRunDB(t)
+
+	var users []schema.User
+	sql := pgtest.SQL(db.Scopes(ActiveEmployees).Where("email = ?", "a@example.com").Find(&users))
+
+	// the OR must stay grouped, so that further conditions apply to both branches
+	assert.Contains(t, sql, `email = 'a@example.com' AND ((EXISTS (`)
+	assert.Contains(t, sql, `))) AND "users"."deleted_at" IS NULL`)
+	// positions given an end date ahead of time still count until they end
+	assert.Contains(t, sql, "up.deleted_at IS NULL AND (up.ended_at IS NULL OR up.ended_at > '")
+}
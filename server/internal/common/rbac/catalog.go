@@ -0,0 +1,95 @@
+package rbac
+
+// PermissionDefinition describes a permission of the catalog seeded into every company.
+type PermissionDefinition struct {
+	Name        string
+	Description string
+}
+
+/*
+Catalog is the canonical list of permissions seeded into every company.
+New permissions must be appended here and to the system positions that should receive them,
+they are seeded into existing companies on the next startup.
+*/
+var Catalog = []PermissionDefinition{
+	{EmployeesRead, "View employees and their assignments."},
+	{EmployeesWrite, "Invite, update, transfer and offboard employees."},
+	{LocationsRead, "View locations."},
+	{LocationsWrite, "Create, update and delete locations."},
+	{PositionsRead, "View positions and their permissions."},
+	{PositionsWrite, "Create and update positions and assign them to employees."},
+	{TimesheetsRead, "View time logs and timesheets of employees."},
+	{TimesheetsApprove, "Approve, reject and reopen timesheets."},
+	{TimekeepingWrite, "Configure overtime, break and rounding rules."},
+	{PayrollRead, "View compensations, payments and payroll runs."},
+	{PayrollWrite, "Manage compensations and run payroll."},
+	{DocumentsRead, "View documents attached to employees and the company."},
+	{DocumentsWrite, "Upload and delete documents."},
+	{SettingsRead, "View company settings and usage."},
+	{SettingsWrite, "Change company settings."},
+}
+
+// System position keys, stored in Position.SystemKey.
+const (
+	OwnerPositionKey    = "owner"
+	AdminPositionKey    = "admin"
+	EmployeePositionKey = "employee"
+)
+
+// PositionDefinition describes a position seeded into every company.
+type PositionDefinition struct {
+	Key         string
+	Name        string
+	Description string
+	CompanyWide bool
+	// Grants every permission of the catalog, including permissions added later.
+	AllPermissions bool
+	Permissions    []string
+}
+
+// SystemPositions are seeded into every company so that it can be administered from the start.
+var SystemPositions = []PositionDefinition{
+	{
+		Key:            OwnerPositionKey,
+		Name:           "Owner",
+		Description:    "Full access to the company.",
+		CompanyWide:    true,
+		AllPermissions: true,
+	},
+	{
+		Key:         AdminPositionKey,
+		Name:        "Admin",
+		Description: "Administers employees, locations, timekeeping and payroll at every location.",
+		CompanyWide: true,
+		Permissions: []string{
+			EmployeesRead, EmployeesWrite,
+			LocationsRead, LocationsWrite,
+			PositionsRead, PositionsWrite,
+			TimesheetsRead, TimesheetsApprove, TimekeepingWrite,
+			PayrollRead, PayrollWrite,
+			DocumentsRead, DocumentsWrite,
+			SettingsRead,
+		},
+	},
+	{
+		Key:         EmployeePositionKey,
+		Name:        "Employee",
+		Description: "Tracks own time and views own timesheets.",
+		CompanyWide: false,
+		Permissions: []string{
+			LocationsRead,
+		},
+	},
+}
+
+// Returns the permissions granted by the position definition.
+func (p PositionDefinition) PermissionNames() []string {
+	if !p.AllPermissions {
+		return p.Permissions
+	}
+	names := make([]string, 0, len(Catalog))
+	for _, permission := range Catalog {
+		names = append(names, permission.Name)
+	}
+	return names
+}
@@ -0,0 +1,32 @@
+package rbac
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCatalog(t *testing.T) {
+	names := make(map[string]bool)
+	for _, permission := range Catalog {
+		assert.False(t, names[permission.Name], "duplicate permission %s", permission.Name)
+		assert.NotEmpty(t, permission.Description, permission.Name)
+		names[permission.Name] = true
+	}
+
+	keys := make(map[string]bool)
+	for _, position := range SystemPositions {
+		assert.False(t, keys[position.Key], "duplicate position %s", position.Key)
+		keys[position.Key] = true
+
+		for _, name := range position.PermissionNames() {
+			assert.True(t, names[name], "position %s grants %s, which is not in the catalog", position.Key, name)
+		}
+	}
+
+	for _, position := range SystemPositions {
+		if position.Key == OwnerPositionKey {
+			assert.Len(t, position.PermissionNames(), len(Catalog))
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package rbac
+
+import (
+	"sort"
+	"time"
+
+	"github.com/alsey89/people-matter/internal/schema"
+
+	"gorm.io/gorm"
+)
+
+// Permission names, in the form <resource>:<action>.
+// A position holding a permission grants it to every user assigned to the position, at the location
+// of the assignment, or at every location if the position is company wide.
+const (
+	EmployeesRead  = "employees:read"
+	EmployeesWrite = "employees:write"
+
+	LocationsRead  = "locations:read"
+	LocationsWrite = "locations:write"
+
+	TimesheetsRead    = "timesheets:read"
+	TimesheetsApprove = "timesheets:approve"
+	TimekeepingWrite  = "timekeeping:write"
+
+	PositionsRead  = "positions:read"
+	PositionsWrite = "positions:write"
+
+	PayrollRead  = "payroll:read"
+	PayrollWrite = "payroll:write"
+
+	DocumentsRead  = "documents:read"
+	DocumentsWrite = "documents:write"
+
+	SettingsRead  = "settings:read"
+	SettingsWrite = "settings:write"
+)
+
+// Grant records where a permission applies.
+type Grant struct {
+	CompanyWide bool
+	LocationIDs map[uint]bool
+}
+
+// PermissionSet maps the permissions granted to a user to where they apply.
+type PermissionSet map[string]*Grant
+
+// Grants the permission at the location, or at every location if companyWide is true.
+func (s PermissionSet) Add(permission string, locationID uint, companyWide bool) {
+	grant, ok := s[permission]
+	if !ok {
+		grant = &Grant{LocationIDs: make(map[uint]bool)}
+		s[permission] = grant
+	}
+	if companyWide {
+		grant.CompanyWide = true
+	}
+	grant.LocationIDs[locationID] = true
+}
+
+// Reports whether the permission is granted at any location.
+func (s PermissionSet) Has(permission string) bool {
+	_, ok := s[permission]
+	return ok
+}
+
+// Reports whether the permission is granted at the location.
+func (s PermissionSet) HasAt(permission string, locationID uint) bool {
+	grant, ok := s[permission]
+	if !ok {
+		return false
+	}
+	return grant.CompanyWide || grant.LocationIDs[locationID]
+}
+
+// Reports whether the permission is granted at every location.
+func (s PermissionSet) HasCompanyWide(permission string) bool {
+	grant, ok := s[permission]
+	return ok && grant.CompanyWide
+}
+
+// Returns the sorted locations the permission is granted at, or companyWide true if it applies everywhere.
+func (s PermissionSet) LocationIDs(permission string) (locationIDs []uint, companyWide bool) {
+	grant, ok := s[permission]
+	if !ok {
+		return nil, false
+	}
+	if grant.CompanyWide {
+		return nil, true
+	}
+
+	locationIDs = make([]uint, 0, len(grant.LocationIDs))
+	for locationID := range grant.LocationIDs {
+		locationIDs = append(locationIDs, locationID)
+	}
+	sort.Slice(locationIDs, func(i, j int) bool { return locationIDs[i] < locationIDs[j] })
+
+	return locationIDs, false
+}
+
+/*
+Returns a GORM scope restricting a query to rows whose locationColumn is a location the permission is granted at.
+Company wide grants are not restricted, and a query without any grant matches nothing.
+e.g. db.Scopes(permissions.ScopeLocations(rbac.LocationsRead, "locations.id"))
+*/
+func (s PermissionSet) ScopeLocations(permission string, locationColumn string) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		locationIDs, companyWide := s.LocationIDs(permission)
+		if companyWide {
+			return db
+		}
+		if len(locationIDs) == 0 {
+			return db.Where("1 = 0")
+		}
+		return db.Where(locationColumn+" IN ?", locationIDs)
+	}
+}
+
+/*
+Returns a GORM scope restricting a query to rows whose userColumn is a user currently assigned to a location
+the permission is granted at. Company wide grants are not restricted, and a query without any grant matches nothing.
+e.g. db.Scopes(permissions.ScopeUsers(rbac.EmployeesRead, "users.id"))
+*/
+func (s PermissionSet) ScopeUsers(permission string, userColumn string) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		locationIDs, companyWide := s.LocationIDs(permission)
+		if companyWide {
+			return db
+		}
+		if len(locationIDs) == 0 {
+			return db.Where("1 = 0")
+		}
+		return db.Where(userColumn+" IN (SELECT user_id FROM user_positions WHERE location_id IN ? AND ? AND deleted_at IS NULL)",
+			locationIDs, schema.ActiveUserPositions("user_positions", time.Now()))
+	}
+}
@@ -0,0 +1,69 @@
+package rbac
+
+import (
+	"testing"
+
+	"github.com/alsey89/people-matter/pkg/pgconn/pgtest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type record struct {
+	ID         uint
+	LocationID uint
+}
+
+func TestPermissionSet(t *testing.T) {
+	permissions := PermissionSet{}
+	permissions.Add(EmployeesRead, 2, false)
+	permissions.Add(EmployeesRead, 1, false)
+	permissions.Add(PayrollRead, 1, true)
+
+	assert.True(t, permissions.Has(EmployeesRead))
+	assert.False(t, permissions.Has(EmployeesWrite))
+
+	assert.True(t, permissions.HasAt(EmployeesRead, 1))
+	assert.False(t, permissions.HasAt(EmployeesRead, 3))
+	assert.True(t, permissions.HasAt(PayrollRead, 3), "company wide")
+	assert.False(t, permissions.HasAt(EmployeesWrite, 1))
+
+	assert.False(t, permissions.HasCompanyWide(EmployeesRead))
+	assert.True(t, permissions.HasCompanyWide(PayrollRead))
+
+	locationIDs, companyWide := permissions.LocationIDs(EmployeesRead)
+	assert.Equal(t, []uint{1, 2}, locationIDs)
+	assert.False(t, companyWide)
+
+	_, companyWide = permissions.LocationIDs(PayrollRead)
+	assert.True(t, companyWide)
+}
+
+func TestScopeLocations(t *testing.T) {
+	db := pgtest.NewDryRunDB(t)
+	permissions := PermissionSet{}
+	permissions.Add(EmployeesRead, 1, false)
+	permissions.Add(PayrollRead, 1, true)
+
+	sql := func(permission string) string {
+		var records []record
+		return pgtest.SQL(db.Scopes(permissions.ScopeLocations(permission, "location_id")).Find(&records))
+	}
+
+	assert.Contains(t, sql(EmployeesRead), "location_id IN (1)")
+	assert.NotContains(t, sql(PayrollRead), "WHERE")
+	assert.Contains(t, sql(EmployeesWrite), "1 = 0")
+}
+
+func TestScopeUsers(t *testing.T) {
+	db := pgtest.NewDryRunDB(t)
+	permissions := PermissionSet{}
+	permissions.Add(EmployeesRead, 1, false)
+	permissions.Add(EmployeesRead, 2, false)
+
+	var records []record
+	sql := pgtest.SQL(db.Scopes(permissions.ScopeUsers(EmployeesRead, "id")).Find(&records))
+
+	assert.Contains(t, sql, "id IN (SELECT user_id FROM user_positions WHERE location_id IN (1,2)")
+	assert.Contains(t, sql, "(user_positions.started_at <= '")
+	assert.Contains(t, sql, "(user_positions.ended_at IS NULL OR user_positions.ended_at > '", "positions ending in the future are still held")
+}
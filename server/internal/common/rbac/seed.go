@@ -0,0 +1,154 @@
+package rbac
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/alsey89/people-matter/internal/schema"
+	"github.com/alsey89/people-matter/pkg/pgconn"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+/*
+Seeds the permission catalog and the system positions into the company db is scoped to,
+e.g. the db returned by pgconn.Module.GetScopedDB.
+
+Idempotent and safe to run concurrently: missing permissions and positions are created, and links
+are only created for positions and permissions created by this run. Links an admin removed from a
+system position are therefore not restored, while permissions newly added to the catalog are
+granted to the system positions that define them.
+*/
+func SeedCompany(db *gorm.DB) error {
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var existing []schema.Permission
+		err := tx.Find(&existing).Error
+		if err != nil {
+			return err
+		}
+		known := make(map[string]bool, len(existing))
+		for _, permission := range existing {
+			known[permission.Name] = true
+		}
+
+		created := make(map[string]bool)
+		for _, definition := range Catalog {
+			if known[definition.Name] {
+				continue
+			}
+			// one row at a time, so that skipped conflicts cannot shift returned ids
+			result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&schema.Permission{
+				Name:        definition.Name,
+				Description: definition.Description,
+			})
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected == 1 {
+				created[definition.Name] = true
+			}
+		}
+
+		var permissions []schema.Permission
+		err = tx.Find(&permissions).Error
+		if err != nil {
+			return err
+		}
+		permissionIDs := make(map[string]uint, len(permissions))
+		for _, permission := range permissions {
+			permissionIDs[permission.Name] = permission.ID
+		}
+
+		for _, definition := range SystemPositions {
+			position, positionCreated, err := seedPosition(tx, definition)
+			if err != nil {
+				return err
+			}
+
+			for _, name := range definition.PermissionNames() {
+				if !positionCreated && !created[name] {
+					continue
+				}
+				permissionID, ok := permissionIDs[name]
+				if !ok {
+					return fmt.Errorf("position %s grants %s, which is not in the catalog", definition.Key, name)
+				}
+				err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&schema.PositionPermission{
+					PositionID:   position.ID,
+					PermissionID: permissionID,
+				}).Error
+				if err != nil {
+					return err
+				}
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("SeedCompany: %w", err)
+	}
+
+	return nil
+}
+
+// Seeds every company, each in its own company transaction so that row level security policies are satisfied.
+// Intended to run on startup, so that catalog additions reach existing companies.
+func SeedAllCompanies(ctx context.Context, pg *pgconn.Module) error {
+	var companyIDs []uint
+	err := pg.GetDB().WithContext(ctx).Model(&schema.Company{}).Order("id").Pluck("id", &companyIDs).Error
+	if err != nil {
+		return fmt.Errorf("SeedAllCompanies: %w", err)
+	}
+
+	for _, companyID := range companyIDs {
+		err := pg.WithCompanyTransaction(pgconn.WithCompanyID(ctx, companyID), func(ctx context.Context) error {
+			db, err := pg.GetScopedDB(ctx)
+			if err != nil {
+				return err
+			}
+			return SeedCompany(db)
+		})
+		if err != nil {
+			return fmt.Errorf("SeedAllCompanies: company %d: %w", companyID, err)
+		}
+	}
+
+	return nil
+}
+
+// Returns the system position with the definition's key, creating it if it does not exist.
+func seedPosition(tx *gorm.DB, definition PositionDefinition) (*schema.Position, bool, error) {
+	var position schema.Position
+	err := tx.Where("system_key = ?", definition.Key).First(&position).Error
+	if err == nil {
+		return &position, false, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, false, fmt.Errorf("seedPosition: %w", err)
+	}
+
+	key := definition.Key
+	position = schema.Position{
+		SystemKey:   &key,
+		Name:        definition.Name,
+		Description: definition.Description,
+		CompanyWide: definition.CompanyWide,
+	}
+	result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&position)
+	if result.Error != nil {
+		return nil, false, fmt.Errorf("seedPosition: %w", result.Error)
+	}
+	if result.RowsAffected == 1 {
+		return &position, true, nil
+	}
+
+	// created concurrently by another process
+	err = tx.Where("system_key = ?", definition.Key).First(&position).Error
+	if err != nil {
+		return nil, false, fmt.Errorf("seedPosition: %w", err)
+	}
+	return &position, false, nil
+}
@@ -40,3 +40,57 @@ func EncodeQueryParam(param string) string {
 func EncodePathParam(param string) string {
 	return url.PathEscape(param)
 }
+
+// Returns the subdomain of host under domain, the inverse of PathToFullURL.
+// Ports are ignored, e.g. "acme.localhost:5555" under "localhost:3000" returns "acme".
+// Only a single subdomain label is accepted.
+func SubdomainFromHost(host string, domain string) (*string, error) {
+	if host == "" || domain == "" {
+		return nil, fmt.Errorf("SubdomainFromHost: missing one or more required parameters. host: %s, domain: %s", host, domain)
+	}
+
+	host = strings.ToLower(stripPort(host))
+	domain = strings.ToLower(stripPort(domain))
+
+	suffix := "." + domain
+	if !strings.HasSuffix(host, suffix) {
+		return nil, fmt.Errorf("SubdomainFromHost: host %s is not a subdomain of %s", host, domain)
+	}
+
+	subdomain := strings.TrimSuffix(host, suffix)
+	if !IsValidSubdomain(subdomain) {
+		return nil, fmt.Errorf("SubdomainFromHost: invalid subdomain %s", subdomain)
+	}
+
+	return &subdomain, nil
+}
+
+// Reports whether s is a single DNS label safe to use as a tenant subdomain:
+// 3 to 63 lowercase letters, digits or hyphens, not starting or ending with a hyphen.
+func IsValidSubdomain(s string) bool {
+	if len(s) < 3 || len(s) > 63 {
+		return false
+	}
+	if s[0] == '-' || s[len(s)-1] == '-' {
+		return false
+	}
+	for _, r := range s {
+		if !(r >= 'a' && r <= 'z') && !(r >= '0' && r <= '9') && r != '-' {
+			return false
+		}
+	}
+	return true
+}
+
+// Returns a LIKE/ILIKE pattern matching strings that contain s, with wildcards in s escaped.
+func ContainsPattern(s string) string {
+	escaped := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+	return "%" + escaped + "%"
+}
+
+func stripPort(hostport string) string {
+	if i := strings.LastIndex(hostport, ":"); i != -1 {
+		return hostport[:i]
+	}
+	return hostport
+}
@@ -0,0 +1,55 @@
+package util
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSubdomainFromHost(t *testing.T) {
+	t.Run("RoundTripsPathToFullURL", func(t *testing.T) {
+		subdomain, err := SubdomainFromHost("acme.localhost:3000", "localhost:3000")
+		assert.NoError(t, err)
+		assert.Equal(t, "acme", *subdomain)
+	})
+
+	t.Run("IgnoresPortsAndCase", func(t *testing.T) {
+		subdomain, err := SubdomainFromHost("ACME.peoplematter.app:443", "peoplematter.app")
+		assert.NoError(t, err)
+		assert.Equal(t, "acme", *subdomain)
+	})
+
+	t.Run("RejectsApexDomain", func(t *testing.T) {
+		subdomain, err := SubdomainFromHost("peoplematter.app", "peoplematter.app")
+		assert.Error(t, err)
+		assert.Nil(t, subdomain)
+	})
+
+	t.Run("RejectsOtherDomain", func(t *testing.T) {
+		subdomain, err := SubdomainFromHost("acme.example.com", "peoplematter.app")
+		assert.Error(t, err)
+		assert.Nil(t, subdomain)
+	})
+
+	t.Run("RejectsNestedSubdomain", func(t *testing.T) {
+		subdomain, err := SubdomainFromHost("a.acme.peoplematter.app", "peoplematter.app")
+		assert.Error(t, err)
+		assert.Nil(t, subdomain)
+	})
+}
+
+func TestIsValidSubdomain(t *testing.T) {
+	assert.True(t, IsValidSubdomain("acme"))
+	assert.True(t, IsValidSubdomain("acme-corp-2"))
+	assert.False(t, IsValidSubdomain("ac"))
+	assert.False(t, IsValidSubdomain("-acme"))
+	assert.False(t, IsValidSubdomain("acme-"))
+	assert.False(t, IsValidSubdomain("Acme"))
+	assert.False(t, IsValidSubdomain("acme.corp"))
+	assert.False(t, IsValidSubdomain("acme_corp"))
+}
+
+func TestContainsPattern(t *testing.T) {
+	assert.Equal(t, "%ann%", ContainsPattern("ann"))
+	assert.Equal(t, `%50\%\_off\\%`, ContainsPattern(`50%_off\`))
+}
@@ -0,0 +1,125 @@
+package company
+
+import (
+	"context"
+
+	"github.com/alsey89/people-matter/internal/common/middleware"
+	"github.com/alsey89/people-matter/internal/common/rbac"
+	"github.com/alsey89/people-matter/internal/common/util"
+	"github.com/alsey89/people-matter/internal/identity"
+	"github.com/alsey89/people-matter/pkg/pgconn"
+	"github.com/alsey89/people-matter/pkg/server"
+	"github.com/alsey89/people-matter/pkg/token"
+
+	"github.com/labstack/echo/v4"
+	"github.com/spf13/viper"
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+)
+
+type Domain struct {
+	scope  string
+	logger *zap.Logger
+	config *Config
+	params Params
+}
+
+type Params struct {
+	fx.In
+	Lifecycle fx.Lifecycle
+	Logger    *zap.Logger
+	DB        *pgconn.Module
+	Server    *server.Module
+	Token     *token.Module
+}
+
+type Config struct {
+	clientDomain string
+}
+
+const (
+	defaultClientDomain = "localhost:3000"
+)
+
+// ! Domain ---------------------------------------------------------------
+
+func InjectDomain(scope string) fx.Option {
+	return fx.Module(
+		scope,
+		fx.Provide(func(p Params) *Domain {
+			d := &Domain{scope: scope}
+			d.params = p
+			d.logger = d.setupLogger(scope, p)
+			d.config = d.setupConfig(scope)
+
+			return d
+		}),
+		fx.Invoke(func(d *Domain, p Params) {
+			d.registerRoutes()
+
+			p.Lifecycle.Append(
+				fx.Hook{
+					OnStart: d.onStart,
+					OnStop:  d.onStop,
+				},
+			)
+		}),
+	)
+}
+
+// ! Internal ---------------------------------------------------------------
+
+func (d *Domain) setupLogger(scope string, p Params) *zap.Logger {
+	logger := p.Logger.Named("[" + scope + "]")
+	return logger
+}
+
+func (d *Domain) setupConfig(scope string) *Config {
+	viper.SetDefault(util.GetConfigPath("global", "client_domain"), defaultClientDomain)
+
+	return &Config{
+		clientDomain: viper.GetString(util.GetConfigPath("global", "client_domain")),
+	}
+}
+
+func (d *Domain) registerRoutes() {
+	e := d.params.Server.GetServer()
+
+	resolveTenant := middleware.ResolveTenant(d.params.DB, d.logger, d.config.clientDomain)
+	requireAuth := d.params.Token.GetJWTMiddleware(identity.AuthTokenScope)
+	requireSameTenant := middleware.RequireSameTenant(d.logger)
+	requireActiveSession := middleware.RequireActiveSession(d.params.DB, d.logger)
+
+	companyGroup := e.Group("/api/v1/company", resolveTenant, requireAuth, requireSameTenant, requireActiveSession)
+	companyGroup.GET("/usage", d.GetUsageHandler, d.requirePermission(rbac.SettingsRead))
+	companyGroup.GET("/settings", d.GetSettingsHandler, d.requirePermission(rbac.SettingsRead))
+	companyGroup.PUT("/settings", d.UpdateSettingsHandler, d.requirePermission(rbac.SettingsWrite))
+	companyGroup.GET("/exchange-rates", d.ListExchangeRatesHandler, d.requirePermission(rbac.SettingsRead))
+	companyGroup.PUT("/exchange-rates", d.SetExchangeRateHandler, d.requirePermission(rbac.SettingsWrite))
+	companyGroup.DELETE("/exchange-rates/:id", d.DeleteExchangeRateHandler, d.requirePermission(rbac.SettingsWrite))
+}
+
+func (d *Domain) requirePermission(permission string) echo.MiddlewareFunc {
+	return middleware.RequirePermission(d.params.DB, d.logger, permission)
+}
+
+func (d *Domain) onStart(ctx context.Context) error {
+	d.logger.Info("Starting company domain.")
+
+	if viper.GetString("global.log_level") == "DEBUG" || viper.GetString("global.log_level") == "debug" {
+		d.logConfigurations()
+	}
+
+	return nil
+}
+
+func (d *Domain) onStop(ctx context.Context) error {
+	d.logger.Info("Stopping company domain.")
+	return nil
+}
+
+func (d *Domain) logConfigurations() {
+	d.logger.Debug("----- Company Configuration -----")
+	d.logger.Debug("Client Domain", zap.String("client_domain", d.config.clientDomain))
+	d.logger.Debug("---------------------------------")
+}
@@ -0,0 +1,149 @@
+package company
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/alsey89/people-matter/internal/common/API"
+	"github.com/alsey89/people-matter/internal/common/errmgr"
+	"github.com/alsey89/people-matter/internal/common/extractor"
+	"github.com/alsey89/people-matter/internal/common/money"
+	"github.com/alsey89/people-matter/internal/schema"
+
+	"github.com/labstack/echo/v4"
+)
+
+// Returns the company's location and employee consumption against its quotas.
+func (d *Domain) GetUsageHandler(c echo.Context) error {
+	usage, err := d.getUsage(c.Request().Context())
+	if err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("GetUsageHandler: %w", err))
+	}
+
+	return c.JSON(http.StatusOK, API.Response{
+		Message: "Usage retrieved.",
+		Data:    usage,
+	})
+}
+
+type UpdateSettingsRequest struct {
+	SalaryBandPolicy         string  `json:"salaryBandPolicy"         validate:"required,oneof=warning error"`
+	PayrollApprovalThreshold *string `json:"payrollApprovalThreshold" validate:"omitempty,max=32"` // decimal in major units, none without a second approver
+	PayrollApprovalCurrency  *string `json:"payrollApprovalCurrency"  validate:"required_with=PayrollApprovalThreshold,omitempty,len=3,uppercase"`
+}
+
+// Fails with errmgr.ErrPayload unless the payroll approval threshold, if any, is a positive amount.
+func (r UpdateSettingsRequest) settings() (Settings, error) {
+	settings := Settings{SalaryBandPolicy: r.SalaryBandPolicy}
+	if r.PayrollApprovalThreshold == nil {
+		return settings, nil
+	}
+
+	threshold, err := money.Parse(*r.PayrollApprovalThreshold, *r.PayrollApprovalCurrency)
+	if err != nil {
+		return Settings{}, fmt.Errorf("settings: %w: %v", errmgr.ErrPayload, err)
+	}
+	if threshold.IsZero() || threshold.IsNegative() {
+		return Settings{}, fmt.Errorf("settings: %w: payroll approval threshold must be positive", errmgr.ErrPayload)
+	}
+	settings.PayrollApprovalThreshold = &threshold
+	return settings, nil
+}
+
+type SetExchangeRateRequest struct {
+	BaseCurrency  string  `json:"baseCurrency"  validate:"required,len=3,uppercase"`
+	QuoteCurrency string  `json:"quoteCurrency" validate:"required,len=3,uppercase,nefield=BaseCurrency"`
+	Rate          float64 `json:"rate"          validate:"required,gt=0"`
+}
+
+func (d *Domain) GetSettingsHandler(c echo.Context) error {
+	settings, err := d.getSettings(c.Request().Context())
+	if err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("GetSettingsHandler: %w", err))
+	}
+
+	return c.JSON(http.StatusOK, API.Response{
+		Message: "Settings retrieved.",
+		Data:    settings,
+	})
+}
+
+// Updates the company wide settings, e.g. whether out of band compensations are rejected or only warned about,
+// and above which total payroll runs need a second approver.
+func (d *Domain) UpdateSettingsHandler(c echo.Context) error {
+	var req UpdateSettingsRequest
+	if err := c.Bind(&req); err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("UpdateSettingsHandler: %w: %v", errmgr.ErrPayload, err))
+	}
+	if err := c.Validate(&req); err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("UpdateSettingsHandler: %w: %v", errmgr.ErrPayload, err))
+	}
+
+	settings, err := req.settings()
+	if err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("UpdateSettingsHandler: %w", err))
+	}
+
+	result, err := d.updateSettings(c.Request().Context(), settings)
+	if err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("UpdateSettingsHandler: %w", err))
+	}
+
+	return c.JSON(http.StatusOK, API.Response{
+		Message: "Settings updated.",
+		Data:    result,
+	})
+}
+
+func (d *Domain) ListExchangeRatesHandler(c echo.Context) error {
+	rates, err := d.listExchangeRates(c.Request().Context())
+	if err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("ListExchangeRatesHandler: %w", err))
+	}
+
+	return c.JSON(http.StatusOK, API.Response{
+		Message: "Exchange rates retrieved.",
+		Data:    rates,
+	})
+}
+
+// Sets the exchange rate of a currency pair, used to compare compensations with salary bands in another currency.
+func (d *Domain) SetExchangeRateHandler(c echo.Context) error {
+	var req SetExchangeRateRequest
+	if err := c.Bind(&req); err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("SetExchangeRateHandler: %w: %v", errmgr.ErrPayload, err))
+	}
+	if err := c.Validate(&req); err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("SetExchangeRateHandler: %w: %v", errmgr.ErrPayload, err))
+	}
+
+	rate, err := d.setExchangeRate(c.Request().Context(), schema.ExchangeRate{
+		BaseCurrency:  req.BaseCurrency,
+		QuoteCurrency: req.QuoteCurrency,
+		Rate:          req.Rate,
+	})
+	if err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("SetExchangeRateHandler: %w", err))
+	}
+
+	return c.JSON(http.StatusOK, API.Response{
+		Message: "Exchange rate set.",
+		Data:    rate,
+	})
+}
+
+func (d *Domain) DeleteExchangeRateHandler(c echo.Context) error {
+	var rateID uint
+	if _, err := extractor.ExtractFromPathParamAs(c, "id", &rateID); err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("DeleteExchangeRateHandler: %w: %v", errmgr.ErrPayload, err))
+	}
+
+	err := d.deleteExchangeRate(c.Request().Context(), rateID)
+	if err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("DeleteExchangeRateHandler: %w", err))
+	}
+
+	return c.JSON(http.StatusOK, API.Response{
+		Message: "Exchange rate deleted.",
+	})
+}
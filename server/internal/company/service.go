@@ -0,0 +1,152 @@
+package company
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/alsey89/people-matter/internal/common/errmgr"
+	"github.com/alsey89/people-matter/internal/common/money"
+	"github.com/alsey89/people-matter/internal/common/quota"
+	"github.com/alsey89/people-matter/internal/schema"
+	"github.com/alsey89/people-matter/pkg/pgconn"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Company wide settings that can be changed by the company.
+type Settings struct {
+	SalaryBandPolicy string `json:"salaryBandPolicy"`
+	// Total above which payroll runs need a second approver, none if nil.
+	PayrollApprovalThreshold *money.Money `json:"payrollApprovalThreshold"`
+}
+
+func (d *Domain) getUsage(ctx context.Context) (*quota.Usage, error) {
+	db, err := d.params.DB.GetScopedDB(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("getUsage: %w: %v", errmgr.ErrTenant, err)
+	}
+
+	usage, err := quota.GetUsage(db)
+	if err != nil {
+		return nil, fmt.Errorf("getUsage: %w", err)
+	}
+
+	return usage, nil
+}
+
+func (d *Domain) getSettings(ctx context.Context) (*Settings, error) {
+	db, err := d.params.DB.GetScopedDB(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("getSettings: %w: %v", errmgr.ErrTenant, err)
+	}
+
+	company, err := findCompany(ctx, db)
+	if err != nil {
+		return nil, fmt.Errorf("getSettings: %w", err)
+	}
+
+	return &Settings{
+		SalaryBandPolicy:         company.SalaryBandPolicy,
+		PayrollApprovalThreshold: company.PayrollApprovalThreshold,
+	}, nil
+}
+
+func (d *Domain) updateSettings(ctx context.Context, settings Settings) (*Settings, error) {
+	db, err := d.params.DB.GetScopedDB(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("updateSettings: %w: %v", errmgr.ErrTenant, err)
+	}
+
+	company, err := findCompany(ctx, db)
+	if err != nil {
+		return nil, fmt.Errorf("updateSettings: %w", err)
+	}
+
+	var threshold, currency interface{}
+	if settings.PayrollApprovalThreshold != nil {
+		threshold, currency = settings.PayrollApprovalThreshold.Minor, settings.PayrollApprovalThreshold.Currency
+	}
+	err = db.Model(company).Updates(map[string]interface{}{
+		"salary_band_policy":         settings.SalaryBandPolicy,
+		"payroll_approval_threshold": threshold,
+		"payroll_approval_currency":  currency,
+	}).Error
+	if err != nil {
+		return nil, fmt.Errorf("updateSettings: %w", err)
+	}
+
+	return &settings, nil
+}
+
+func (d *Domain) listExchangeRates(ctx context.Context) ([]schema.ExchangeRate, error) {
+	db, err := d.params.DB.GetScopedDB(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("listExchangeRates: %w: %v", errmgr.ErrTenant, err)
+	}
+
+	var rates []schema.ExchangeRate
+	err = db.Order("base_currency, quote_currency").Find(&rates).Error
+	if err != nil {
+		return nil, fmt.Errorf("listExchangeRates: %w", err)
+	}
+
+	return rates, nil
+}
+
+// Creates the exchange rate of the currency pair, or replaces the rate if the pair already exists.
+func (d *Domain) setExchangeRate(ctx context.Context, rate schema.ExchangeRate) (*schema.ExchangeRate, error) {
+	db, err := d.params.DB.GetScopedDB(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("setExchangeRate: %w: %v", errmgr.ErrTenant, err)
+	}
+
+	err = db.Clauses(clause.OnConflict{
+		Columns:     []clause.Column{{Name: "company_id"}, {Name: "base_currency"}, {Name: "quote_currency"}},
+		TargetWhere: clause.Where{Exprs: []clause.Expression{clause.Expr{SQL: "deleted_at IS NULL"}}},
+		DoUpdates:   clause.AssignmentColumns([]string{"rate", "updated_at"}),
+	}).Create(&rate).Error
+	if err != nil {
+		return nil, fmt.Errorf("setExchangeRate: %w", err)
+	}
+
+	return &rate, nil
+}
+
+func (d *Domain) deleteExchangeRate(ctx context.Context, rateID uint) error {
+	db, err := d.params.DB.GetScopedDB(ctx)
+	if err != nil {
+		return fmt.Errorf("deleteExchangeRate: %w: %v", errmgr.ErrTenant, err)
+	}
+
+	result := db.Where("id = ?", rateID).Delete(&schema.ExchangeRate{})
+	if result.Error != nil {
+		return fmt.Errorf("deleteExchangeRate: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("deleteExchangeRate: %w", errmgr.ErrExchangeRateNotFound)
+	}
+
+	return nil
+}
+
+// ! Helpers ---------------------------------------------------------------
+
+// The companies table is not company scoped, so the company is looked up by the id carried by ctx.
+func findCompany(ctx context.Context, db *gorm.DB) (*schema.Company, error) {
+	companyID, ok := pgconn.CompanyIDFromContext(ctx)
+	if !ok {
+		return nil, fmt.Errorf("findCompany: %w", errmgr.ErrTenant)
+	}
+
+	var company schema.Company
+	err := db.Where("id = ?", companyID).First(&company).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("findCompany: %w", errmgr.ErrTenant)
+		}
+		return nil, fmt.Errorf("findCompany: %w", err)
+	}
+	return &company, nil
+}
@@ -0,0 +1,108 @@
+package compensation
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/alsey89/people-matter/internal/common/money"
+	"github.com/alsey89/people-matter/internal/schema"
+)
+
+// Outcomes of a salary band check
+const (
+	BandStatusWithin        = "within"
+	BandStatusBelow         = "below"
+	BandStatusAbove         = "above"
+	BandStatusUnconvertible = "unconvertible" // no exchange rate between the currencies
+)
+
+// Hours per year used to compare hourly compensations with other intervals, 40 hours a week.
+const hoursPerYear = 2080
+
+// Number of periods of each interval in a year.
+var periodsPerYear = map[string]int64{
+	schema.CompensationIntervalHourly:   hoursPerYear,
+	schema.CompensationIntervalWeekly:   52,
+	schema.CompensationIntervalBiWeekly: 26,
+	schema.CompensationIntervalMonthly:  12,
+	schema.CompensationIntervalAnnually: 1,
+}
+
+// Result of comparing a compensation with the salary band of one position.
+// Amount is the compensation normalized to the band's interval and currency, nil if it could not be converted.
+type BandCheck struct {
+	PositionID   uint         `json:"positionId"`
+	PositionName string       `json:"positionName"`
+	SalaryMin    *money.Money `json:"salaryMin"`
+	SalaryMax    *money.Money `json:"salaryMax"`
+	Currency     string       `json:"currency"`
+	Interval     string       `json:"interval"`
+	Amount       *money.Money `json:"amount"`
+	Status       string       `json:"status"`
+}
+
+func (b BandCheck) outOfBand() bool {
+	return b.Status == BandStatusBelow || b.Status == BandStatusAbove
+}
+
+// Returns the factor converting an amount paid per one interval to the amount paid per another, e.g. monthly to annually.
+func intervalFactor(from string, to string) (*big.Rat, error) {
+	fromPeriods, ok := periodsPerYear[from]
+	if !ok {
+		return nil, fmt.Errorf("intervalFactor: unknown interval %q", from)
+	}
+	toPeriods, ok := periodsPerYear[to]
+	if !ok {
+		return nil, fmt.Errorf("intervalFactor: unknown interval %q", to)
+	}
+	return big.NewRat(fromPeriods, toPeriods), nil
+}
+
+// Reports whether the position defines a salary band, a band needs a currency and at least one bound.
+func hasBand(position schema.Position) bool {
+	return position.SalaryCurrency != nil && (position.SalaryMin != nil || position.SalaryMax != nil)
+}
+
+// Compares the compensation with the salary band of the position, which must have one (see hasBand).
+func checkBand(compensation schema.Compensation, position schema.Position, rates money.Rates) (BandCheck, error) {
+	interval := schema.CompensationIntervalAnnually
+	if position.SalaryInterval != nil {
+		interval = *position.SalaryInterval
+	}
+	check := BandCheck{
+		PositionID:   position.ID,
+		PositionName: position.Name,
+		SalaryMin:    position.SalaryMin,
+		SalaryMax:    position.SalaryMax,
+		Currency:     *position.SalaryCurrency,
+		Interval:     interval,
+	}
+
+	factor, err := intervalFactor(compensation.Interval, interval)
+	if err != nil {
+		return check, fmt.Errorf("checkBand: %w", err)
+	}
+	rate, ok := rates.Rate(compensation.Currency, check.Currency)
+	if !ok {
+		check.Status = BandStatusUnconvertible
+		return check, nil
+	}
+	// the currency column is authoritative, one rounding to the minor unit of the band's currency
+	amount := compensation.Amount
+	amount.Currency = compensation.Currency
+	amount, err = amount.Convert(factor.Mul(factor, rate), check.Currency)
+	if err != nil {
+		return check, fmt.Errorf("checkBand: %w", err)
+	}
+	check.Amount = &amount
+
+	switch {
+	case position.SalaryMin != nil && amount.Minor < position.SalaryMin.Minor:
+		check.Status = BandStatusBelow
+	case position.SalaryMax != nil && amount.Minor > position.SalaryMax.Minor:
+		check.Status = BandStatusAbove
+	default:
+		check.Status = BandStatusWithin
+	}
+	return check, nil
+}
@@ -0,0 +1,67 @@
+package compensation
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/alsey89/people-matter/internal/common/money"
+	"github.com/alsey89/people-matter/internal/schema"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIntervalFactor(t *testing.T) {
+	factor, err := intervalFactor(schema.CompensationIntervalMonthly, schema.CompensationIntervalAnnually)
+	require.NoError(t, err)
+	assert.Equal(t, big.NewRat(12, 1), factor)
+
+	factor, err = intervalFactor(schema.CompensationIntervalHourly, schema.CompensationIntervalWeekly)
+	require.NoError(t, err)
+	assert.Equal(t, big.NewRat(40, 1), factor)
+
+	_, err = intervalFactor("daily", schema.CompensationIntervalAnnually)
+	assert.Error(t, err)
+}
+
+func TestCheckBand(t *testing.T) {
+	min, max, currency := money.New(5000000, "USD"), money.New(7000000, "USD"), "USD"
+	position := schema.Position{Name: "Engineer", SalaryMin: &min, SalaryMax: &max, SalaryCurrency: &currency}
+	rates := money.Rates{}
+	rates.SetFloat("EUR", "USD", 2)
+	monthly := func(amount string, currency string) schema.Compensation {
+		m, err := money.Parse(amount, currency)
+		require.NoError(t, err)
+		return schema.Compensation{Amount: m, Currency: currency, Interval: schema.CompensationIntervalMonthly}
+	}
+
+	tests := []struct {
+		name         string
+		compensation schema.Compensation
+		status       string
+		amount       string
+	}{
+		{"within", monthly("5000", "USD"), BandStatusWithin, "60000.00"},
+		{"lower bound", monthly("4166.67", "USD"), BandStatusWithin, "50000.04"},
+		{"below", monthly("4166.66", "USD"), BandStatusBelow, "49999.92"},
+		{"above", monthly("6000", "USD"), BandStatusAbove, "72000.00"},
+		{"converted", monthly("2500", "EUR"), BandStatusWithin, "60000.00"},
+		{"unconvertible", monthly("500000", "JPY"), BandStatusUnconvertible, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			check, err := checkBand(tt.compensation, position, rates)
+			require.NoError(t, err)
+			assert.Equal(t, tt.status, check.Status)
+			assert.Equal(t, schema.CompensationIntervalAnnually, check.Interval)
+			if tt.amount == "" {
+				assert.Nil(t, check.Amount)
+			} else {
+				assert.Equal(t, tt.amount, check.Amount.Decimal())
+			}
+		})
+	}
+
+	assert.True(t, hasBand(position))
+	assert.False(t, hasBand(schema.Position{SalaryMin: &min}), "no currency")
+}
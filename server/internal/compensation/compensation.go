@@ -0,0 +1,49 @@
+package compensation
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/alsey89/people-matter/internal/common/errmgr"
+	"github.com/alsey89/people-matter/internal/schema"
+
+	"gorm.io/gorm"
+)
+
+/*
+Fails with errmgr.ErrCompensationOverlap unless a compensation starting at startedAt can follow the latest
+compensation of the employee. History is append only: a new compensation must start after the latest one,
+which it then ends.
+*/
+func validateStart(startedAt time.Time, latest *schema.Compensation) error {
+	if latest != nil && !startedAt.After(latest.StartedAt) {
+		return fmt.Errorf("validateStart: %w: %s is not after the start %s of compensation %d",
+			errmgr.ErrCompensationOverlap, startedAt.Format(time.RFC3339), latest.StartedAt.Format(time.RFC3339), latest.ID)
+	}
+	return nil
+}
+
+// Subquery selecting the id of the compensation covering the given time for the user in the outer users row.
+func activeCompensationQuery(db *gorm.DB, at time.Time) *gorm.DB {
+	return db.Session(&gorm.Session{NewDB: true}).
+		Model(&schema.Compensation{}).
+		Select("compensations.id").
+		Where("compensations.user_id = users.id AND compensations.started_at <= ? AND (compensations.ended_at IS NULL OR compensations.ended_at > ?)", at, at).
+		Order("compensations.started_at DESC").
+		Limit(1)
+}
+
+/*
+Points User.ActiveCompensationID of the users within the scopes at the compensation covering the given time,
+or clears it if there is none. Only rows that change are updated. Returns the number of users updated.
+*/
+func syncActiveCompensations(db *gorm.DB, at time.Time, scopes ...func(*gorm.DB) *gorm.DB) (int64, error) {
+	result := db.Model(&schema.User{}).
+		Scopes(scopes...).
+		Where("users.active_compensation_id IS DISTINCT FROM (?)", activeCompensationQuery(db, at)).
+		Update("active_compensation_id", gorm.Expr("(?)", activeCompensationQuery(db, at)))
+	if result.Error != nil {
+		return 0, fmt.Errorf("syncActiveCompensations: %w", result.Error)
+	}
+	return result.RowsAffected, nil
+}
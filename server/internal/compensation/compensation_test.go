@@ -0,0 +1,40 @@
+package compensation
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alsey89/people-matter/internal/common/errmgr"
+	"github.com/alsey89/people-matter/internal/schema"
+	"github.com/alsey89/people-matter/pkg/pgconn/pgtest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func TestValidateStart(t *testing.T) {
+	start := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	latest := &schema.Compensation{StartedAt: start}
+
+	assert.NoError(t, validateStart(start, nil))
+	assert.NoError(t, validateStart(start.AddDate(0, 1, 0), latest))
+	assert.ErrorIs(t, validateStart(start, latest), errmgr.ErrCompensationOverlap)
+	assert.ErrorIs(t, validateStart(start.AddDate(0, -1, 0), latest), errmgr.ErrCompensationOverlap)
+}
+
+func TestSyncActiveCompensations(t *testing.T) {
+	db := pgtest.NewDryRunDB(t)
+	statements := pgtest.Capture(t, db)
+	at := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	_, err := syncActiveCompensations(db, at, func(db *gorm.DB) *gorm.DB { return db.Where("users.id = ?", 7) })
+	require.NoError(t, err)
+
+	sql := statements.Last()
+	assert.Contains(t, sql, `UPDATE "users" SET "active_compensation_id"=(SELECT compensations.id FROM "compensations"`)
+	assert.Contains(t, sql, "compensations.started_at <= '2024-03-01 00:00:00' AND (compensations.ended_at IS NULL OR compensations.ended_at > '2024-03-01 00:00:00')")
+	assert.Contains(t, sql, `"compensations"."deleted_at" IS NULL ORDER BY compensations.started_at DESC LIMIT 1)`)
+	assert.Contains(t, sql, "users.id = 7")
+	assert.Contains(t, sql, "users.active_compensation_id IS DISTINCT FROM (SELECT")
+}
@@ -0,0 +1,161 @@
+package compensation
+
+import (
+	"context"
+	"time"
+
+	"github.com/alsey89/people-matter/internal/common/middleware"
+	"github.com/alsey89/people-matter/internal/common/rbac"
+	"github.com/alsey89/people-matter/internal/common/util"
+	"github.com/alsey89/people-matter/internal/identity"
+	"github.com/alsey89/people-matter/pkg/pgconn"
+	"github.com/alsey89/people-matter/pkg/server"
+	"github.com/alsey89/people-matter/pkg/token"
+
+	"github.com/labstack/echo/v4"
+	"github.com/spf13/viper"
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+)
+
+type Domain struct {
+	scope  string
+	logger *zap.Logger
+	config *Config
+	params Params
+
+	// stops the activation job, see runActivationJob
+	stop chan struct{}
+	done chan struct{}
+}
+
+type Params struct {
+	fx.In
+	Lifecycle fx.Lifecycle
+	Logger    *zap.Logger
+	DB        *pgconn.Module
+	Server    *server.Module
+	Token     *token.Module
+}
+
+type Config struct {
+	clientDomain string
+
+	activationIntervalMinutes int
+}
+
+const (
+	defaultClientDomain = "localhost:3000"
+
+	defaultActivationIntervalMinutes = 15
+)
+
+// ! Domain ---------------------------------------------------------------
+
+func InjectDomain(scope string) fx.Option {
+	return fx.Module(
+		scope,
+		fx.Provide(func(p Params) *Domain {
+			d := &Domain{scope: scope}
+			d.params = p
+			d.logger = d.setupLogger(scope, p)
+			d.config = d.setupConfig(scope)
+
+			return d
+		}),
+		fx.Invoke(func(d *Domain, p Params) {
+			d.registerRoutes()
+
+			p.Lifecycle.Append(
+				fx.Hook{
+					OnStart: d.onStart,
+					OnStop:  d.onStop,
+				},
+			)
+		}),
+	)
+}
+
+// ! Internal ---------------------------------------------------------------
+
+func (d *Domain) setupLogger(scope string, p Params) *zap.Logger {
+	logger := p.Logger.Named("[" + scope + "]")
+	return logger
+}
+
+func (d *Domain) setupConfig(scope string) *Config {
+	viper.SetDefault(util.GetConfigPath("global", "client_domain"), defaultClientDomain)
+	viper.SetDefault(util.GetConfigPath(scope, "activation_interval_minutes"), defaultActivationIntervalMinutes)
+
+	// the activation job ticks every interval, which must be positive
+	activationIntervalMinutes := viper.GetInt(util.GetConfigPath(scope, "activation_interval_minutes"))
+	if activationIntervalMinutes <= 0 {
+		d.logger.Warn("Invalid activation interval, using the default.",
+			zap.Int("activation_interval_minutes", activationIntervalMinutes),
+			zap.Int("default", defaultActivationIntervalMinutes))
+		activationIntervalMinutes = defaultActivationIntervalMinutes
+	}
+
+	return &Config{
+		clientDomain: viper.GetString(util.GetConfigPath("global", "client_domain")),
+
+		activationIntervalMinutes: activationIntervalMinutes,
+	}
+}
+
+func (d *Domain) registerRoutes() {
+	e := d.params.Server.GetServer()
+
+	resolveTenant := middleware.ResolveTenant(d.params.DB, d.logger, d.config.clientDomain)
+	requireAuth := d.params.Token.GetJWTMiddleware(identity.AuthTokenScope)
+	requireSameTenant := middleware.RequireSameTenant(d.logger)
+	requireActiveSession := middleware.RequireActiveSession(d.params.DB, d.logger)
+
+	// handlers scope the employee to the locations the caller holds the permission at
+	compensationGroup := e.Group("/api/v1/employees/:id/compensations", resolveTenant, requireAuth, requireSameTenant, requireActiveSession)
+	compensationGroup.GET("", d.GetCompensationHistoryHandler, d.requirePermission(rbac.PayrollRead))
+	compensationGroup.POST("", d.CreateCompensationHandler, d.requirePermission(rbac.PayrollWrite))
+	compensationGroup.PUT("/:compensationId", d.UpdateCompensationHandler, d.requirePermission(rbac.PayrollWrite))
+	compensationGroup.DELETE("/:compensationId", d.CancelCompensationHandler, d.requirePermission(rbac.PayrollWrite))
+
+	reportGroup := e.Group("/api/v1/compensations", resolveTenant, requireAuth, requireSameTenant, requireActiveSession)
+	reportGroup.GET("/out-of-band", d.GetOutOfBandReportHandler, d.requirePermission(rbac.PayrollRead))
+}
+
+func (d *Domain) requirePermission(permission string) echo.MiddlewareFunc {
+	return middleware.RequirePermission(d.params.DB, d.logger, permission)
+}
+
+func (d *Domain) onStart(ctx context.Context) error {
+	d.logger.Info("Starting compensation domain.")
+
+	if viper.GetString("global.log_level") == "DEBUG" || viper.GetString("global.log_level") == "debug" {
+		d.logConfigurations()
+	}
+
+	d.stop = make(chan struct{})
+	d.done = make(chan struct{})
+	go d.runActivationJob(time.Duration(d.config.activationIntervalMinutes) * time.Minute)
+
+	return nil
+}
+
+func (d *Domain) onStop(ctx context.Context) error {
+	d.logger.Info("Stopping compensation domain.")
+
+	close(d.stop)
+	select {
+	case <-d.done:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	return nil
+}
+
+func (d *Domain) logConfigurations() {
+	d.logger.Debug("----- Compensation Configuration -----")
+	d.logger.Debug("Client Domain", zap.String("client_domain", d.config.clientDomain))
+	d.logger.Debug("Activation Interval Minutes", zap.Int("activation_interval_minutes", d.config.activationIntervalMinutes))
+	d.logger.Debug("--------------------------------------")
+}
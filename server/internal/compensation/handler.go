@@ -0,0 +1,196 @@
+package compensation
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/alsey89/people-matter/internal/common/API"
+	"github.com/alsey89/people-matter/internal/common/errmgr"
+	"github.com/alsey89/people-matter/internal/common/extractor"
+	"github.com/alsey89/people-matter/internal/common/middleware"
+	"github.com/alsey89/people-matter/internal/common/money"
+	"github.com/alsey89/people-matter/internal/common/rbac"
+	"github.com/alsey89/people-matter/internal/schema"
+
+	"github.com/labstack/echo/v4"
+)
+
+type CompensationRequest struct {
+	Amount      string  `json:"amount"         validate:"required,max=32"` // decimal in major units, e.g. "2500.00"
+	Currency    string  `json:"currency"       validate:"required,len=3,uppercase"`
+	Interval    string  `json:"interval"       validate:"required,oneof=hourly weekly bi-weekly monthly annually"`
+	ChannelType string  `json:"channelType"    validate:"required,max=255"`
+	ChannelCode *string `json:"channelId"      validate:"omitempty,max=255"`
+	Account     string  `json:"channelAccount" validate:"required,max=255"`
+}
+
+// Fails with errmgr.ErrPayload unless the amount is a positive decimal with at most the currency's decimals.
+func (r CompensationRequest) compensation() (schema.Compensation, error) {
+	amount, err := money.Parse(r.Amount, r.Currency)
+	if err != nil {
+		return schema.Compensation{}, fmt.Errorf("compensation: %w: %v", errmgr.ErrPayload, err)
+	}
+	if amount.Minor <= 0 {
+		return schema.Compensation{}, fmt.Errorf("compensation: %w: amount must be positive", errmgr.ErrPayload)
+	}
+
+	return schema.Compensation{
+		Amount:      amount,
+		Currency:    r.Currency,
+		Interval:    r.Interval,
+		ChannelType: r.ChannelType,
+		ChannelCode: r.ChannelCode,
+		Account:     r.Account,
+	}, nil
+}
+
+type CreateCompensationRequest struct {
+	CompensationRequest
+	StartedAt *time.Time `json:"startedAt"` // defaults to now, may be in the future
+}
+
+// Lists the compensation history of an employee the caller may read payroll of.
+func (d *Domain) GetCompensationHistoryHandler(c echo.Context) error {
+	permissions, err := middleware.LoadPermissions(c, d.params.DB)
+	if err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("GetCompensationHistoryHandler: %w", err))
+	}
+
+	var userID uint
+	if _, err := extractor.ExtractFromPathParamAs(c, "id", &userID); err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("GetCompensationHistoryHandler: %w: %v", errmgr.ErrPayload, err))
+	}
+
+	compensations, err := d.getCompensationHistory(c.Request().Context(), userID, permissions.ScopeUsers(rbac.PayrollRead, "users.id"))
+	if err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("GetCompensationHistoryHandler: %w", err))
+	}
+
+	return c.JSON(http.StatusOK, API.Response{
+		Message: "Compensation history retrieved.",
+		Data:    compensations,
+	})
+}
+
+// Adds a compensation effective now or at a given time, ending the current one.
+func (d *Domain) CreateCompensationHandler(c echo.Context) error {
+	permissions, err := middleware.LoadPermissions(c, d.params.DB)
+	if err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("CreateCompensationHandler: %w", err))
+	}
+
+	var userID uint
+	if _, err := extractor.ExtractFromPathParamAs(c, "id", &userID); err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("CreateCompensationHandler: %w: %v", errmgr.ErrPayload, err))
+	}
+
+	var req CreateCompensationRequest
+	if err := c.Bind(&req); err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("CreateCompensationHandler: %w: %v", errmgr.ErrPayload, err))
+	}
+	if err := c.Validate(&req); err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("CreateCompensationHandler: %w: %v", errmgr.ErrPayload, err))
+	}
+
+	compensation, err := req.compensation()
+	if err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("CreateCompensationHandler: %w", err))
+	}
+	compensation.StartedAt = time.Now().UTC()
+	if req.StartedAt != nil {
+		compensation.StartedAt = req.StartedAt.UTC()
+	}
+
+	result, err := d.createCompensation(c.Request().Context(), userID, compensation, permissions.ScopeUsers(rbac.PayrollWrite, "users.id"))
+	if err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("CreateCompensationHandler: %w", err))
+	}
+
+	return c.JSON(http.StatusCreated, API.Response{
+		Message: "Compensation created.",
+		Data:    result,
+	})
+}
+
+// Updates the amount and payment details of a compensation that has not started yet.
+func (d *Domain) UpdateCompensationHandler(c echo.Context) error {
+	permissions, err := middleware.LoadPermissions(c, d.params.DB)
+	if err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("UpdateCompensationHandler: %w", err))
+	}
+
+	var userID, compensationID uint
+	if _, err := extractor.ExtractFromPathParamAs(c, "id", &userID); err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("UpdateCompensationHandler: %w: %v", errmgr.ErrPayload, err))
+	}
+	if _, err := extractor.ExtractFromPathParamAs(c, "compensationId", &compensationID); err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("UpdateCompensationHandler: %w: %v", errmgr.ErrPayload, err))
+	}
+
+	var req CompensationRequest
+	if err := c.Bind(&req); err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("UpdateCompensationHandler: %w: %v", errmgr.ErrPayload, err))
+	}
+	if err := c.Validate(&req); err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("UpdateCompensationHandler: %w: %v", errmgr.ErrPayload, err))
+	}
+
+	update, err := req.compensation()
+	if err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("UpdateCompensationHandler: %w", err))
+	}
+
+	result, err := d.updateCompensation(c.Request().Context(), userID, compensationID, update, permissions.ScopeUsers(rbac.PayrollWrite, "users.id"))
+	if err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("UpdateCompensationHandler: %w", err))
+	}
+
+	return c.JSON(http.StatusOK, API.Response{
+		Message: "Compensation updated.",
+		Data:    result,
+	})
+}
+
+// Cancels a compensation that has not started yet.
+func (d *Domain) CancelCompensationHandler(c echo.Context) error {
+	permissions, err := middleware.LoadPermissions(c, d.params.DB)
+	if err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("CancelCompensationHandler: %w", err))
+	}
+
+	var userID, compensationID uint
+	if _, err := extractor.ExtractFromPathParamAs(c, "id", &userID); err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("CancelCompensationHandler: %w: %v", errmgr.ErrPayload, err))
+	}
+	if _, err := extractor.ExtractFromPathParamAs(c, "compensationId", &compensationID); err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("CancelCompensationHandler: %w: %v", errmgr.ErrPayload, err))
+	}
+
+	err = d.cancelCompensation(c.Request().Context(), userID, compensationID, permissions.ScopeUsers(rbac.PayrollWrite, "users.id"))
+	if err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("CancelCompensationHandler: %w", err))
+	}
+
+	return c.JSON(http.StatusOK, API.Response{
+		Message: "Compensation cancelled.",
+	})
+}
+
+// Lists the employees the caller may read payroll of whose compensation is outside their position's salary band.
+func (d *Domain) GetOutOfBandReportHandler(c echo.Context) error {
+	permissions, err := middleware.LoadPermissions(c, d.params.DB)
+	if err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("GetOutOfBandReportHandler: %w", err))
+	}
+
+	entries, err := d.getOutOfBandReport(c.Request().Context(), permissions.ScopeUsers(rbac.PayrollRead, "users.id"))
+	if err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("GetOutOfBandReportHandler: %w", err))
+	}
+
+	return c.JSON(http.StatusOK, API.Response{
+		Message: "Out of band report retrieved.",
+		Data:    entries,
+	})
+}
@@ -0,0 +1,468 @@
+package compensation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/alsey89/people-matter/internal/common/errmgr"
+	"github.com/alsey89/people-matter/internal/common/lookup"
+	"github.com/alsey89/people-matter/internal/common/money"
+	"github.com/alsey89/people-matter/internal/common/quota"
+	"github.com/alsey89/people-matter/internal/schema"
+	"github.com/alsey89/people-matter/pkg/pgconn"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// Returns all compensations of the employee, most recent first. The employee is looked up within the scopes.
+func (d *Domain) getCompensationHistory(ctx context.Context, userID uint, scopes ...func(*gorm.DB) *gorm.DB) ([]schema.Compensation, error) {
+	db, err := d.params.DB.GetScopedDB(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("getCompensationHistory: %w: %v", errmgr.ErrTenant, err)
+	}
+
+	_, err = lookup.FindEmployee(db, userID, false, scopes...)
+	if err != nil {
+		return nil, fmt.Errorf("getCompensationHistory: %w", err)
+	}
+
+	var compensations []schema.Compensation
+	err = db.Where("user_id = ?", userID).Order("started_at DESC").Find(&compensations).Error
+	if err != nil {
+		return nil, fmt.Errorf("getCompensationHistory: %w", err)
+	}
+
+	return compensations, nil
+}
+
+// A created or updated compensation with the salary bands it is outside of, or could not be compared with.
+type CompensationResult struct {
+	schema.Compensation
+	BandWarnings []BandCheck `json:"bandWarnings"`
+}
+
+/*
+Adds a compensation effective at its StartedAt, which may be in the future, and ends the previous compensation
+at that time. If the new compensation is already effective it becomes the active compensation right away,
+otherwise the activation job switches to it once it starts. The employee is looked up within the scopes.
+The compensation is checked against the salary bands of the employee's positions at its start, see checkBands.
+*/
+func (d *Domain) createCompensation(ctx context.Context, userID uint, compensation schema.Compensation, scopes ...func(*gorm.DB) *gorm.DB) (*CompensationResult, error) {
+	db, err := d.params.DB.GetScopedDB(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("createCompensation: %w: %v", errmgr.ErrTenant, err)
+	}
+
+	compensation.UserID = userID
+	var warnings []BandCheck
+	err = db.Transaction(func(tx *gorm.DB) error {
+		user, err := lookup.FindEmployee(tx, userID, true, scopes...)
+		if err != nil {
+			return err
+		}
+		err = requireEmployed(tx, user)
+		if err != nil {
+			return err
+		}
+
+		warnings, err = checkBands(tx, user, compensation)
+		if err != nil {
+			return err
+		}
+
+		var latest schema.Compensation
+		err = tx.Where("user_id = ?", userID).Order("started_at DESC").Limit(1).Find(&latest).Error
+		if err != nil {
+			return err
+		}
+		if latest.ID != 0 {
+			err = validateStart(compensation.StartedAt, &latest)
+			if err != nil {
+				return err
+			}
+		}
+
+		err = tx.Model(&schema.Compensation{}).
+			Where("user_id = ? AND (ended_at IS NULL OR ended_at > ?)", userID, compensation.StartedAt).
+			Update("ended_at", compensation.StartedAt).Error
+		if err != nil {
+			return err
+		}
+
+		err = tx.Create(&compensation).Error
+		if err != nil {
+			return err
+		}
+
+		_, err = syncActiveCompensations(tx, time.Now().UTC(), userScope(userID))
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("createCompensation: %w", err)
+	}
+
+	return &CompensationResult{Compensation: compensation, BandWarnings: warnings}, nil
+}
+
+/*
+Replaces the amount and payment details of a compensation that has not started yet.
+Its period is kept, cancel it and create a new one to change the start. The employee is looked up within the scopes.
+*/
+func (d *Domain) updateCompensation(ctx context.Context, userID uint, compensationID uint, update schema.Compensation, scopes ...func(*gorm.DB) *gorm.DB) (*CompensationResult, error) {
+	db, err := d.params.DB.GetScopedDB(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("updateCompensation: %w: %v", errmgr.ErrTenant, err)
+	}
+
+	var compensation schema.Compensation
+	var warnings []BandCheck
+	err = db.Transaction(func(tx *gorm.DB) error {
+		user, err := lookup.FindEmployee(tx, userID, true, scopes...)
+		if err != nil {
+			return err
+		}
+
+		err = findUpcomingCompensation(tx, userID, compensationID, &compensation)
+		if err != nil {
+			return err
+		}
+
+		compensation.Amount = update.Amount
+		compensation.Currency = update.Currency
+		compensation.Interval = update.Interval
+		compensation.ChannelType = update.ChannelType
+		compensation.ChannelCode = update.ChannelCode
+		compensation.Account = update.Account
+
+		warnings, err = checkBands(tx, user, compensation)
+		if err != nil {
+			return err
+		}
+
+		return tx.Model(&compensation).Updates(map[string]interface{}{
+			"amount":       compensation.Amount,
+			"currency":     compensation.Currency,
+			"interval":     compensation.Interval,
+			"channel_type": compensation.ChannelType,
+			"channel_code": compensation.ChannelCode,
+			"account":      compensation.Account,
+		}).Error
+	})
+	if err != nil {
+		return nil, fmt.Errorf("updateCompensation: %w", err)
+	}
+
+	return &CompensationResult{Compensation: compensation, BandWarnings: warnings}, nil
+}
+
+/*
+Removes a compensation that has not started yet. The compensation before it is extended to the end
+of the removed one, so the history stays without gaps. The employee is looked up within the scopes.
+*/
+func (d *Domain) cancelCompensation(ctx context.Context, userID uint, compensationID uint, scopes ...func(*gorm.DB) *gorm.DB) error {
+	db, err := d.params.DB.GetScopedDB(ctx)
+	if err != nil {
+		return fmt.Errorf("cancelCompensation: %w: %v", errmgr.ErrTenant, err)
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		_, err := lookup.FindEmployee(tx, userID, true, scopes...)
+		if err != nil {
+			return err
+		}
+
+		var compensation schema.Compensation
+		err = findUpcomingCompensation(tx, userID, compensationID, &compensation)
+		if err != nil {
+			return err
+		}
+
+		err = tx.Model(&schema.Compensation{}).
+			Where("user_id = ? AND ended_at = ?", userID, compensation.StartedAt).
+			Update("ended_at", compensation.EndedAt).Error
+		if err != nil {
+			return err
+		}
+
+		err = tx.Delete(&compensation).Error
+		if err != nil {
+			return err
+		}
+
+		_, err = syncActiveCompensations(tx, time.Now().UTC(), userScope(userID))
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("cancelCompensation: %w", err)
+	}
+
+	return nil
+}
+
+// An employee whose active compensation is outside the salary band of one of their current positions.
+type OutOfBandEntry struct {
+	UserID         uint   `json:"userId"`
+	Name           string `json:"name"`
+	Email          string `json:"email"`
+	CompensationID uint   `json:"compensationId"`
+	// the compensation as stored, BandCheck.Amount is normalized to the band
+	CompensationAmount   money.Money `json:"compensationAmount"`
+	CompensationInterval string      `json:"compensationInterval"`
+	BandCheck
+}
+
+/*
+Lists the employees within the scopes whose active compensation is below or above the salary band of a position
+they currently hold, one entry per position, ordered by employee name.
+Compensations that cannot be converted to the band's currency are not listed.
+*/
+func (d *Domain) getOutOfBandReport(ctx context.Context, scopes ...func(*gorm.DB) *gorm.DB) ([]OutOfBandEntry, error) {
+	db, err := d.params.DB.GetScopedDB(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("getOutOfBandReport: %w: %v", errmgr.ErrTenant, err)
+	}
+
+	var users []schema.User
+	err = db.Scopes(scopes...).Where("users.active_compensation_id IS NOT NULL").Order("users.name, users.id").Find(&users).Error
+	if err != nil {
+		return nil, fmt.Errorf("getOutOfBandReport: %w", err)
+	}
+	if len(users) == 0 {
+		return []OutOfBandEntry{}, nil
+	}
+
+	userIDs := make([]uint, 0, len(users))
+	compensationIDs := make([]uint, 0, len(users))
+	for _, user := range users {
+		userIDs = append(userIDs, user.ID)
+		compensationIDs = append(compensationIDs, *user.ActiveCompensationID)
+	}
+
+	var compensations []schema.Compensation
+	err = db.Where("id IN ?", compensationIDs).Find(&compensations).Error
+	if err != nil {
+		return nil, fmt.Errorf("getOutOfBandReport: %w", err)
+	}
+	compensationsByID := make(map[uint]schema.Compensation, len(compensations))
+	for _, compensation := range compensations {
+		compensationsByID[compensation.ID] = compensation
+	}
+
+	now := time.Now().UTC()
+	var userPositions []schema.UserPosition
+	err = db.Preload("Position").
+		Where("user_id IN ? AND started_at <= ? AND (ended_at IS NULL OR ended_at > ?)", userIDs, now, now).
+		Order("started_at").
+		Find(&userPositions).Error
+	if err != nil {
+		return nil, fmt.Errorf("getOutOfBandReport: %w", err)
+	}
+	positionsByUser := map[uint][]schema.Position{}
+	for _, userPosition := range userPositions {
+		positionsByUser[userPosition.UserID] = append(positionsByUser[userPosition.UserID], userPosition.Position)
+	}
+
+	rates, err := money.LoadRates(db)
+	if err != nil {
+		return nil, fmt.Errorf("getOutOfBandReport: %w", err)
+	}
+
+	entries := []OutOfBandEntry{}
+	for _, user := range users {
+		compensation, ok := compensationsByID[*user.ActiveCompensationID]
+		if !ok {
+			continue
+		}
+		for _, position := range positionsByUser[user.ID] {
+			if !hasBand(position) {
+				continue
+			}
+			check, err := checkBand(compensation, position, rates)
+			if err != nil {
+				return nil, fmt.Errorf("getOutOfBandReport: %w", err)
+			}
+			if !check.outOfBand() {
+				continue
+			}
+			entries = append(entries, OutOfBandEntry{
+				UserID:               user.ID,
+				Name:                 user.Name,
+				Email:                user.Email,
+				CompensationID:       compensation.ID,
+				CompensationAmount:   compensation.Amount,
+				CompensationInterval: compensation.Interval,
+				BandCheck:            check,
+			})
+		}
+	}
+
+	return entries, nil
+}
+
+// ! Activation Job ---------------------------------------------------------------
+
+// Activates due compensations every interval until the domain stops, starting right away.
+func (d *Domain) runActivationJob(interval time.Duration) {
+	defer close(d.done)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		<-d.stop
+		cancel()
+	}()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		updated, err := d.activateCompensations(ctx, time.Now().UTC())
+		if err != nil {
+			d.logger.Error("runActivationJob: failed to activate compensations", zap.Error(err))
+		} else if updated > 0 {
+			d.logger.Info("Active compensations updated.", zap.Int64("users", updated))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+/*
+Syncs User.ActiveCompensationID of every company to the compensations covering the given time,
+which activates future dated compensations once they start and clears ended ones.
+A failing company does not stop the others, the errors are joined. Returns the number of users updated.
+*/
+func (d *Domain) activateCompensations(ctx context.Context, at time.Time) (int64, error) {
+	var companyIDs []uint
+	err := d.params.DB.GetDB().WithContext(ctx).Model(&schema.Company{}).Order("id").Pluck("id", &companyIDs).Error
+	if err != nil {
+		return 0, fmt.Errorf("activateCompensations: %w", err)
+	}
+
+	var total int64
+	var errs []error
+	for _, companyID := range companyIDs {
+		err := d.params.DB.WithCompanyTransaction(pgconn.WithCompanyID(ctx, companyID), func(ctx context.Context) error {
+			db, err := d.params.DB.GetScopedDB(ctx)
+			if err != nil {
+				return err
+			}
+			updated, err := syncActiveCompensations(db, at)
+			total += updated
+			return err
+		})
+		if err != nil {
+			errs = append(errs, fmt.Errorf("company %d: %w", companyID, err))
+		}
+	}
+	if len(errs) > 0 {
+		return total, fmt.Errorf("activateCompensations: %w", errors.Join(errs...))
+	}
+
+	return total, nil
+}
+
+// ! Helpers ---------------------------------------------------------------
+
+// Fails with errmgr.ErrOffboarded if the employee was offboarded and not reassigned a position since.
+func requireEmployed(tx *gorm.DB, user *schema.User) error {
+	if user.SessionsRevokedAt == nil {
+		return nil
+	}
+
+	var active int64
+	err := tx.Model(&schema.User{}).Scopes(quota.ActiveEmployees).Where("id = ?", user.ID).Count(&active).Error
+	if err != nil {
+		return fmt.Errorf("requireEmployed: %w", err)
+	}
+	if active == 0 {
+		return fmt.Errorf("requireEmployed: %w", errmgr.ErrOffboarded)
+	}
+	return nil
+}
+
+// Loads the compensation of the employee into compensation, failing with errmgr.ErrCompensationStarted if it already started.
+func findUpcomingCompensation(tx *gorm.DB, userID uint, compensationID uint, compensation *schema.Compensation) error {
+	err := tx.Where("id = ? AND user_id = ?", compensationID, userID).First(compensation).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return fmt.Errorf("findUpcomingCompensation: %w", errmgr.ErrCompensationNotFound)
+		}
+		return fmt.Errorf("findUpcomingCompensation: %w", err)
+	}
+	if !compensation.StartedAt.After(time.Now()) {
+		return fmt.Errorf("findUpcomingCompensation: %w: started at %s", errmgr.ErrCompensationStarted, compensation.StartedAt.Format(time.RFC3339))
+	}
+	return nil
+}
+
+/*
+Checks the compensation against the salary bands of the positions the employee holds at its start.
+Returns the checks that are out of band or could not be converted to the band's currency.
+If the company's salary band policy is error, out of band compensations fail with errmgr.ErrSalaryOutOfBand instead.
+*/
+func checkBands(tx *gorm.DB, user *schema.User, compensation schema.Compensation) ([]BandCheck, error) {
+	at := compensation.StartedAt
+	var positions []schema.Position
+	err := tx.Model(&schema.Position{}).
+		Joins("JOIN user_positions ON user_positions.position_id = positions.id AND user_positions.deleted_at IS NULL").
+		Where("user_positions.user_id = ? AND ?", user.ID, schema.ActiveUserPositions("user_positions", at)).
+		Distinct().
+		Find(&positions).Error
+	if err != nil {
+		return nil, fmt.Errorf("checkBands: %w", err)
+	}
+
+	rates, err := money.LoadRates(tx)
+	if err != nil {
+		return nil, fmt.Errorf("checkBands: %w", err)
+	}
+
+	warnings := []BandCheck{}
+	outOfBand := false
+	for _, position := range positions {
+		if !hasBand(position) {
+			continue
+		}
+		check, err := checkBand(compensation, position, rates)
+		if err != nil {
+			return nil, fmt.Errorf("checkBands: %w: %v", errmgr.ErrPayload, err)
+		}
+		if check.Status == BandStatusWithin {
+			continue
+		}
+		warnings = append(warnings, check)
+		outOfBand = outOfBand || check.outOfBand()
+	}
+	if !outOfBand {
+		return warnings, nil
+	}
+
+	var company schema.Company
+	err = tx.Select("id", "salary_band_policy").Where("id = ?", user.CompanyID).First(&company).Error
+	if err != nil {
+		return nil, fmt.Errorf("checkBands: %w", err)
+	}
+	if company.SalaryBandPolicy == schema.SalaryBandPolicyError {
+		for _, check := range warnings {
+			if check.outOfBand() {
+				return nil, fmt.Errorf("checkBands: %w: %s band of position %q", errmgr.ErrSalaryOutOfBand, check.Status, check.PositionName)
+			}
+		}
+	}
+
+	return warnings, nil
+}
+
+func userScope(userID uint) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("users.id = ?", userID)
+	}
+}
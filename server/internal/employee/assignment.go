@@ -0,0 +1,55 @@
+package employee
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/alsey89/people-matter/internal/common/errmgr"
+)
+
+// Assignments are half open periods [StartedAt, EndedAt), a nil EndedAt has no end date yet.
+// So a transfer ending one assignment and starting the next at the same instant does not overlap.
+
+// Returns t if set, otherwise now. Times are stored in UTC.
+func effectiveAt(t *time.Time, now time.Time) time.Time {
+	if t == nil {
+		return now.UTC()
+	}
+	return t.UTC()
+}
+
+// Fails with errmgr.ErrPayload unless the period ends after it starts.
+func validatePeriod(start time.Time, end *time.Time) error {
+	if end != nil && !end.After(start) {
+		return fmt.Errorf("validatePeriod: %w: end %s is not after start %s", errmgr.ErrPayload, end.Format(time.RFC3339), start.Format(time.RFC3339))
+	}
+	return nil
+}
+
+/*
+Fails unless an assignment for [start, end) can end at the given time: errmgr.ErrAssignmentEnded if it
+already ended by then, errmgr.ErrPayload if the time is not after its start.
+*/
+func validateEnd(start time.Time, end *time.Time, at time.Time) error {
+	if end != nil && !end.After(at) {
+		return fmt.Errorf("validateEnd: %w: ended at %s", errmgr.ErrAssignmentEnded, end.Format(time.RFC3339))
+	}
+	if !at.After(start) {
+		return fmt.Errorf("validateEnd: %w: %s is not after the start %s", errmgr.ErrPayload, at.Format(time.RFC3339), start.Format(time.RFC3339))
+	}
+	return nil
+}
+
+/*
+Fails with errmgr.ErrPayload unless an offboarding at endedAt with the final payment at finalPaymentAt
+is valid: the employee cannot be offboarded in the future, and cannot be paid before leaving.
+*/
+func validateOffboarding(endedAt time.Time, finalPaymentAt time.Time, now time.Time) error {
+	if endedAt.After(now) {
+		return fmt.Errorf("validateOffboarding: %w: end %s is in the future", errmgr.ErrPayload, endedAt.Format(time.RFC3339))
+	}
+	if finalPaymentAt.Before(endedAt) {
+		return fmt.Errorf("validateOffboarding: %w: final payment %s is before the end %s", errmgr.ErrPayload, finalPaymentAt.Format(time.RFC3339), endedAt.Format(time.RFC3339))
+	}
+	return nil
+}
@@ -0,0 +1,51 @@
+package employee
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alsey89/people-matter/internal/common/errmgr"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEffectiveAt(t *testing.T) {
+	now := time.Date(2024, 3, 4, 12, 0, 0, 0, time.UTC)
+	set := time.Date(2024, 3, 5, 9, 0, 0, 0, time.FixedZone("UTC+2", 2*60*60))
+
+	assert.Equal(t, now, effectiveAt(nil, now))
+	assert.Equal(t, time.Date(2024, 3, 5, 7, 0, 0, 0, time.UTC), effectiveAt(&set, now))
+}
+
+func TestValidatePeriod(t *testing.T) {
+	start := time.Date(2024, 3, 4, 0, 0, 0, 0, time.UTC)
+	later := start.Add(time.Hour)
+
+	assert.NoError(t, validatePeriod(start, nil))
+	assert.NoError(t, validatePeriod(start, &later))
+	assert.ErrorIs(t, validatePeriod(start, &start), errmgr.ErrPayload)
+	assert.ErrorIs(t, validatePeriod(later, &start), errmgr.ErrPayload)
+}
+
+func TestValidateEnd(t *testing.T) {
+	start := time.Date(2024, 3, 4, 0, 0, 0, 0, time.UTC)
+	at := start.Add(24 * time.Hour)
+	before := at.Add(-time.Hour)
+	after := at.Add(time.Hour)
+
+	assert.NoError(t, validateEnd(start, nil, at))
+	assert.NoError(t, validateEnd(start, &after, at), "ending earlier than planned")
+	assert.ErrorIs(t, validateEnd(start, &before, at), errmgr.ErrAssignmentEnded)
+	assert.ErrorIs(t, validateEnd(start, &at, at), errmgr.ErrAssignmentEnded)
+	assert.ErrorIs(t, validateEnd(start, nil, start), errmgr.ErrPayload)
+}
+
+func TestValidateOffboarding(t *testing.T) {
+	now := time.Date(2024, 3, 4, 12, 0, 0, 0, time.UTC)
+	earlier := now.Add(-24 * time.Hour)
+
+	assert.NoError(t, validateOffboarding(now, now, now))
+	assert.NoError(t, validateOffboarding(earlier, now.Add(24*time.Hour), now), "final payment may be scheduled")
+	assert.ErrorIs(t, validateOffboarding(now.Add(time.Hour), now.Add(time.Hour), now), errmgr.ErrPayload)
+	assert.ErrorIs(t, validateOffboarding(now, earlier, now), errmgr.ErrPayload)
+}
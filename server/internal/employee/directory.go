@@ -0,0 +1,89 @@
+package employee
+
+import (
+	"strings"
+	"time"
+
+	"github.com/alsey89/people-matter/internal/common/API"
+	"github.com/alsey89/people-matter/internal/common/util"
+	"github.com/alsey89/people-matter/internal/schema"
+
+	"gorm.io/gorm"
+)
+
+// Assignment filters of the employee directory.
+const (
+	StatusActive = "active" // holds a matching assignment that has started and not ended
+	StatusEnded  = "ended"  // held matching assignments, all of which have ended
+)
+
+// Filters, sorting and paging of the employee directory, all optional.
+type DirectoryFilter struct {
+	API.PageQuery
+	LocationID    *uint  `query:"locationId"`
+	PositionID    *uint  `query:"positionId"`
+	Status        string `query:"status"        validate:"omitempty,oneof=active ended"`
+	EmailVerified *bool  `query:"emailVerified"`
+	Search        string `query:"search"        validate:"omitempty,max=255"`
+	Sort          string `query:"sort"          validate:"omitempty,oneof=name -name email -email createdAt -createdAt"`
+}
+
+// Order clauses for the sort query parameter, a leading "-" sorts descending.
+var directoryOrders = map[string]string{
+	"":           "users.name ASC, users.id ASC",
+	"name":       "users.name ASC, users.id ASC",
+	"-name":      "users.name DESC, users.id DESC",
+	"email":      "users.email ASC, users.id ASC",
+	"-email":     "users.email DESC, users.id DESC",
+	"createdAt":  "users.created_at ASC, users.id ASC",
+	"-createdAt": "users.created_at DESC, users.id DESC",
+}
+
+/*
+Returns a GORM scope applying the filter, but not the sorting and paging, to a query on users.
+The location, position and status filters apply to the same assignment, e.g. location 1 with status
+ended matches users whose assignments at location 1 have all ended.
+Assignments are matched with EXISTS subqueries, so every user appears once regardless of how many
+assignments match.
+*/
+func (f DirectoryFilter) scope(now time.Time) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if f.EmailVerified != nil {
+			db = db.Where("users.email_verified = ?", *f.EmailVerified)
+		}
+
+		if search := strings.TrimSpace(f.Search); search != "" {
+			pattern := util.ContainsPattern(search)
+			db = db.Where("(users.name ILIKE ? OR users.email ILIKE ?)", pattern, pattern)
+		}
+
+		if f.LocationID == nil && f.PositionID == nil && f.Status == "" {
+			return db
+		}
+
+		conditions := []string{"up.user_id = users.id", "up.deleted_at IS NULL"}
+		// assignments ending in the future are not ended yet, active ones also started, like Assignment.activeAt
+		args := map[string]interface{}{
+			"open":   schema.OpenUserPositions("up", now),
+			"active": schema.ActiveUserPositions("up", now),
+		}
+		if f.LocationID != nil {
+			conditions = append(conditions, "up.location_id = @locationID")
+			args["locationID"] = *f.LocationID
+		}
+		if f.PositionID != nil {
+			conditions = append(conditions, "up.position_id = @positionID")
+			args["positionID"] = *f.PositionID
+		}
+		matching := "SELECT 1 FROM user_positions up WHERE " + strings.Join(conditions, " AND ")
+
+		switch f.Status {
+		case StatusActive:
+			return db.Where("EXISTS ("+matching+" AND @active)", args)
+		case StatusEnded:
+			return db.Where("EXISTS ("+matching+") AND NOT EXISTS ("+matching+" AND @open)", args)
+		default:
+			return db.Where("EXISTS ("+matching+")", args)
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package employee
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alsey89/people-matter/internal/schema"
+	"github.com/alsey89/people-matter/pkg/pgconn/pgtest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func directorySQL(t *testing.T, filter DirectoryFilter) string {
+	var users []schema.User
+	now := time.Date(2024, 3, 4, 12, 0, 0, 0, time.UTC)
+	return pgtest.SQL(pgtest.NewDryRunDB(t).Scopes(filter.scope(now)).Find(&users))
+}
+
+func TestDirectoryFilterScope(t *testing.T) {
+	verified := true
+	locationID := uint(4)
+
+	sql := directorySQL(t, DirectoryFilter{})
+	assert.NotContains(t, sql, "user_positions")
+	assert.NotContains(t, sql, "users.email_verified")
+
+	sql = directorySQL(t, DirectoryFilter{EmailVerified: &verified, Search: "50%"})
+	assert.Contains(t, sql, "users.email_verified = true")
+	assert.Contains(t, sql, `(users.name ILIKE '%50\%%' OR users.email ILIKE '%50\%%')`)
+
+	sql = directorySQL(t, DirectoryFilter{LocationID: &locationID, Status: StatusActive})
+	assert.Contains(t, sql, "EXISTS (SELECT 1 FROM user_positions up WHERE up.user_id = users.id AND up.deleted_at IS NULL AND up.location_id = 4")
+	assert.Contains(t, sql, "(up.started_at <= '2024-03-04 12:00:00'")
+	assert.Contains(t, sql, "(up.ended_at IS NULL OR up.ended_at > '2024-03-04 12:00:00')")
+	assert.NotContains(t, sql, "NOT EXISTS")
+
+	sql = directorySQL(t, DirectoryFilter{Status: StatusEnded})
+	assert.Contains(t, sql, "AND NOT EXISTS (")
+	assert.NotContains(t, sql, "up.started_at", "assignments that have not started yet have not ended either")
+}
+
+func TestAssignmentActiveAt(t *testing.T) {
+	now := time.Date(2024, 3, 4, 12, 0, 0, 0, time.UTC)
+	past := now.Add(-time.Hour)
+	future := now.Add(time.Hour)
+
+	assert.True(t, Assignment{StartedAt: past}.activeAt(now))
+	assert.True(t, Assignment{StartedAt: past, EndedAt: &future}.activeAt(now))
+	assert.False(t, Assignment{StartedAt: past, EndedAt: &past}.activeAt(now))
+	assert.False(t, Assignment{StartedAt: future}.activeAt(now), "not started yet")
+}
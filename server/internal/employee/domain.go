@@ -0,0 +1,176 @@
+package employee
+
+import (
+	"context"
+
+	"github.com/alsey89/people-matter/internal/common/middleware"
+	"github.com/alsey89/people-matter/internal/common/rbac"
+	"github.com/alsey89/people-matter/internal/common/util"
+	"github.com/alsey89/people-matter/internal/identity"
+	"github.com/alsey89/people-matter/internal/transmail"
+	"github.com/alsey89/people-matter/pkg/pgconn"
+	"github.com/alsey89/people-matter/pkg/server"
+	"github.com/alsey89/people-matter/pkg/token"
+
+	"github.com/labstack/echo/v4"
+	"github.com/spf13/viper"
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+)
+
+type Domain struct {
+	scope  string
+	logger *zap.Logger
+	config *Config
+	params Params
+}
+
+type Params struct {
+	fx.In
+	Lifecycle fx.Lifecycle
+	Logger    *zap.Logger
+	DB        *pgconn.Module
+	Server    *server.Module
+	Token     *token.Module
+	Transmail *transmail.Domain
+}
+
+type Config struct {
+	clientDomain string
+
+	invitationTemplateID int
+	invitationURLPath    string
+	invitationTTLHours   int
+
+	offboardingTemplateID        int
+	offboardingManagerTemplateID int
+}
+
+// Token scopes used by this domain, must be registered with the token module.
+const (
+	InvitationTokenScope = "invitation"
+)
+
+const (
+	defaultClientDomain = "localhost:3000"
+
+	defaultInvitationTemplateID = 0
+	defaultInvitationURLPath    = "/auth/accept-invitation"
+	defaultInvitationTTLHours   = 168
+
+	defaultOffboardingTemplateID        = 0
+	defaultOffboardingManagerTemplateID = 0
+)
+
+// ! Domain ---------------------------------------------------------------
+
+func InjectDomain(scope string) fx.Option {
+	return fx.Module(
+		scope,
+		fx.Provide(func(p Params) *Domain {
+			d := &Domain{scope: scope}
+			d.params = p
+			d.logger = d.setupLogger(scope, p)
+			d.config = d.setupConfig(scope)
+
+			return d
+		}),
+		fx.Invoke(func(d *Domain, p Params) {
+			d.registerRoutes()
+
+			p.Lifecycle.Append(
+				fx.Hook{
+					OnStart: d.onStart,
+					OnStop:  d.onStop,
+				},
+			)
+		}),
+	)
+}
+
+// ! Internal ---------------------------------------------------------------
+
+func (d *Domain) setupLogger(scope string, p Params) *zap.Logger {
+	logger := p.Logger.Named("[" + scope + "]")
+	return logger
+}
+
+func (d *Domain) setupConfig(scope string) *Config {
+	viper.SetDefault(util.GetConfigPath("global", "client_domain"), defaultClientDomain)
+	viper.SetDefault(util.GetConfigPath(scope, "invitation_template_id"), defaultInvitationTemplateID)
+	viper.SetDefault(util.GetConfigPath(scope, "invitation_url_path"), defaultInvitationURLPath)
+	viper.SetDefault(util.GetConfigPath(scope, "invitation_ttl_hours"), defaultInvitationTTLHours)
+	viper.SetDefault(util.GetConfigPath(scope, "offboarding_template_id"), defaultOffboardingTemplateID)
+	viper.SetDefault(util.GetConfigPath(scope, "offboarding_manager_template_id"), defaultOffboardingManagerTemplateID)
+
+	return &Config{
+		clientDomain: viper.GetString(util.GetConfigPath("global", "client_domain")),
+
+		invitationTemplateID: viper.GetInt(util.GetConfigPath(scope, "invitation_template_id")),
+		invitationURLPath:    viper.GetString(util.GetConfigPath(scope, "invitation_url_path")),
+		invitationTTLHours:   viper.GetInt(util.GetConfigPath(scope, "invitation_ttl_hours")),
+
+		offboardingTemplateID:        viper.GetInt(util.GetConfigPath(scope, "offboarding_template_id")),
+		offboardingManagerTemplateID: viper.GetInt(util.GetConfigPath(scope, "offboarding_manager_template_id")),
+	}
+}
+
+func (d *Domain) registerRoutes() {
+	e := d.params.Server.GetServer()
+
+	resolveTenant := middleware.ResolveTenant(d.params.DB, d.logger, d.config.clientDomain)
+	requireAuth := d.params.Token.GetJWTMiddleware(identity.AuthTokenScope)
+	requireSameTenant := middleware.RequireSameTenant(d.logger)
+	requireActiveSession := middleware.RequireActiveSession(d.params.DB, d.logger)
+
+	// the locationId query parameter makes RequirePermission check the grant at that location
+	employeeGroup := e.Group("/api/v1/employees", resolveTenant, requireAuth, requireSameTenant, requireActiveSession)
+	employeeGroup.GET("", d.ListEmployeesHandler, d.requirePermission(rbac.EmployeesRead))
+	employeeGroup.GET("/:id", d.GetEmployeeHandler, d.requirePermission(rbac.EmployeesRead))
+	employeeGroup.POST("/:id/offboard", d.OffboardEmployeeHandler, d.requirePermission(rbac.EmployeesWrite))
+
+	assignmentGroup := employeeGroup.Group("/:id/assignments")
+	assignmentGroup.GET("", d.GetAssignmentHistoryHandler, d.requirePermission(rbac.EmployeesRead))
+	assignmentGroup.POST("", d.AssignPositionHandler, d.requirePermission(rbac.EmployeesWrite))
+	assignmentGroup.POST("/:assignmentId/transfer", d.TransferPositionHandler, d.requirePermission(rbac.EmployeesWrite))
+	assignmentGroup.POST("/:assignmentId/end", d.EndPositionHandler, d.requirePermission(rbac.EmployeesWrite))
+
+	invitationGroup := employeeGroup.Group("/invitations")
+	invitationGroup.GET("", d.ListInvitationsHandler, d.requirePermission(rbac.EmployeesRead))
+	invitationGroup.POST("", d.CreateInvitationHandler, d.requirePermission(rbac.EmployeesWrite))
+	invitationGroup.POST("/:id/resend", d.ResendInvitationHandler, d.requirePermission(rbac.EmployeesWrite))
+	invitationGroup.DELETE("/:id", d.RevokeInvitationHandler, d.requirePermission(rbac.EmployeesWrite))
+
+	// the invitee has no account yet, the invite token authenticates the request
+	e.POST("/api/v1/invitations/accept", d.AcceptInvitationHandler, resolveTenant)
+}
+
+func (d *Domain) requirePermission(permission string) echo.MiddlewareFunc {
+	return middleware.RequirePermission(d.params.DB, d.logger, permission)
+}
+
+func (d *Domain) onStart(ctx context.Context) error {
+	d.logger.Info("Starting employee domain.")
+
+	if viper.GetString("global.log_level") == "DEBUG" || viper.GetString("global.log_level") == "debug" {
+		d.logConfigurations()
+	}
+
+	return nil
+}
+
+func (d *Domain) onStop(ctx context.Context) error {
+	d.logger.Info("Stopping employee domain.")
+	return nil
+}
+
+func (d *Domain) logConfigurations() {
+	d.logger.Debug("----- Employee Configuration -----")
+	d.logger.Debug("Client Domain", zap.String("client_domain", d.config.clientDomain))
+	d.logger.Debug("Invitation Template ID", zap.Int("invitation_template_id", d.config.invitationTemplateID))
+	d.logger.Debug("Invitation URL Path", zap.String("invitation_url_path", d.config.invitationURLPath))
+	d.logger.Debug("Invitation TTL Hours", zap.Int("invitation_ttl_hours", d.config.invitationTTLHours))
+	d.logger.Debug("Offboarding Template ID", zap.Int("offboarding_template_id", d.config.offboardingTemplateID))
+	d.logger.Debug("Offboarding Manager Template ID", zap.Int("offboarding_manager_template_id", d.config.offboardingManagerTemplateID))
+	d.logger.Debug("----------------------------------")
+}
@@ -0,0 +1,463 @@
+package employee
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/alsey89/people-matter/internal/common/API"
+	"github.com/alsey89/people-matter/internal/common/errmgr"
+	"github.com/alsey89/people-matter/internal/common/extractor"
+	"github.com/alsey89/people-matter/internal/common/middleware"
+	"github.com/alsey89/people-matter/internal/common/rbac"
+	"github.com/alsey89/people-matter/pkg/pgconn"
+
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+type AssignPositionRequest struct {
+	PositionID uint       `json:"positionId" validate:"required"`
+	LocationID uint       `json:"locationId" validate:"required"`
+	StartedAt  *time.Time `json:"startedAt"` // defaults to now
+	EndedAt    *time.Time `json:"endedAt"`
+}
+
+type TransferPositionRequest struct {
+	LocationID  uint       `json:"locationId"  validate:"required"`
+	PositionID  *uint      `json:"positionId"`  // defaults to the current position
+	EffectiveAt *time.Time `json:"effectiveAt"` // defaults to now
+}
+
+type EndPositionRequest struct {
+	EndedAt *time.Time `json:"endedAt"` // defaults to now
+}
+
+type OffboardEmployeeRequest struct {
+	EndedAt        *time.Time `json:"endedAt"`        // defaults to now
+	FinalPaymentAt *time.Time `json:"finalPaymentAt"` // defaults to the end
+	ManagerID      *uint      `json:"managerId"`      // notified of the offboarding, defaults to the requester
+}
+
+type CreateInvitationRequest struct {
+	Name  string `json:"name"  validate:"required,max=255"`
+	Email string `json:"email" validate:"required,email,max=255"`
+}
+
+type ListInvitationsQuery struct {
+	API.PageQuery
+	Status string `query:"status" validate:"omitempty,oneof=pending accepted revoked"`
+}
+
+type AcceptInvitationRequest struct {
+	Password string `json:"password" validate:"required,min=8,max=72"`
+}
+
+// Lists the employees the caller may read, see DirectoryFilter for the query parameters.
+func (d *Domain) ListEmployeesHandler(c echo.Context) error {
+	permissions, err := middleware.LoadPermissions(c, d.params.DB)
+	if err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("ListEmployeesHandler: %w", err))
+	}
+
+	var filter DirectoryFilter
+	if err := c.Bind(&filter); err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("ListEmployeesHandler: %w: %v", errmgr.ErrPayload, err))
+	}
+	if err := c.Validate(&filter); err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("ListEmployeesHandler: %w: %v", errmgr.ErrPayload, err))
+	}
+
+	employees, total, err := d.listEmployees(c.Request().Context(), filter, permissions.ScopeUsers(rbac.EmployeesRead, "users.id"))
+	if err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("ListEmployeesHandler: %w", err))
+	}
+
+	return c.JSON(http.StatusOK, API.Response{
+		Message:    "Employees retrieved.",
+		Data:       employees,
+		Pagination: filter.Pagination(total),
+	})
+}
+
+// Returns an employee assigned to a location the caller may read, with their assignment history.
+func (d *Domain) GetEmployeeHandler(c echo.Context) error {
+	permissions, err := middleware.LoadPermissions(c, d.params.DB)
+	if err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("GetEmployeeHandler: %w", err))
+	}
+
+	var userID uint
+	if _, err := extractor.ExtractFromPathParamAs(c, "id", &userID); err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("GetEmployeeHandler: %w: %v", errmgr.ErrPayload, err))
+	}
+
+	employee, err := d.getEmployee(c.Request().Context(), userID, permissions.ScopeUsers(rbac.EmployeesRead, "users.id"))
+	if err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("GetEmployeeHandler: %w", err))
+	}
+
+	return c.JSON(http.StatusOK, API.Response{
+		Message: "Employee retrieved.",
+		Data:    employee,
+	})
+}
+
+// Returns the position assignments of an employee the caller may read, oldest first.
+func (d *Domain) GetAssignmentHistoryHandler(c echo.Context) error {
+	permissions, err := middleware.LoadPermissions(c, d.params.DB)
+	if err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("GetAssignmentHistoryHandler: %w", err))
+	}
+
+	var userID uint
+	if _, err := extractor.ExtractFromPathParamAs(c, "id", &userID); err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("GetAssignmentHistoryHandler: %w: %v", errmgr.ErrPayload, err))
+	}
+
+	assignments, err := d.getAssignmentHistory(c.Request().Context(), userID, permissions.ScopeUsers(rbac.EmployeesRead, "users.id"))
+	if err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("GetAssignmentHistoryHandler: %w", err))
+	}
+
+	return c.JSON(http.StatusOK, API.Response{
+		Message: "Assignment history retrieved.",
+		Data:    assignments,
+	})
+}
+
+// Assigns a position at a location to an employee, which needs the write permission at that location.
+func (d *Domain) AssignPositionHandler(c echo.Context) error {
+	permissions, err := middleware.LoadPermissions(c, d.params.DB)
+	if err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("AssignPositionHandler: %w", err))
+	}
+
+	var userID uint
+	if _, err := extractor.ExtractFromPathParamAs(c, "id", &userID); err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("AssignPositionHandler: %w: %v", errmgr.ErrPayload, err))
+	}
+
+	var req AssignPositionRequest
+	if err := c.Bind(&req); err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("AssignPositionHandler: %w: %v", errmgr.ErrPayload, err))
+	}
+	if err := c.Validate(&req); err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("AssignPositionHandler: %w: %v", errmgr.ErrPayload, err))
+	}
+
+	if !permissions.HasAt(rbac.EmployeesWrite, req.LocationID) {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("AssignPositionHandler: %w: missing %s at location %d", errmgr.ErrPermission, rbac.EmployeesWrite, req.LocationID))
+	}
+
+	var endedAt *time.Time
+	if req.EndedAt != nil {
+		end := req.EndedAt.UTC()
+		endedAt = &end
+	}
+
+	assignment, err := d.assignPosition(c.Request().Context(), userID, req.PositionID, req.LocationID, effectiveAt(req.StartedAt, time.Now()), endedAt)
+	if err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("AssignPositionHandler: %w", err))
+	}
+
+	return c.JSON(http.StatusCreated, API.Response{
+		Message: "Position assigned.",
+		Data:    assignment,
+	})
+}
+
+/*
+Ends an assignment and starts one at another location, optionally in another position.
+Needs the write permission at both the current and the new location.
+*/
+func (d *Domain) TransferPositionHandler(c echo.Context) error {
+	permissions, err := middleware.LoadPermissions(c, d.params.DB)
+	if err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("TransferPositionHandler: %w", err))
+	}
+
+	var userID, assignmentID uint
+	if _, err := extractor.ExtractFromPathParamAs(c, "id", &userID); err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("TransferPositionHandler: %w: %v", errmgr.ErrPayload, err))
+	}
+	if _, err := extractor.ExtractFromPathParamAs(c, "assignmentId", &assignmentID); err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("TransferPositionHandler: %w: %v", errmgr.ErrPayload, err))
+	}
+
+	var req TransferPositionRequest
+	if err := c.Bind(&req); err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("TransferPositionHandler: %w: %v", errmgr.ErrPayload, err))
+	}
+	if err := c.Validate(&req); err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("TransferPositionHandler: %w: %v", errmgr.ErrPayload, err))
+	}
+
+	if !permissions.HasAt(rbac.EmployeesWrite, req.LocationID) {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("TransferPositionHandler: %w: missing %s at location %d", errmgr.ErrPermission, rbac.EmployeesWrite, req.LocationID))
+	}
+
+	assignment, err := d.transferPosition(
+		c.Request().Context(),
+		userID,
+		assignmentID,
+		req.PositionID,
+		req.LocationID,
+		effectiveAt(req.EffectiveAt, time.Now()),
+		permissions.ScopeLocations(rbac.EmployeesWrite, "user_positions.location_id"),
+	)
+	if err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("TransferPositionHandler: %w", err))
+	}
+
+	return c.JSON(http.StatusCreated, API.Response{
+		Message: "Position transferred.",
+		Data:    assignment,
+	})
+}
+
+// Ends an assignment now or at a given time, which needs the write permission at its location.
+func (d *Domain) EndPositionHandler(c echo.Context) error {
+	permissions, err := middleware.LoadPermissions(c, d.params.DB)
+	if err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("EndPositionHandler: %w", err))
+	}
+
+	var userID, assignmentID uint
+	if _, err := extractor.ExtractFromPathParamAs(c, "id", &userID); err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("EndPositionHandler: %w: %v", errmgr.ErrPayload, err))
+	}
+	if _, err := extractor.ExtractFromPathParamAs(c, "assignmentId", &assignmentID); err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("EndPositionHandler: %w: %v", errmgr.ErrPayload, err))
+	}
+
+	var req EndPositionRequest
+	if err := c.Bind(&req); err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("EndPositionHandler: %w: %v", errmgr.ErrPayload, err))
+	}
+
+	assignment, err := d.endPosition(
+		c.Request().Context(),
+		userID,
+		assignmentID,
+		effectiveAt(req.EndedAt, time.Now()),
+		permissions.ScopeLocations(rbac.EmployeesWrite, "user_positions.location_id"),
+	)
+	if err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("EndPositionHandler: %w", err))
+	}
+
+	return c.JSON(http.StatusOK, API.Response{
+		Message: "Position ended.",
+		Data:    assignment,
+	})
+}
+
+// Offboards an employee, which affects all their assignments and so needs the write permission company wide.
+func (d *Domain) OffboardEmployeeHandler(c echo.Context) error {
+	if err := d.requireCompanyWideWrite(c); err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("OffboardEmployeeHandler: %w", err))
+	}
+
+	actorID, _, err := extractor.ExtractUserAndCompanyIDFromContext(c)
+	if err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("OffboardEmployeeHandler: %w: %v", errmgr.ErrInvalidToken, err))
+	}
+
+	var userID uint
+	if _, err := extractor.ExtractFromPathParamAs(c, "id", &userID); err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("OffboardEmployeeHandler: %w: %v", errmgr.ErrPayload, err))
+	}
+	if userID == actorID {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("OffboardEmployeeHandler: %w: cannot offboard yourself", errmgr.ErrPayload))
+	}
+
+	var req OffboardEmployeeRequest
+	if err := c.Bind(&req); err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("OffboardEmployeeHandler: %w: %v", errmgr.ErrPayload, err))
+	}
+
+	now := time.Now()
+	endedAt := effectiveAt(req.EndedAt, now)
+	finalPaymentAt := effectiveAt(req.FinalPaymentAt, endedAt)
+	if err := validateOffboarding(endedAt, finalPaymentAt, now); err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("OffboardEmployeeHandler: %w", err))
+	}
+
+	managerID := actorID
+	if req.ManagerID != nil {
+		managerID = *req.ManagerID
+	}
+
+	result, err := d.offboard(c.Request().Context(), userID, managerID, endedAt, finalPaymentAt)
+	if err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("OffboardEmployeeHandler: %w", err))
+	}
+
+	return c.JSON(http.StatusOK, API.Response{
+		Message: "Employee offboarded.",
+		Data:    result,
+	})
+}
+
+func (d *Domain) ListInvitationsHandler(c echo.Context) error {
+	var query ListInvitationsQuery
+	if err := c.Bind(&query); err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("ListInvitationsHandler: %w: %v", errmgr.ErrPayload, err))
+	}
+	if err := c.Validate(&query); err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("ListInvitationsHandler: %w: %v", errmgr.ErrPayload, err))
+	}
+
+	invitations, total, err := d.listInvitations(c.Request().Context(), query.Status, query.PageQuery)
+	if err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("ListInvitationsHandler: %w", err))
+	}
+
+	return c.JSON(http.StatusOK, API.Response{
+		Message:    "Invitations retrieved.",
+		Data:       invitations,
+		Pagination: query.Pagination(total),
+	})
+}
+
+// Invites an employee by email. The pending user is not assigned to any location yet,
+// so the write permission must be granted company wide.
+func (d *Domain) CreateInvitationHandler(c echo.Context) error {
+	inviterID, _, err := extractor.ExtractUserAndCompanyIDFromContext(c)
+	if err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("CreateInvitationHandler: %w: %v", errmgr.ErrInvalidToken, err))
+	}
+
+	err = d.requireCompanyWideWrite(c)
+	if err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("CreateInvitationHandler: %w", err))
+	}
+
+	var req CreateInvitationRequest
+	if err := c.Bind(&req); err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("CreateInvitationHandler: %w: %v", errmgr.ErrPayload, err))
+	}
+	if err := c.Validate(&req); err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("CreateInvitationHandler: %w: %v", errmgr.ErrPayload, err))
+	}
+
+	invitation, err := d.createInvitation(c.Request().Context(), inviterID, req.Name, req.Email)
+	if err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("CreateInvitationHandler: %w", err))
+	}
+
+	// the invitation exists once committed and can be resent, so a failed email does not fail the request
+	pgconn.AfterCommit(c.Request().Context(), func() {
+		err := d.sendInvitation(invitation)
+		if err != nil {
+			d.logger.Error("CreateInvitationHandler: error sending invitation", zap.Error(err))
+		}
+	})
+
+	return c.JSON(http.StatusCreated, API.Response{
+		Message: "Invitation sent.",
+		Data:    invitation,
+	})
+}
+
+// Sends a new invite link, earlier links stop working.
+func (d *Domain) ResendInvitationHandler(c echo.Context) error {
+	err := d.requireCompanyWideWrite(c)
+	if err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("ResendInvitationHandler: %w", err))
+	}
+
+	var invitationID uint
+	if _, err := extractor.ExtractFromPathParamAs(c, "id", &invitationID); err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("ResendInvitationHandler: %w: %v", errmgr.ErrPayload, err))
+	}
+
+	invitation, err := d.resendInvitation(c.Request().Context(), invitationID)
+	if err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("ResendInvitationHandler: %w", err))
+	}
+
+	// earlier links stop working once the new token is committed, so the email waits for it
+	pgconn.AfterCommit(c.Request().Context(), func() {
+		err := d.sendInvitation(invitation)
+		if err != nil {
+			d.logger.Error("ResendInvitationHandler: error sending invitation", zap.Error(err))
+		}
+	})
+
+	return c.JSON(http.StatusOK, API.Response{
+		Message: "Invitation resent.",
+		Data:    invitation,
+	})
+}
+
+func (d *Domain) RevokeInvitationHandler(c echo.Context) error {
+	err := d.requireCompanyWideWrite(c)
+	if err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("RevokeInvitationHandler: %w", err))
+	}
+
+	var invitationID uint
+	if _, err := extractor.ExtractFromPathParamAs(c, "id", &invitationID); err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("RevokeInvitationHandler: %w: %v", errmgr.ErrPayload, err))
+	}
+
+	invitation, err := d.revokeInvitation(c.Request().Context(), invitationID)
+	if err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("RevokeInvitationHandler: %w", err))
+	}
+
+	return c.JSON(http.StatusOK, API.Response{
+		Message: "Invitation revoked.",
+		Data:    invitation,
+	})
+}
+
+// Sets the invitee's password using the invite token of the emailed link, see extractor.ExtractLinkToken.
+func (d *Domain) AcceptInvitationHandler(c echo.Context) error {
+	tokenString := extractor.ExtractLinkToken(c)
+	if tokenString == "" {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("AcceptInvitationHandler: %w: missing token", errmgr.ErrInvalidToken))
+	}
+
+	var req AcceptInvitationRequest
+	if err := c.Bind(&req); err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("AcceptInvitationHandler: %w: %v", errmgr.ErrPayload, err))
+	}
+	if err := c.Validate(&req); err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("AcceptInvitationHandler: %w: %v", errmgr.ErrPayload, err))
+	}
+
+	claims, err := d.params.Token.ParseToken(InvitationTokenScope, tokenString)
+	if err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("AcceptInvitationHandler: %w: %v", errmgr.ErrInvalidToken, err))
+	}
+
+	companyID, err := extractor.ExtractCompanyIDFromContext(c)
+	if err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("AcceptInvitationHandler: %w: %v", errmgr.ErrTenant, err))
+	}
+
+	user, err := d.acceptInvitation(c.Request().Context(), *companyID, claims, req.Password)
+	if err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("AcceptInvitationHandler: %w", err))
+	}
+
+	return c.JSON(http.StatusOK, API.Response{
+		Message: "Invitation accepted. You can now sign in.",
+		Data:    user,
+	})
+}
+
+// Invitations are not tied to a location, so managing them needs the write permission company wide.
+func (d *Domain) requireCompanyWideWrite(c echo.Context) error {
+	permissions, err := middleware.LoadPermissions(c, d.params.DB)
+	if err != nil {
+		return fmt.Errorf("requireCompanyWideWrite: %w", err)
+	}
+	if !permissions.HasCompanyWide(rbac.EmployeesWrite) {
+		return fmt.Errorf("requireCompanyWideWrite: %w: missing company wide %s", errmgr.ErrPermission, rbac.EmployeesWrite)
+	}
+	return nil
+}
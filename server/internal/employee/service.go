@@ -0,0 +1,939 @@
+package employee
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/alsey89/people-matter/internal/common/API"
+	"github.com/alsey89/people-matter/internal/common/errmgr"
+	"github.com/alsey89/people-matter/internal/common/quota"
+	"github.com/alsey89/people-matter/internal/common/util"
+	"github.com/alsey89/people-matter/internal/identity"
+	"github.com/alsey89/people-matter/internal/schema"
+	"github.com/alsey89/people-matter/pkg/pgconn"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// An employee with their position assignments, most recent first.
+type Employee struct {
+	ID            uint         `json:"id"`
+	Name          string       `json:"name"`
+	Email         string       `json:"email"`
+	EmailVerified bool         `json:"emailVerified"`
+	CreatedAt     time.Time    `json:"createdAt"`
+	Active        bool         `json:"active"`
+	Assignments   []Assignment `json:"assignments"`
+}
+
+// A position assignment with the names of its position and location.
+type Assignment struct {
+	ID           uint       `json:"id"`
+	UserID       uint       `json:"-"`
+	PositionID   uint       `json:"positionId"`
+	PositionName string     `json:"positionName"`
+	LocationID   uint       `json:"locationId"`
+	LocationName string     `json:"locationName"`
+	StartedAt    time.Time  `json:"startedAt"`
+	EndedAt      *time.Time `json:"endedAt"`
+}
+
+// Reports whether the assignment is open at the given time.
+func (a Assignment) activeAt(at time.Time) bool {
+	return !a.StartedAt.After(at) && (a.EndedAt == nil || a.EndedAt.After(at))
+}
+
+/*
+Returns one page of the employees matching the filter within the scopes, and the total number of matches.
+Uses two queries regardless of the page size: one for the page of users and one joining the
+assignments of those users with their positions and locations.
+*/
+func (d *Domain) listEmployees(ctx context.Context, filter DirectoryFilter, scopes ...func(*gorm.DB) *gorm.DB) ([]Employee, int64, error) {
+	db, err := d.params.DB.GetScopedDB(ctx)
+	if err != nil {
+		return nil, 0, fmt.Errorf("listEmployees: %w: %v", errmgr.ErrTenant, err)
+	}
+
+	now := time.Now()
+	filtered := db.Model(&schema.User{}).Scopes(scopes...).Scopes(filter.scope(now))
+
+	var total int64
+	err = filtered.Session(&gorm.Session{}).Count(&total).Error
+	if err != nil {
+		return nil, 0, fmt.Errorf("listEmployees: %w", err)
+	}
+
+	var users []schema.User
+	err = filtered.
+		Order(directoryOrders[filter.Sort]).
+		Limit(filter.Limit()).
+		Offset(filter.Offset()).
+		Find(&users).Error
+	if err != nil {
+		return nil, 0, fmt.Errorf("listEmployees: %w", err)
+	}
+
+	employees, err := withAssignments(db, users, now)
+	if err != nil {
+		return nil, 0, fmt.Errorf("listEmployees: %w", err)
+	}
+
+	return employees, total, nil
+}
+
+// Returns an employee within the scopes, users outside them are reported as not found.
+func (d *Domain) getEmployee(ctx context.Context, userID uint, scopes ...func(*gorm.DB) *gorm.DB) (*Employee, error) {
+	db, err := d.params.DB.GetScopedDB(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("getEmployee: %w: %v", errmgr.ErrTenant, err)
+	}
+
+	var user schema.User
+	err = db.Scopes(scopes...).Where("users.id = ?", userID).First(&user).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("getEmployee: %w", errmgr.ErrUserNotFound)
+		}
+		return nil, fmt.Errorf("getEmployee: %w", err)
+	}
+
+	employees, err := withAssignments(db, []schema.User{user}, time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("getEmployee: %w", err)
+	}
+
+	return &employees[0], nil
+}
+
+// Returns the assignments of an employee within the scopes in chronological order, users outside them are reported as not found.
+func (d *Domain) getAssignmentHistory(ctx context.Context, userID uint, scopes ...func(*gorm.DB) *gorm.DB) ([]Assignment, error) {
+	db, err := d.params.DB.GetScopedDB(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("getAssignmentHistory: %w: %v", errmgr.ErrTenant, err)
+	}
+
+	var count int64
+	err = db.Model(&schema.User{}).Scopes(scopes...).Where("users.id = ?", userID).Count(&count).Error
+	if err != nil {
+		return nil, fmt.Errorf("getAssignmentHistory: %w", err)
+	}
+	if count == 0 {
+		return nil, fmt.Errorf("getAssignmentHistory: %w", errmgr.ErrUserNotFound)
+	}
+
+	assignments := []Assignment{}
+	err = assignmentQuery(db).
+		Where("user_positions.user_id = ?", userID).
+		Order("user_positions.started_at ASC, user_positions.id ASC").
+		Scan(&assignments).Error
+	if err != nil {
+		return nil, fmt.Errorf("getAssignmentHistory: %w", err)
+	}
+
+	return assignments, nil
+}
+
+/*
+Assigns a position at a location to an employee for [startedAt, endedAt), endedAt is optional.
+Fails with errmgr.ErrAssignmentOverlap if the employee holds the same position during that period,
+and with errmgr.ErrQuotaExceeded if this reactivates an employee beyond the employee quota.
+*/
+func (d *Domain) assignPosition(ctx context.Context, userID uint, positionID uint, locationID uint, startedAt time.Time, endedAt *time.Time) (*Assignment, error) {
+	db, err := d.params.DB.GetScopedDB(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("assignPosition: %w: %v", errmgr.ErrTenant, err)
+	}
+
+	err = validatePeriod(startedAt, endedAt)
+	if err != nil {
+		return nil, fmt.Errorf("assignPosition: %w", err)
+	}
+
+	var created *schema.UserPosition
+	err = db.Transaction(func(tx *gorm.DB) error {
+		_, err := lockEmployee(tx, userID)
+		if err != nil {
+			return err
+		}
+
+		created, err = createAssignment(tx, userID, positionID, locationID, startedAt, endedAt)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("assignPosition: %w", err)
+	}
+
+	assignment, err := findAssignment(db, created.ID)
+	if err != nil {
+		return nil, fmt.Errorf("assignPosition: %w", err)
+	}
+
+	return assignment, nil
+}
+
+/*
+Moves an employee to another location and optionally another position at the given time:
+the assignment ends at that time and a new one, with the same end date if it had one, starts.
+The assignment is looked up within the scopes, so callers can restrict which assignments may be moved.
+Returns the new assignment.
+*/
+func (d *Domain) transferPosition(ctx context.Context, userID uint, assignmentID uint, positionID *uint, locationID uint, at time.Time, scopes ...func(*gorm.DB) *gorm.DB) (*Assignment, error) {
+	db, err := d.params.DB.GetScopedDB(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("transferPosition: %w: %v", errmgr.ErrTenant, err)
+	}
+
+	var created *schema.UserPosition
+	err = db.Transaction(func(tx *gorm.DB) error {
+		_, err := lockEmployee(tx, userID)
+		if err != nil {
+			return err
+		}
+
+		previous, err := findUserPosition(tx, userID, assignmentID, scopes...)
+		if err != nil {
+			return err
+		}
+		err = validateEnd(previous.StartedAt, previous.EndedAt, at)
+		if err != nil {
+			return err
+		}
+
+		nextPositionID := previous.PositionID
+		if positionID != nil {
+			nextPositionID = *positionID
+		}
+		if nextPositionID == previous.PositionID && locationID == previous.LocationID {
+			return fmt.Errorf("%w: transfer to the same position and location", errmgr.ErrPayload)
+		}
+
+		// copied, the update below may write through the pointer
+		var plannedEnd *time.Time
+		if previous.EndedAt != nil {
+			end := *previous.EndedAt
+			plannedEnd = &end
+		}
+		err = tx.Model(previous).Update("ended_at", at).Error
+		if err != nil {
+			return err
+		}
+
+		created, err = createAssignment(tx, userID, nextPositionID, locationID, at, plannedEnd)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("transferPosition: %w", err)
+	}
+
+	assignment, err := findAssignment(db, created.ID)
+	if err != nil {
+		return nil, fmt.Errorf("transferPosition: %w", err)
+	}
+
+	return assignment, nil
+}
+
+// Ends an assignment at the given time, which may be in the future. The assignment is looked up within the scopes.
+func (d *Domain) endPosition(ctx context.Context, userID uint, assignmentID uint, at time.Time, scopes ...func(*gorm.DB) *gorm.DB) (*Assignment, error) {
+	db, err := d.params.DB.GetScopedDB(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("endPosition: %w: %v", errmgr.ErrTenant, err)
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		_, err := lockEmployee(tx, userID)
+		if err != nil {
+			return err
+		}
+
+		userPosition, err := findUserPosition(tx, userID, assignmentID, scopes...)
+		if err != nil {
+			return err
+		}
+		err = validateEnd(userPosition.StartedAt, userPosition.EndedAt, at)
+		if err != nil {
+			return err
+		}
+
+		return tx.Model(userPosition).Update("ended_at", at).Error
+	})
+	if err != nil {
+		return nil, fmt.Errorf("endPosition: %w", err)
+	}
+
+	assignment, err := findAssignment(db, assignmentID)
+	if err != nil {
+		return nil, fmt.Errorf("endPosition: %w", err)
+	}
+
+	return assignment, nil
+}
+
+// Outcome of an offboarding, FinalPayment is nil if the employee had no active compensation.
+type OffboardingResult struct {
+	UserID             uint            `json:"userId"`
+	EndedAt            time.Time       `json:"endedAt"`
+	EndedAssignments   int64           `json:"endedAssignments"`
+	EndedCompensations int64           `json:"endedCompensations"`
+	FinalPayment       *schema.Payment `json:"finalPayment"`
+}
+
+/*
+Offboards the employee at endedAt in one transaction: open and future assignments and compensations are ended
+or removed, the active compensation is cleared, sessions and outstanding tokens are revoked, and a final payment
+of the compensation active at endedAt is scheduled at finalPaymentAt.
+The employee and the manager are notified after the transaction commits, failures to send are only logged.
+*/
+func (d *Domain) offboard(ctx context.Context, userID uint, managerID uint, endedAt time.Time, finalPaymentAt time.Time) (*OffboardingResult, error) {
+	db, err := d.params.DB.GetScopedDB(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("offboard: %w: %v", errmgr.ErrTenant, err)
+	}
+
+	var manager schema.User
+	err = db.Where("id = ?", managerID).First(&manager).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("offboard: %w: manager %d", errmgr.ErrUserNotFound, managerID)
+		}
+		return nil, fmt.Errorf("offboard: %w", err)
+	}
+
+	result := &OffboardingResult{UserID: userID, EndedAt: endedAt}
+	var user *schema.User
+	err = db.Transaction(func(tx *gorm.DB) error {
+		user, err = lockEmployee(tx, userID)
+		if err != nil {
+			return err
+		}
+		now := time.Now().UTC()
+
+		// the compensation paid out last, looked up before it is ended
+		var compensation schema.Compensation
+		err = tx.Where("user_id = ? AND started_at < ? AND (ended_at IS NULL OR ended_at > ?)", userID, endedAt, endedAt).
+			Order("started_at DESC").
+			Limit(1).
+			Find(&compensation).Error
+		if err != nil {
+			return err
+		}
+
+		result.EndedAssignments, err = endOpenPeriods(tx, &schema.UserPosition{}, userID, endedAt)
+		if err != nil {
+			return err
+		}
+		result.EndedCompensations, err = endOpenPeriods(tx, &schema.Compensation{}, userID, endedAt)
+		if err != nil {
+			return err
+		}
+		if user.SessionsRevokedAt != nil && result.EndedAssignments == 0 && result.EndedCompensations == 0 {
+			return fmt.Errorf("%w: sessions revoked at %s", errmgr.ErrOffboarded, user.SessionsRevokedAt.Format(time.RFC3339))
+		}
+
+		user.ActiveCompensationID = nil
+		user.SessionsRevokedAt = &now
+		err = tx.Model(user).Updates(map[string]interface{}{
+			"active_compensation_id": nil,
+			"sessions_revoked_at":    now,
+		}).Error
+		if err != nil {
+			return err
+		}
+
+		// password reset links would let the employee set a password again, invite links would reactivate them
+		err = tx.Model(&schema.PasswordReset{}).
+			Where("user_id = ? AND used_at IS NULL", userID).
+			Update("used_at", now).Error
+		if err != nil {
+			return err
+		}
+		err = tx.Model(&schema.Invitation{}).
+			Where("user_id = ? AND status = ?", userID, schema.InvitationStatusPending).
+			Update("status", schema.InvitationStatusRevoked).Error
+		if err != nil {
+			return err
+		}
+
+		if compensation.ID == 0 {
+			return nil
+		}
+		payment := schema.Payment{
+			UserID:         userID,
+			CompensationID: compensation.ID,
+			Currency:       compensation.Currency,
+			PaidAt:         finalPaymentAt,
+			Status:         schema.PaymentStatusScheduled,
+			Final:          true,
+		}
+		err = tx.Create(&payment).Error
+		if err != nil {
+			return err
+		}
+		result.FinalPayment = &payment
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("offboard: %w", err)
+	}
+
+	// with row level security ctx carries the request's transaction, which the one above is only a savepoint of
+	pgconn.AfterCommit(ctx, func() {
+		d.sendOffboardingNotices(user, &manager, result)
+	})
+
+	return result, nil
+}
+
+// Returns one page of the invitations, optionally only those with the given status, most recent first.
+func (d *Domain) listInvitations(ctx context.Context, status string, page API.PageQuery) ([]schema.Invitation, int64, error) {
+	db, err := d.params.DB.GetScopedDB(ctx)
+	if err != nil {
+		return nil, 0, fmt.Errorf("listInvitations: %w: %v", errmgr.ErrTenant, err)
+	}
+
+	filtered := db.Model(&schema.Invitation{})
+	if status != "" {
+		filtered = filtered.Where("status = ?", status)
+	}
+
+	var total int64
+	err = filtered.Session(&gorm.Session{}).Count(&total).Error
+	if err != nil {
+		return nil, 0, fmt.Errorf("listInvitations: %w", err)
+	}
+
+	var invitations []schema.Invitation
+	err = filtered.
+		Order("created_at DESC, id DESC").
+		Limit(page.Limit()).
+		Offset(page.Offset()).
+		Find(&invitations).Error
+	if err != nil {
+		return nil, 0, fmt.Errorf("listInvitations: %w", err)
+	}
+
+	return invitations, total, nil
+}
+
+/*
+Creates a pending, unverified user without a password and an invitation for them.
+The user counts against the employee quota until the invitation is revoked.
+A user whose earlier invitation was revoked is restored and invited again, any other existing user
+fails with errmgr.ErrEmailTaken, or errmgr.ErrInvitationPending if their invitation is still pending.
+The invitation email is sent separately by sendInvitation.
+*/
+func (d *Domain) createInvitation(ctx context.Context, inviterID uint, name string, email string) (*schema.Invitation, error) {
+	db, err := d.params.DB.GetScopedDB(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("createInvitation: %w: %v", errmgr.ErrTenant, err)
+	}
+
+	now := time.Now()
+	invitation := schema.Invitation{
+		InvitedByID: inviterID,
+		Email:       identity.NormalizeEmail(email),
+		TokenID:     uuid.NewString(),
+		Status:      schema.InvitationStatusPending,
+		ExpiresAt:   now.Add(d.invitationTTL()),
+		SentAt:      now,
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		user, err := findInvitee(tx, invitation.Email)
+		if err != nil {
+			return err
+		}
+
+		err = quota.ReserveEmployee(tx)
+		if err != nil {
+			return err
+		}
+
+		if user == nil {
+			user = &schema.User{
+				Name:          strings.TrimSpace(name),
+				Email:         invitation.Email,
+				EmailVerified: false,
+			}
+			err = tx.Create(user).Error
+		} else {
+			user.Name = strings.TrimSpace(name)
+			user.DeletedAt = gorm.DeletedAt{}
+			err = tx.Unscoped().Model(user).Updates(map[string]interface{}{
+				"name":       user.Name,
+				"deleted_at": nil,
+			}).Error
+		}
+		if err != nil {
+			return err
+		}
+
+		invitation.UserID = user.ID
+		invitation.User = *user
+		return tx.Omit("User").Create(&invitation).Error
+	})
+	if err != nil {
+		return nil, fmt.Errorf("createInvitation: %w", err)
+	}
+
+	return &invitation, nil
+}
+
+// Issues a new invite link for a pending invitation, invalidating earlier links and extending the expiry.
+func (d *Domain) resendInvitation(ctx context.Context, invitationID uint) (*schema.Invitation, error) {
+	db, err := d.params.DB.GetScopedDB(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("resendInvitation: %w: %v", errmgr.ErrTenant, err)
+	}
+
+	var invitation schema.Invitation
+	err = db.Transaction(func(tx *gorm.DB) error {
+		err := findPendingInvitation(tx, invitationID, &invitation)
+		if err != nil {
+			return err
+		}
+
+		invitation.TokenID = uuid.NewString()
+		invitation.SentAt = time.Now()
+		invitation.ExpiresAt = invitation.SentAt.Add(d.invitationTTL())
+		return tx.Model(&invitation).Updates(map[string]interface{}{
+			"token_id":   invitation.TokenID,
+			"expires_at": invitation.ExpiresAt,
+			"sent_at":    invitation.SentAt,
+		}).Error
+	})
+	if err != nil {
+		return nil, fmt.Errorf("resendInvitation: %w", err)
+	}
+
+	return &invitation, nil
+}
+
+/*
+Revokes a pending invitation, so that its link no longer works, and soft deletes the pending user,
+which frees their slot in the employee quota. The email can be invited again afterwards.
+*/
+func (d *Domain) revokeInvitation(ctx context.Context, invitationID uint) (*schema.Invitation, error) {
+	db, err := d.params.DB.GetScopedDB(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("revokeInvitation: %w: %v", errmgr.ErrTenant, err)
+	}
+
+	var invitation schema.Invitation
+	err = db.Transaction(func(tx *gorm.DB) error {
+		err := findPendingInvitation(tx, invitationID, &invitation)
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		invitation.Status = schema.InvitationStatusRevoked
+		invitation.RevokedAt = &now
+		err = tx.Model(&invitation).Updates(map[string]interface{}{
+			"status":     invitation.Status,
+			"revoked_at": invitation.RevokedAt,
+		}).Error
+		if err != nil {
+			return err
+		}
+
+		return tx.Delete(&schema.User{}, invitation.UserID).Error
+	})
+	if err != nil {
+		return nil, fmt.Errorf("revokeInvitation: %w", err)
+	}
+
+	return &invitation, nil
+}
+
+/*
+Accepts the invitation identified by the invite token claims: sets the password of the pending user
+and marks their email as verified, since the link was delivered to it.
+Only the latest link of a pending, unexpired invitation is accepted, and only once.
+*/
+func (d *Domain) acceptInvitation(ctx context.Context, tenantCompanyID uint, claims jwt.MapClaims, password string) (*schema.User, error) {
+	db, err := d.params.DB.GetScopedDB(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("acceptInvitation: %w: %v", errmgr.ErrTenant, err)
+	}
+
+	companyID, ok := claims["companyId"].(float64)
+	if !ok || uint(companyID) != tenantCompanyID {
+		return nil, fmt.Errorf("acceptInvitation: %w: token company does not match tenant", errmgr.ErrTenant)
+	}
+	tokenID, ok := claims["jti"].(string)
+	if !ok || tokenID == "" {
+		return nil, fmt.Errorf("acceptInvitation: %w: no token id in claims", errmgr.ErrInvalidToken)
+	}
+
+	passwordHash, err := identity.HashPassword(password)
+	if err != nil {
+		return nil, fmt.Errorf("acceptInvitation: %w", err)
+	}
+
+	var user schema.User
+	err = db.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+
+		var invitation schema.Invitation
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("token_id = ?", tokenID).
+			First(&invitation).Error
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return fmt.Errorf("%w: link was replaced or is unknown", errmgr.ErrInvalidToken)
+			}
+			return err
+		}
+		if invitation.Status != schema.InvitationStatusPending || !invitation.ExpiresAt.After(now) {
+			return fmt.Errorf("%w: invitation is %s or expired", errmgr.ErrInvalidToken, invitation.Status)
+		}
+
+		err = tx.Model(&invitation).Updates(map[string]interface{}{
+			"status":      schema.InvitationStatusAccepted,
+			"accepted_at": now,
+		}).Error
+		if err != nil {
+			return err
+		}
+
+		err = tx.Where("id = ?", invitation.UserID).First(&user).Error
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errmgr.ErrUserNotFound
+			}
+			return err
+		}
+
+		user.PasswordHash = passwordHash
+		user.EmailVerified = true
+		return tx.Model(&user).Updates(map[string]interface{}{
+			"password_hash":  user.PasswordHash,
+			"email_verified": user.EmailVerified,
+		}).Error
+	})
+	if err != nil {
+		return nil, fmt.Errorf("acceptInvitation: %w", err)
+	}
+
+	return &user, nil
+}
+
+// Emails the invite link of the invitation to the invitee.
+func (d *Domain) sendInvitation(invitation *schema.Invitation) error {
+	inviteToken, err := d.params.Token.GenerateToken(InvitationTokenScope, jwt.MapClaims{
+		"id":        invitation.UserID,
+		"companyId": invitation.CompanyID,
+		"email":     invitation.Email,
+		"jti":       invitation.TokenID,
+		// the link expires together with the invitation
+		"exp": jwt.NewNumericDate(invitation.ExpiresAt),
+	})
+	if err != nil {
+		return fmt.Errorf("sendInvitation: %w", err)
+	}
+
+	urlPath := fmt.Sprintf("%s?token=%s", d.config.invitationURLPath, util.EncodeQueryParam(*inviteToken))
+
+	err = d.params.Transmail.SendMail(
+		invitation.CompanyID,
+		invitation.Email,
+		d.config.invitationTemplateID,
+		&urlPath,
+		map[string]interface{}{
+			"name":      invitation.User.Name,
+			"expiresAt": invitation.ExpiresAt.UTC().Format(time.RFC3339),
+		},
+	)
+	if err != nil {
+		return fmt.Errorf("sendInvitation: %w", err)
+	}
+
+	return nil
+}
+
+// Emails the offboarding notice to the employee and to their manager. Errors are logged, not returned,
+// because the offboarding itself has already been committed.
+func (d *Domain) sendOffboardingNotices(user *schema.User, manager *schema.User, result *OffboardingResult) {
+	variables := map[string]interface{}{
+		"name":        user.Name,
+		"managerName": manager.Name,
+		"endedAt":     result.EndedAt.Format(time.RFC3339),
+	}
+	if result.FinalPayment != nil {
+		variables["finalPaymentAt"] = result.FinalPayment.PaidAt.Format(time.RFC3339)
+	}
+
+	err := d.params.Transmail.SendMail(user.CompanyID, user.Email, d.config.offboardingTemplateID, nil, variables)
+	if err != nil {
+		d.logger.Error("sendOffboardingNotices: failed to notify employee", zap.Uint("userID", user.ID), zap.Error(err))
+	}
+
+	err = d.params.Transmail.SendMail(manager.CompanyID, manager.Email, d.config.offboardingManagerTemplateID, nil, variables)
+	if err != nil {
+		d.logger.Error("sendOffboardingNotices: failed to notify manager", zap.Uint("managerID", manager.ID), zap.Error(err))
+	}
+}
+
+// ! Helpers ---------------------------------------------------------------
+
+/*
+Locks the employee's row, so that changes to the assignments of one employee run one after another
+and the overlap check in createAssignment cannot miss a concurrently created assignment.
+*/
+func lockEmployee(tx *gorm.DB, userID uint) (*schema.User, error) {
+	var user schema.User
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", userID).First(&user).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("lockEmployee: %w", errmgr.ErrUserNotFound)
+		}
+		return nil, fmt.Errorf("lockEmployee: %w", err)
+	}
+	return &user, nil
+}
+
+// Loads an assignment of the user within the scopes, the row is locked until the transaction ends.
+func findUserPosition(tx *gorm.DB, userID uint, assignmentID uint, scopes ...func(*gorm.DB) *gorm.DB) (*schema.UserPosition, error) {
+	var userPosition schema.UserPosition
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Scopes(scopes...).
+		Where("user_positions.id = ? AND user_positions.user_id = ?", assignmentID, userID).
+		First(&userPosition).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("findUserPosition: %w", errmgr.ErrAssignmentNotFound)
+		}
+		return nil, fmt.Errorf("findUserPosition: %w", err)
+	}
+	return &userPosition, nil
+}
+
+/*
+Creates an assignment for [startedAt, endedAt) after checking the position and location exist,
+that the employee does not hold the position during that period, and the employee quota.
+The location is locked in share mode, so it cannot be deleted before the transaction ends.
+Must run after lockEmployee in the same transaction.
+*/
+func createAssignment(tx *gorm.DB, userID uint, positionID uint, locationID uint, startedAt time.Time, endedAt *time.Time) (*schema.UserPosition, error) {
+	var location schema.Location
+	err := tx.Clauses(clause.Locking{Strength: "SHARE"}).Where("id = ?", locationID).First(&location).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("createAssignment: %w: unknown location", errmgr.ErrPayload)
+		}
+		return nil, fmt.Errorf("createAssignment: %w", err)
+	}
+
+	var count int64
+	err = tx.Model(&schema.Position{}).Where("id = ?", positionID).Count(&count).Error
+	if err != nil {
+		return nil, fmt.Errorf("createAssignment: %w", err)
+	}
+	if count == 0 {
+		return nil, fmt.Errorf("createAssignment: %w: unknown position", errmgr.ErrPayload)
+	}
+
+	overlapping := tx.Model(&schema.UserPosition{}).
+		Where("user_id = ? AND position_id = ?", userID, positionID).
+		Where(schema.OpenUserPositions("user_positions", startedAt))
+	if endedAt != nil {
+		overlapping = overlapping.Where("started_at < ?", *endedAt)
+	}
+	err = overlapping.Count(&count).Error
+	if err != nil {
+		return nil, fmt.Errorf("createAssignment: %w", err)
+	}
+	if count > 0 {
+		return nil, fmt.Errorf("createAssignment: %w", errmgr.ErrAssignmentOverlap)
+	}
+
+	err = quota.ReserveReactivation(tx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("createAssignment: %w", err)
+	}
+
+	userPosition := schema.UserPosition{
+		UserID:     userID,
+		PositionID: positionID,
+		LocationID: locationID,
+		StartedAt:  startedAt,
+		EndedAt:    endedAt,
+	}
+	err = tx.Create(&userPosition).Error
+	if err != nil {
+		return nil, fmt.Errorf("createAssignment: %w", err)
+	}
+
+	return &userPosition, nil
+}
+
+/*
+Ends the periods of the model (user_positions or compensations) that are still open at the given time,
+and soft deletes those that would only start after it. Returns the number of periods affected.
+*/
+func endOpenPeriods(tx *gorm.DB, model interface{}, userID uint, at time.Time) (int64, error) {
+	ended := tx.Model(model).
+		Where("user_id = ? AND started_at < ? AND (ended_at IS NULL OR ended_at > ?)", userID, at, at).
+		Update("ended_at", at)
+	if ended.Error != nil {
+		return 0, fmt.Errorf("endOpenPeriods: %w", ended.Error)
+	}
+
+	removed := tx.Where("user_id = ? AND started_at >= ?", userID, at).Delete(model)
+	if removed.Error != nil {
+		return 0, fmt.Errorf("endOpenPeriods: %w", removed.Error)
+	}
+
+	return ended.RowsAffected + removed.RowsAffected, nil
+}
+
+func findAssignment(db *gorm.DB, assignmentID uint) (*Assignment, error) {
+	var assignment Assignment
+	result := assignmentQuery(db).Where("user_positions.id = ?", assignmentID).Scan(&assignment)
+	if result.Error != nil {
+		return nil, fmt.Errorf("findAssignment: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return nil, fmt.Errorf("findAssignment: %w", errmgr.ErrAssignmentNotFound)
+	}
+	return &assignment, nil
+}
+
+func (d *Domain) invitationTTL() time.Duration {
+	return time.Duration(d.config.invitationTTLHours) * time.Hour
+}
+
+/*
+Returns the user an invitation to email can be created for: nil if there is no user with that email,
+or the soft deleted user of a revoked invitation. Fails for any other existing user.
+*/
+func findInvitee(tx *gorm.DB, email string) (*schema.User, error) {
+	var user schema.User
+	err := tx.Unscoped().Where("email = ?", email).First(&user).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("findInvitee: %w", err)
+	}
+
+	if user.DeletedAt.Valid && user.PasswordHash == "" {
+		return &user, nil
+	}
+
+	var pending int64
+	err = tx.Model(&schema.Invitation{}).
+		Where("user_id = ? AND status = ?", user.ID, schema.InvitationStatusPending).
+		Count(&pending).Error
+	if err != nil {
+		return nil, fmt.Errorf("findInvitee: %w", err)
+	}
+	if pending > 0 {
+		return nil, fmt.Errorf("findInvitee: %w", errmgr.ErrInvitationPending)
+	}
+
+	return nil, fmt.Errorf("findInvitee: %w", errmgr.ErrEmailTaken)
+}
+
+// Locks and loads a pending invitation with its user, failing if it is unknown or no longer pending.
+func findPendingInvitation(tx *gorm.DB, invitationID uint, invitation *schema.Invitation) error {
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("id = ?", invitationID).
+		First(invitation).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return fmt.Errorf("findPendingInvitation: %w", errmgr.ErrInvitationNotFound)
+		}
+		return fmt.Errorf("findPendingInvitation: %w", err)
+	}
+	if invitation.Status != schema.InvitationStatusPending {
+		return fmt.Errorf("findPendingInvitation: %w: invitation is %s", errmgr.ErrInvitationStatus, invitation.Status)
+	}
+
+	err = tx.Where("id = ?", invitation.UserID).First(&invitation.User).Error
+	if err != nil {
+		return fmt.Errorf("findPendingInvitation: %w", err)
+	}
+
+	return nil
+}
+
+// Returns a query selecting assignments as Assignment rows, joined with their position and location names.
+func assignmentQuery(db *gorm.DB) *gorm.DB {
+	return db.Model(&schema.UserPosition{}).
+		Select(`user_positions.id, user_positions.user_id,
+			user_positions.position_id, positions.name AS position_name,
+			user_positions.location_id, locations.name AS location_name,
+			user_positions.started_at, user_positions.ended_at`).
+		Joins("JOIN positions ON positions.id = user_positions.position_id").
+		Joins("JOIN locations ON locations.id = user_positions.location_id")
+}
+
+// Loads the assignments of the users in a single query, most recent first.
+func findAssignments(db *gorm.DB, userIDs []uint) ([]Assignment, error) {
+	var assignments []Assignment
+	if len(userIDs) == 0 {
+		return assignments, nil
+	}
+
+	err := assignmentQuery(db).
+		Where("user_positions.user_id IN ?", userIDs).
+		Order("user_positions.started_at DESC, user_positions.id DESC").
+		Scan(&assignments).Error
+	if err != nil {
+		return nil, fmt.Errorf("findAssignments: %w", err)
+	}
+
+	return assignments, nil
+}
+
+// Returns the users as employees, in the same order, with their assignments attached.
+func withAssignments(db *gorm.DB, users []schema.User, now time.Time) ([]Employee, error) {
+	userIDs := make([]uint, 0, len(users))
+	for _, user := range users {
+		userIDs = append(userIDs, user.ID)
+	}
+
+	assignments, err := findAssignments(db, userIDs)
+	if err != nil {
+		return nil, fmt.Errorf("withAssignments: %w", err)
+	}
+
+	byUser := make(map[uint][]Assignment)
+	for _, assignment := range assignments {
+		byUser[assignment.UserID] = append(byUser[assignment.UserID], assignment)
+	}
+
+	employees := make([]Employee, 0, len(users))
+	for _, user := range users {
+		employee := Employee{
+			ID:            user.ID,
+			Name:          user.Name,
+			Email:         user.Email,
+			EmailVerified: user.EmailVerified,
+			CreatedAt:     user.CreatedAt,
+			Assignments:   byUser[user.ID],
+		}
+		if employee.Assignments == nil {
+			employee.Assignments = []Assignment{}
+		}
+		for _, assignment := range employee.Assignments {
+			if assignment.activeAt(now) {
+				employee.Active = true
+				break
+			}
+		}
+		employees = append(employees, employee)
+	}
+
+	return employees, nil
+}
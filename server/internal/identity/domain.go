@@ -0,0 +1,155 @@
+package identity
+
+import (
+	"context"
+
+	"github.com/alsey89/people-matter/internal/common/middleware"
+	"github.com/alsey89/people-matter/internal/common/util"
+	"github.com/alsey89/people-matter/internal/transmail"
+	"github.com/alsey89/people-matter/pkg/pgconn"
+	"github.com/alsey89/people-matter/pkg/server"
+	"github.com/alsey89/people-matter/pkg/token"
+
+	"github.com/spf13/viper"
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+)
+
+type Domain struct {
+	scope  string
+	logger *zap.Logger
+	config *Config
+	params Params
+}
+
+type Params struct {
+	fx.In
+	Lifecycle fx.Lifecycle
+	Logger    *zap.Logger
+	DB        *pgconn.Module
+	Server    *server.Module
+	Token     *token.Module
+	Transmail *transmail.Domain
+}
+
+type Config struct {
+	clientDomain string
+
+	verifyEmailTemplateID int
+	verifyEmailURLPath    string
+
+	resetPasswordTemplateID int
+	resetPasswordURLPath    string
+}
+
+// Token scopes used by this domain, must be registered with the token module.
+const (
+	AuthTokenScope              = "jwt"
+	EmailVerificationTokenScope = "email_verification"
+	PasswordResetTokenScope     = "password_reset"
+)
+
+const (
+	defaultClientDomain = "localhost:3000"
+
+	defaultVerifyEmailTemplateID = 0
+	defaultVerifyEmailURLPath    = "/auth/verify-email"
+
+	defaultResetPasswordTemplateID = 0
+	defaultResetPasswordURLPath    = "/auth/reset-password"
+)
+
+// ! Domain ---------------------------------------------------------------
+
+func InjectDomain(scope string) fx.Option {
+	return fx.Module(
+		scope,
+		fx.Provide(func(p Params) *Domain {
+			d := &Domain{scope: scope}
+			d.params = p
+			d.logger = d.setupLogger(scope, p)
+			d.config = d.setupConfig(scope)
+
+			return d
+		}),
+		fx.Invoke(func(d *Domain, p Params) {
+			d.registerRoutes()
+
+			p.Lifecycle.Append(
+				fx.Hook{
+					OnStart: d.onStart,
+					OnStop:  d.onStop,
+				},
+			)
+		}),
+	)
+}
+
+// ! Internal ---------------------------------------------------------------
+
+func (d *Domain) setupLogger(scope string, p Params) *zap.Logger {
+	logger := p.Logger.Named("[" + scope + "]")
+	return logger
+}
+
+func (d *Domain) setupConfig(scope string) *Config {
+	viper.SetDefault(util.GetConfigPath("global", "client_domain"), defaultClientDomain)
+	viper.SetDefault(util.GetConfigPath(scope, "verify_email_template_id"), defaultVerifyEmailTemplateID)
+	viper.SetDefault(util.GetConfigPath(scope, "verify_email_url_path"), defaultVerifyEmailURLPath)
+	viper.SetDefault(util.GetConfigPath(scope, "reset_password_template_id"), defaultResetPasswordTemplateID)
+	viper.SetDefault(util.GetConfigPath(scope, "reset_password_url_path"), defaultResetPasswordURLPath)
+
+	return &Config{
+		clientDomain: viper.GetString(util.GetConfigPath("global", "client_domain")),
+
+		verifyEmailTemplateID: viper.GetInt(util.GetConfigPath(scope, "verify_email_template_id")),
+		verifyEmailURLPath:    viper.GetString(util.GetConfigPath(scope, "verify_email_url_path")),
+
+		resetPasswordTemplateID: viper.GetInt(util.GetConfigPath(scope, "reset_password_template_id")),
+		resetPasswordURLPath:    viper.GetString(util.GetConfigPath(scope, "reset_password_url_path")),
+	}
+}
+
+func (d *Domain) registerRoutes() {
+	e := d.params.Server.GetServer()
+
+	resolveTenant := middleware.ResolveTenant(d.params.DB, d.logger, d.config.clientDomain)
+	requireAuth := d.params.Token.GetJWTMiddleware(AuthTokenScope)
+	requireSameTenant := middleware.RequireSameTenant(d.logger)
+	requireActiveSession := middleware.RequireActiveSession(d.params.DB, d.logger)
+
+	authGroup := e.Group("/api/v1/auth")
+	// signing out only clears the cookie and must work even if the tenant cannot be resolved
+	authGroup.POST("/signout", d.SignOutHandler)
+	authGroup.POST("/signup", d.SignUpHandler, resolveTenant)
+	authGroup.POST("/signin", d.SignInHandler, resolveTenant)
+	authGroup.POST("/verify-email", d.VerifyEmailHandler, resolveTenant)
+	authGroup.POST("/forgot-password", d.ForgotPasswordHandler, resolveTenant)
+	authGroup.POST("/reset-password", d.ResetPasswordHandler, resolveTenant)
+	authGroup.PUT("/password", d.ChangePasswordHandler, resolveTenant, requireAuth, requireSameTenant, requireActiveSession)
+}
+
+func (d *Domain) onStart(ctx context.Context) error {
+	d.logger.Info("Starting identity domain.")
+
+	if viper.GetString("global.log_level") == "DEBUG" || viper.GetString("global.log_level") == "debug" {
+		d.logConfigurations()
+	}
+
+	return nil
+}
+
+func (d *Domain) onStop(ctx context.Context) error {
+	d.logger.Info("Stopping identity domain.")
+	return nil
+}
+
+func (d *Domain) logConfigurations() {
+	d.logger.Debug("----- Identity Configuration -----")
+	d.logger.Debug("Client Domain", zap.String("client_domain", d.config.clientDomain))
+	d.logger.Debug("Verify Email Template ID", zap.Int("verify_email_template_id", d.config.verifyEmailTemplateID))
+	d.logger.Debug("Verify Email URL Path", zap.String("verify_email_url_path", d.config.verifyEmailURLPath))
+	d.logger.Debug("Reset Password Template ID", zap.Int("reset_password_template_id", d.config.resetPasswordTemplateID))
+	d.logger.Debug("Reset Password URL Path", zap.String("reset_password_url_path", d.config.resetPasswordURLPath))
+	d.logger.Debug("----------------------------------")
+}
@@ -0,0 +1,213 @@
+package identity
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/alsey89/people-matter/internal/common/API"
+	"github.com/alsey89/people-matter/internal/common/errmgr"
+	"github.com/alsey89/people-matter/internal/common/extractor"
+	"github.com/alsey89/people-matter/pkg/pgconn"
+
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+type SignUpRequest struct {
+	Name     string `json:"name"     validate:"required,max=255"`
+	Email    string `json:"email"    validate:"required,email,max=255"`
+	Password string `json:"password" validate:"required,min=8,max=72"`
+}
+
+type SignInRequest struct {
+	Email    string `json:"email"    validate:"required,email"`
+	Password string `json:"password" validate:"required"`
+}
+
+type ForgotPasswordRequest struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+type ResetPasswordRequest struct {
+	Password string `json:"password" validate:"required,min=8,max=72"`
+}
+
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"currentPassword" validate:"required"`
+	NewPassword     string `json:"newPassword"     validate:"required,min=8,max=72"`
+}
+
+// Registers a new, unverified user under the requesting tenant and sends a verification email.
+func (d *Domain) SignUpHandler(c echo.Context) error {
+	var req SignUpRequest
+	if err := c.Bind(&req); err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("SignUpHandler: %w: %v", errmgr.ErrPayload, err))
+	}
+	if err := c.Validate(&req); err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("SignUpHandler: %w: %v", errmgr.ErrPayload, err))
+	}
+
+	user, err := d.signUp(c.Request().Context(), req.Name, req.Email, req.Password)
+	if err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("SignUpHandler: %w", err))
+	}
+
+	// the user exists once committed, a failed email cannot undo that and is logged instead
+	pgconn.AfterCommit(c.Request().Context(), func() {
+		err := d.SendVerificationEmail(user, 0)
+		if err != nil {
+			d.logger.Error("SignUpHandler: error sending verification email", zap.Error(err))
+		}
+	})
+
+	return c.JSON(http.StatusCreated, API.Response{
+		Message: "User created. Please verify your email.",
+		Data:    user,
+	})
+}
+
+// Validates credentials and issues the HTTP-only JWT cookie.
+func (d *Domain) SignInHandler(c echo.Context) error {
+	var req SignInRequest
+	if err := c.Bind(&req); err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("SignInHandler: %w: %v", errmgr.ErrPayload, err))
+	}
+	if err := c.Validate(&req); err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("SignInHandler: %w: %v", errmgr.ErrPayload, err))
+	}
+
+	user, err := d.signIn(c.Request().Context(), req.Email, req.Password)
+	if err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("SignInHandler: %w", err))
+	}
+
+	cookie, err := d.params.Token.GenerateTokenAndHTTPonlyCookie(AuthTokenScope, authClaims(user))
+	if err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("SignInHandler: %w", err))
+	}
+	c.SetCookie(cookie)
+
+	return c.JSON(http.StatusOK, API.Response{
+		Message: "Signed in.",
+		Data:    user,
+	})
+}
+
+// Clears the HTTP-only JWT cookie. Always succeeds.
+func (d *Domain) SignOutHandler(c echo.Context) error {
+	c.SetCookie(d.params.Token.GenerateExpiredHTTPonlyCookie())
+
+	return c.JSON(http.StatusOK, API.Response{
+		Message: "Signed out.",
+	})
+}
+
+// Verifies the email of the user identified by the confirmation token of the emailed link, see extractor.ExtractLinkToken.
+func (d *Domain) VerifyEmailHandler(c echo.Context) error {
+	tokenString := extractor.ExtractLinkToken(c)
+	if tokenString == "" {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("VerifyEmailHandler: %w: missing token", errmgr.ErrInvalidToken))
+	}
+
+	claims, err := d.params.Token.ParseToken(EmailVerificationTokenScope, tokenString)
+	if err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("VerifyEmailHandler: %w: %v", errmgr.ErrInvalidToken, err))
+	}
+
+	companyID, err := extractor.ExtractCompanyIDFromContext(c)
+	if err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("VerifyEmailHandler: %w: %v", errmgr.ErrTenant, err))
+	}
+
+	user, err := d.verifyEmail(c.Request().Context(), *companyID, claims)
+	if err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("VerifyEmailHandler: %w", err))
+	}
+
+	return c.JSON(http.StatusOK, API.Response{
+		Message: "Email verified.",
+		Data:    user,
+	})
+}
+
+// Sends a password reset link if the email belongs to a user of the requesting tenant.
+// Responds identically whether or not the user exists to avoid leaking registered emails.
+func (d *Domain) ForgotPasswordHandler(c echo.Context) error {
+	var req ForgotPasswordRequest
+	if err := c.Bind(&req); err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("ForgotPasswordHandler: %w: %v", errmgr.ErrPayload, err))
+	}
+	if err := c.Validate(&req); err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("ForgotPasswordHandler: %w: %v", errmgr.ErrPayload, err))
+	}
+
+	err := d.requestPasswordReset(c.Request().Context(), req.Email)
+	if err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("ForgotPasswordHandler: %w", err))
+	}
+
+	return c.JSON(http.StatusOK, API.Response{
+		Message: "If the email is registered, a password reset link has been sent.",
+	})
+}
+
+// Sets a new password for the user identified by the reset token of the emailed link, see extractor.ExtractLinkToken.
+// Each reset token can only be used once.
+func (d *Domain) ResetPasswordHandler(c echo.Context) error {
+	tokenString := extractor.ExtractLinkToken(c)
+	if tokenString == "" {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("ResetPasswordHandler: %w: missing token", errmgr.ErrInvalidToken))
+	}
+
+	var req ResetPasswordRequest
+	if err := c.Bind(&req); err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("ResetPasswordHandler: %w: %v", errmgr.ErrPayload, err))
+	}
+	if err := c.Validate(&req); err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("ResetPasswordHandler: %w: %v", errmgr.ErrPayload, err))
+	}
+
+	claims, err := d.params.Token.ParseToken(PasswordResetTokenScope, tokenString)
+	if err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("ResetPasswordHandler: %w: %v", errmgr.ErrInvalidToken, err))
+	}
+
+	companyID, err := extractor.ExtractCompanyIDFromContext(c)
+	if err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("ResetPasswordHandler: %w: %v", errmgr.ErrTenant, err))
+	}
+
+	err = d.resetPassword(c.Request().Context(), *companyID, claims, req.Password)
+	if err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("ResetPasswordHandler: %w", err))
+	}
+
+	return c.JSON(http.StatusOK, API.Response{
+		Message: "Password has been reset.",
+	})
+}
+
+// Changes the password of the signed-in user after checking the current password.
+func (d *Domain) ChangePasswordHandler(c echo.Context) error {
+	userID, _, err := extractor.ExtractUserAndCompanyIDFromContext(c)
+	if err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("ChangePasswordHandler: %w: %v", errmgr.ErrInvalidToken, err))
+	}
+
+	var req ChangePasswordRequest
+	if err := c.Bind(&req); err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("ChangePasswordHandler: %w: %v", errmgr.ErrPayload, err))
+	}
+	if err := c.Validate(&req); err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("ChangePasswordHandler: %w: %v", errmgr.ErrPayload, err))
+	}
+
+	err = d.changePassword(c.Request().Context(), userID, req.CurrentPassword, req.NewPassword)
+	if err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("ChangePasswordHandler: %w", err))
+	}
+
+	return c.JSON(http.StatusOK, API.Response{
+		Message: "Password changed.",
+	})
+}
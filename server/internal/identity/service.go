@@ -0,0 +1,380 @@
+package identity
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/alsey89/people-matter/internal/common/errmgr"
+	"github.com/alsey89/people-matter/internal/common/quota"
+	"github.com/alsey89/people-matter/internal/common/util"
+	"github.com/alsey89/people-matter/internal/schema"
+	"github.com/alsey89/people-matter/pkg/pgconn"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+// Name of the unique index on the company and email of users.
+const emailIndex = "idx_company_email"
+
+func (d *Domain) signUp(ctx context.Context, name string, email string, password string) (*schema.User, error) {
+	db, err := d.params.DB.GetScopedDB(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("signUp: %w: %v", errmgr.ErrTenant, err)
+	}
+
+	passwordHash, err := HashPassword(password)
+	if err != nil {
+		return nil, fmt.Errorf("signUp: %w", err)
+	}
+
+	user := schema.User{
+		Name:          strings.TrimSpace(name),
+		Email:         NormalizeEmail(email),
+		PasswordHash:  passwordHash,
+		EmailVerified: false,
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		var count int64
+		err := tx.Model(&schema.User{}).
+			Where("email = ?", user.Email).
+			Count(&count).Error
+		if err != nil {
+			return err
+		}
+		if count > 0 {
+			return errmgr.ErrEmailTaken
+		}
+
+		err = quota.ReserveEmployee(tx)
+		if err != nil {
+			return err
+		}
+
+		return tx.Create(&user).Error
+	})
+	if err != nil {
+		// a concurrent sign up with the same email won the race between the count and the insert
+		if pgconn.IsUniqueViolation(err, emailIndex) {
+			return nil, fmt.Errorf("signUp: %w", errmgr.ErrEmailTaken)
+		}
+		return nil, fmt.Errorf("signUp: %w", err)
+	}
+
+	return &user, nil
+}
+
+func (d *Domain) signIn(ctx context.Context, email string, password string) (*schema.User, error) {
+	db, err := d.params.DB.GetScopedDB(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("signIn: %w: %v", errmgr.ErrTenant, err)
+	}
+
+	var user schema.User
+	err = db.Where("email = ?", NormalizeEmail(email)).First(&user).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			// do not reveal whether the email exists
+			return nil, fmt.Errorf("signIn: %w", errmgr.ErrInvalidCredentials)
+		}
+		return nil, fmt.Errorf("signIn: %w", err)
+	}
+
+	if !checkPassword(user.PasswordHash, password) {
+		return nil, fmt.Errorf("signIn: %w", errmgr.ErrInvalidCredentials)
+	}
+	if !user.EmailVerified {
+		return nil, fmt.Errorf("signIn: %w", errmgr.ErrEmailUnverified)
+	}
+
+	// offboarded users may sign in again only once they were reassigned a position
+	if user.SessionsRevokedAt != nil {
+		var active int64
+		err = db.Model(&schema.User{}).Scopes(quota.ActiveEmployees).Where("id = ?", user.ID).Count(&active).Error
+		if err != nil {
+			return nil, fmt.Errorf("signIn: %w", err)
+		}
+		if active == 0 {
+			return nil, fmt.Errorf("signIn: %w: user has been offboarded", errmgr.ErrInvalidCredentials)
+		}
+	}
+
+	return &user, nil
+}
+
+func (d *Domain) verifyEmail(ctx context.Context, tenantCompanyID uint, claims jwt.MapClaims) (*schema.User, error) {
+	db, err := d.params.DB.GetScopedDB(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("verifyEmail: %w: %v", errmgr.ErrTenant, err)
+	}
+
+	userID, companyID, email, err := identityFromClaims(claims)
+	if err != nil {
+		return nil, fmt.Errorf("verifyEmail: %w: %v", errmgr.ErrInvalidToken, err)
+	}
+	if companyID != tenantCompanyID {
+		return nil, fmt.Errorf("verifyEmail: %w: token company does not match tenant", errmgr.ErrTenant)
+	}
+
+	var user schema.User
+	err = db.Where("id = ?", userID).First(&user).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("verifyEmail: %w", errmgr.ErrUserNotFound)
+		}
+		return nil, fmt.Errorf("verifyEmail: %w", err)
+	}
+
+	// token was issued for a different address, e.g. before an email change
+	if user.Email != email {
+		return nil, fmt.Errorf("verifyEmail: %w: email mismatch", errmgr.ErrInvalidToken)
+	}
+
+	if user.EmailVerified {
+		return &user, nil
+	}
+
+	err = db.Model(&user).Update("email_verified", true).Error
+	if err != nil {
+		return nil, fmt.Errorf("verifyEmail: %w", err)
+	}
+
+	return &user, nil
+}
+
+/*
+Sends the email verification link to the user.
+templateID selects the email template, so other domains can embed the link in their own emails,
+e.g. the welcome email sent on onboarding. Pass 0 to use the configured verification template.
+*/
+func (d *Domain) SendVerificationEmail(user *schema.User, templateID int) error {
+	if templateID == 0 {
+		templateID = d.config.verifyEmailTemplateID
+	}
+
+	verificationToken, err := d.params.Token.GenerateToken(EmailVerificationTokenScope, jwt.MapClaims{
+		"id":        user.ID,
+		"companyId": user.CompanyID,
+		"email":     user.Email,
+	})
+	if err != nil {
+		return fmt.Errorf("SendVerificationEmail: %w", err)
+	}
+
+	urlPath := fmt.Sprintf("%s?token=%s", d.config.verifyEmailURLPath, util.EncodeQueryParam(*verificationToken))
+
+	err = d.params.Transmail.SendMail(
+		user.CompanyID,
+		user.Email,
+		templateID,
+		&urlPath,
+		map[string]interface{}{
+			"name": user.Name,
+		},
+	)
+	if err != nil {
+		return fmt.Errorf("SendVerificationEmail: %w", err)
+	}
+
+	return nil
+}
+
+func (d *Domain) requestPasswordReset(ctx context.Context, email string) error {
+	db, err := d.params.DB.GetScopedDB(ctx)
+	if err != nil {
+		return fmt.Errorf("requestPasswordReset: %w: %v", errmgr.ErrTenant, err)
+	}
+
+	var user schema.User
+	err = db.Where("email = ?", NormalizeEmail(email)).First(&user).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			d.logger.Info("requestPasswordReset: no user with email, skipping")
+			return nil
+		}
+		return fmt.Errorf("requestPasswordReset: %w", err)
+	}
+
+	passwordReset := schema.PasswordReset{
+		UserID:  user.ID,
+		TokenID: uuid.NewString(),
+	}
+	err = db.Create(&passwordReset).Error
+	if err != nil {
+		return fmt.Errorf("requestPasswordReset: %w", err)
+	}
+
+	resetToken, err := d.params.Token.GenerateToken(PasswordResetTokenScope, jwt.MapClaims{
+		"id":        user.ID,
+		"companyId": user.CompanyID,
+		"email":     user.Email,
+		"jti":       passwordReset.TokenID,
+	})
+	if err != nil {
+		return fmt.Errorf("requestPasswordReset: %w", err)
+	}
+
+	urlPath := fmt.Sprintf("%s?token=%s", d.config.resetPasswordURLPath, util.EncodeQueryParam(*resetToken))
+
+	// the link only works once the reset is committed, and failures are logged like for unknown emails
+	pgconn.AfterCommit(ctx, func() {
+		err := d.params.Transmail.SendMail(
+			user.CompanyID,
+			user.Email,
+			d.config.resetPasswordTemplateID,
+			&urlPath,
+			map[string]interface{}{
+				"name": user.Name,
+			},
+		)
+		if err != nil {
+			d.logger.Error("requestPasswordReset: error sending password reset email", zap.Error(err))
+		}
+	})
+
+	return nil
+}
+
+func (d *Domain) resetPassword(ctx context.Context, tenantCompanyID uint, claims jwt.MapClaims, newPassword string) error {
+	db, err := d.params.DB.GetScopedDB(ctx)
+	if err != nil {
+		return fmt.Errorf("resetPassword: %w: %v", errmgr.ErrTenant, err)
+	}
+
+	userID, companyID, _, err := identityFromClaims(claims)
+	if err != nil {
+		return fmt.Errorf("resetPassword: %w: %v", errmgr.ErrInvalidToken, err)
+	}
+	if companyID != tenantCompanyID {
+		return fmt.Errorf("resetPassword: %w: token company does not match tenant", errmgr.ErrTenant)
+	}
+	tokenID, ok := claims["jti"].(string)
+	if !ok || tokenID == "" {
+		return fmt.Errorf("resetPassword: %w: no token id in claims", errmgr.ErrInvalidToken)
+	}
+
+	passwordHash, err := HashPassword(newPassword)
+	if err != nil {
+		return fmt.Errorf("resetPassword: %w", err)
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+
+		// conditional update so that concurrent requests cannot both consume the same token
+		result := tx.Model(&schema.PasswordReset{}).
+			Where("token_id = ? AND user_id = ? AND used_at IS NULL", tokenID, userID).
+			Update("used_at", now)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected != 1 {
+			return fmt.Errorf("%w: token already used or unknown", errmgr.ErrInvalidToken)
+		}
+
+		result = tx.Model(&schema.User{}).
+			Where("id = ?", userID).
+			Update("password_hash", passwordHash)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected != 1 {
+			return errmgr.ErrUserNotFound
+		}
+
+		// any other outstanding reset links are no longer valid
+		return tx.Model(&schema.PasswordReset{}).
+			Where("user_id = ? AND used_at IS NULL", userID).
+			Update("used_at", now).Error
+	})
+	if err != nil {
+		return fmt.Errorf("resetPassword: %w", err)
+	}
+
+	return nil
+}
+
+func (d *Domain) changePassword(ctx context.Context, userID uint, currentPassword string, newPassword string) error {
+	db, err := d.params.DB.GetScopedDB(ctx)
+	if err != nil {
+		return fmt.Errorf("changePassword: %w: %v", errmgr.ErrTenant, err)
+	}
+
+	var user schema.User
+	err = db.Where("id = ?", userID).First(&user).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return fmt.Errorf("changePassword: %w", errmgr.ErrUserNotFound)
+		}
+		return fmt.Errorf("changePassword: %w", err)
+	}
+
+	if !checkPassword(user.PasswordHash, currentPassword) {
+		return fmt.Errorf("changePassword: %w", errmgr.ErrInvalidCredentials)
+	}
+
+	passwordHash, err := HashPassword(newPassword)
+	if err != nil {
+		return fmt.Errorf("changePassword: %w", err)
+	}
+
+	err = db.Model(&user).Update("password_hash", passwordHash).Error
+	if err != nil {
+		return fmt.Errorf("changePassword: %w", err)
+	}
+
+	return nil
+}
+
+// ! Helpers ---------------------------------------------------------------
+
+// "iat" lets RequireActiveSession reject tokens issued before the user's sessions were revoked.
+func authClaims(user *schema.User) jwt.MapClaims {
+	return jwt.MapClaims{
+		"id":        user.ID,
+		"companyId": user.CompanyID,
+		"email":     user.Email,
+		"iat":       jwt.NewNumericDate(time.Now()),
+	}
+}
+
+// JSON numbers are decoded as float64, so ids are converted back to uint.
+func identityFromClaims(claims jwt.MapClaims) (userID uint, companyID uint, email string, err error) {
+	id, ok := claims["id"].(float64)
+	if !ok || id == 0 {
+		return 0, 0, "", fmt.Errorf("identityFromClaims: %s", "no user id in claims")
+	}
+	cid, ok := claims["companyId"].(float64)
+	if !ok || cid == 0 {
+		return 0, 0, "", fmt.Errorf("identityFromClaims: %s", "no company id in claims")
+	}
+	email, ok = claims["email"].(string)
+	if !ok || email == "" {
+		return 0, 0, "", fmt.Errorf("identityFromClaims: %s", "no email in claims")
+	}
+
+	return uint(id), uint(cid), email, nil
+}
+
+func NormalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+func HashPassword(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", fmt.Errorf("HashPassword: %w", err)
+	}
+	return string(hash), nil
+}
+
+func checkPassword(hash string, password string) bool {
+	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil
+}
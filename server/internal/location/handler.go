@@ -0,0 +1,155 @@
+package location
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/alsey89/people-matter/internal/common/API"
+	"github.com/alsey89/people-matter/internal/common/errmgr"
+	"github.com/alsey89/people-matter/internal/common/extractor"
+	"github.com/alsey89/people-matter/internal/common/middleware"
+	"github.com/alsey89/people-matter/internal/common/rbac"
+	"github.com/alsey89/people-matter/internal/schema"
+
+	"github.com/labstack/echo/v4"
+)
+
+type LocationRequest struct {
+	Name    string             `json:"name"    validate:"required,max=255"`
+	Address API.AddressRequest `json:"address"`
+	Email   *string            `json:"email"   validate:"omitempty,email,max=255"`
+	Phone   *string            `json:"phone"   validate:"omitempty,max=255"`
+	Website *string            `json:"website" validate:"omitempty,url,max=255"`
+}
+
+type ListLocationsQuery struct {
+	API.PageQuery
+	Search string `query:"search" validate:"omitempty,max=255"`
+	Sort   string `query:"sort"   validate:"omitempty,oneof=name -name createdAt -createdAt"`
+}
+
+// Lists the locations the caller may read, optionally filtered by a name or city search.
+func (d *Domain) ListLocationsHandler(c echo.Context) error {
+	permissions, err := middleware.LoadPermissions(c, d.params.DB)
+	if err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("ListLocationsHandler: %w", err))
+	}
+
+	var query ListLocationsQuery
+	if err := c.Bind(&query); err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("ListLocationsHandler: %w: %v", errmgr.ErrPayload, err))
+	}
+	if err := c.Validate(&query); err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("ListLocationsHandler: %w: %v", errmgr.ErrPayload, err))
+	}
+
+	locations, total, err := d.listLocations(c.Request().Context(), query, permissions.ScopeLocations(rbac.LocationsRead, "locations.id"))
+	if err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("ListLocationsHandler: %w", err))
+	}
+
+	return c.JSON(http.StatusOK, API.Response{
+		Message:    "Locations retrieved.",
+		Data:       locations,
+		Pagination: query.Pagination(total),
+	})
+}
+
+func (d *Domain) GetLocationHandler(c echo.Context) error {
+	var locationID uint
+	if _, err := extractor.ExtractFromPathParamAs(c, middleware.LocationParam, &locationID); err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("GetLocationHandler: %w: %v", errmgr.ErrPayload, err))
+	}
+
+	location, err := d.getLocation(c.Request().Context(), locationID)
+	if err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("GetLocationHandler: %w", err))
+	}
+
+	return c.JSON(http.StatusOK, API.Response{
+		Message: "Location retrieved.",
+		Data:    location,
+	})
+}
+
+// Creates a location, counted against the company's location quota.
+// The location does not exist yet, so the write permission must be granted company wide.
+func (d *Domain) CreateLocationHandler(c echo.Context) error {
+	permissions, err := middleware.LoadPermissions(c, d.params.DB)
+	if err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("CreateLocationHandler: %w", err))
+	}
+	if !permissions.HasCompanyWide(rbac.LocationsWrite) {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("CreateLocationHandler: %w: missing company wide %s", errmgr.ErrPermission, rbac.LocationsWrite))
+	}
+
+	var req LocationRequest
+	if err := c.Bind(&req); err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("CreateLocationHandler: %w: %v", errmgr.ErrPayload, err))
+	}
+	if err := c.Validate(&req); err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("CreateLocationHandler: %w: %v", errmgr.ErrPayload, err))
+	}
+
+	location, err := d.createLocation(c.Request().Context(), req.toLocation())
+	if err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("CreateLocationHandler: %w", err))
+	}
+
+	return c.JSON(http.StatusCreated, API.Response{
+		Message: "Location created.",
+		Data:    location,
+	})
+}
+
+func (d *Domain) UpdateLocationHandler(c echo.Context) error {
+	var locationID uint
+	if _, err := extractor.ExtractFromPathParamAs(c, middleware.LocationParam, &locationID); err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("UpdateLocationHandler: %w: %v", errmgr.ErrPayload, err))
+	}
+
+	var req LocationRequest
+	if err := c.Bind(&req); err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("UpdateLocationHandler: %w: %v", errmgr.ErrPayload, err))
+	}
+	if err := c.Validate(&req); err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("UpdateLocationHandler: %w: %v", errmgr.ErrPayload, err))
+	}
+
+	location, err := d.updateLocation(c.Request().Context(), locationID, req.toLocation())
+	if err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("UpdateLocationHandler: %w", err))
+	}
+
+	return c.JSON(http.StatusOK, API.Response{
+		Message: "Location updated.",
+		Data:    location,
+	})
+}
+
+// Soft deletes a location, which is refused while employees still hold positions at it.
+func (d *Domain) DeleteLocationHandler(c echo.Context) error {
+	var locationID uint
+	if _, err := extractor.ExtractFromPathParamAs(c, middleware.LocationParam, &locationID); err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("DeleteLocationHandler: %w: %v", errmgr.ErrPayload, err))
+	}
+
+	err := d.deleteLocation(c.Request().Context(), locationID)
+	if err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("DeleteLocationHandler: %w", err))
+	}
+
+	return c.JSON(http.StatusOK, API.Response{
+		Message: "Location deleted.",
+	})
+}
+
+func (req *LocationRequest) toLocation() schema.Location {
+	return schema.Location{
+		Name:    req.Name,
+		Address: req.Address.ToSchema(),
+		Email:   req.Email,
+		Phone:   req.Phone,
+		Website: req.Website,
+	}
+}
@@ -0,0 +1,97 @@
+//go:build integration
+
+package location
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alsey89/people-matter/internal/common/errmgr"
+	"github.com/alsey89/people-matter/internal/schema"
+	"github.com/alsey89/people-matter/pkg/pgconn"
+	"github.com/alsey89/people-matter/pkg/pgconn/pgtest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+/*
+Runs against an ephemeral postgres, see pgtest.StartPostgres.
+Run with: go test -tags integration ./internal/location/
+*/
+
+const integrationScope = "location_integration"
+
+func newIntegrationDomain(t *testing.T) (*Domain, context.Context) {
+	t.Helper()
+
+	pgtest.StartPostgres(t, 54330, integrationScope)
+	m := pgconn.NewPGConn(integrationScope, zap.NewNop())
+	migrations, err := pgconn.LoadMigrations(schema.Migrations, schema.MigrationsDir)
+	require.NoError(t, err)
+	_, err = m.MigrateUp(migrations, false)
+	require.NoError(t, err)
+
+	company := schema.Company{TenantID: "acme", Name: "Acme", Email: "owner@acme.test", LocationQuota: 2}
+	require.NoError(t, m.GetDB().Create(&company).Error)
+
+	d := &Domain{logger: zap.NewNop(), params: Params{DB: m}}
+	return d, pgconn.WithCompanyID(context.Background(), company.ID)
+}
+
+func TestLocationIntegration(t *testing.T) {
+	d, ctx := newIntegrationDomain(t)
+
+	newLocation := func(name string) schema.Location {
+		return schema.Location{Name: name, Address: schema.Address{Street: "1 Main St", City: "Springfield", Country: "US", PostalCode: "12345"}}
+	}
+
+	first, err := d.createLocation(ctx, newLocation("Head office"))
+	require.NoError(t, err)
+	second, err := d.createLocation(ctx, newLocation("Warehouse"))
+	require.NoError(t, err)
+
+	t.Run("CreateOverQuota", func(t *testing.T) {
+		_, err := d.createLocation(ctx, newLocation("Store"))
+		assert.ErrorIs(t, err, errmgr.ErrQuotaExceeded)
+	})
+
+	db, err := d.params.DB.GetScopedDB(ctx)
+	require.NoError(t, err)
+	user := schema.User{Name: "Jane", Email: "jane@acme.test"}
+	require.NoError(t, db.Create(&user).Error)
+	position := schema.Position{Name: "Clerk"}
+	require.NoError(t, db.Create(&position).Error)
+	assign := func(locationID uint, endedAt *time.Time) {
+		require.NoError(t, db.Create(&schema.UserPosition{
+			UserID:     user.ID,
+			PositionID: position.ID,
+			LocationID: locationID,
+			StartedAt:  time.Now().AddDate(0, -1, 0),
+			EndedAt:    endedAt,
+		}).Error)
+	}
+
+	t.Run("DeleteWithOpenAssignment", func(t *testing.T) {
+		assign(first.ID, nil)
+		assert.ErrorIs(t, d.deleteLocation(ctx, first.ID), errmgr.ErrLocationInUse)
+	})
+
+	t.Run("DeleteWithFutureEndingAssignment", func(t *testing.T) {
+		tomorrow := time.Now().AddDate(0, 0, 1)
+		assign(second.ID, &tomorrow)
+		assert.ErrorIs(t, d.deleteLocation(ctx, second.ID), errmgr.ErrLocationInUse)
+	})
+
+	t.Run("DeleteWithEndedAssignment", func(t *testing.T) {
+		yesterday := time.Now().AddDate(0, 0, -1)
+		err := db.Model(&schema.UserPosition{}).Where("location_id = ?", second.ID).Update("ended_at", yesterday).Error
+		require.NoError(t, err)
+		require.NoError(t, d.deleteLocation(ctx, second.ID))
+
+		_, err = d.createLocation(ctx, newLocation("Store"))
+		assert.NoError(t, err, "deleted locations no longer count against the quota")
+	})
+}
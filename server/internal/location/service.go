@@ -0,0 +1,181 @@
+package location
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/alsey89/people-matter/internal/common/errmgr"
+	"github.com/alsey89/people-matter/internal/common/quota"
+	"github.com/alsey89/people-matter/internal/common/util"
+	"github.com/alsey89/people-matter/internal/schema"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Order clauses for the sort query parameter, a leading "-" sorts descending.
+var locationOrders = map[string]string{
+	"":           "name ASC, id ASC",
+	"name":       "name ASC, id ASC",
+	"-name":      "name DESC, id DESC",
+	"createdAt":  "created_at ASC, id ASC",
+	"-createdAt": "created_at DESC, id DESC",
+}
+
+// Returns one page of the locations within the scopes, and the total number of matching locations.
+func (d *Domain) listLocations(ctx context.Context, query ListLocationsQuery, scopes ...func(*gorm.DB) *gorm.DB) ([]schema.Location, int64, error) {
+	db, err := d.params.DB.GetScopedDB(ctx)
+	if err != nil {
+		return nil, 0, fmt.Errorf("listLocations: %w: %v", errmgr.ErrTenant, err)
+	}
+
+	filtered := db.Model(&schema.Location{}).Scopes(scopes...)
+	if search := strings.TrimSpace(query.Search); search != "" {
+		pattern := util.ContainsPattern(search)
+		filtered = filtered.Where("(name ILIKE ? OR address_city ILIKE ?)", pattern, pattern)
+	}
+
+	var total int64
+	err = filtered.Session(&gorm.Session{}).Count(&total).Error
+	if err != nil {
+		return nil, 0, fmt.Errorf("listLocations: %w", err)
+	}
+
+	var locations []schema.Location
+	err = filtered.
+		Order(locationOrders[query.Sort]).
+		Limit(query.Limit()).
+		Offset(query.Offset()).
+		Find(&locations).Error
+	if err != nil {
+		return nil, 0, fmt.Errorf("listLocations: %w", err)
+	}
+
+	return locations, total, nil
+}
+
+func (d *Domain) getLocation(ctx context.Context, locationID uint) (*schema.Location, error) {
+	db, err := d.params.DB.GetScopedDB(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("getLocation: %w: %v", errmgr.ErrTenant, err)
+	}
+
+	var location schema.Location
+	err = db.Where("id = ?", locationID).First(&location).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("getLocation: %w", errmgr.ErrLocationNotFound)
+		}
+		return nil, fmt.Errorf("getLocation: %w", err)
+	}
+
+	return &location, nil
+}
+
+// Creates a location, failing with errmgr.ErrQuotaExceeded if the company's location quota is used up.
+func (d *Domain) createLocation(ctx context.Context, location schema.Location) (*schema.Location, error) {
+	db, err := d.params.DB.GetScopedDB(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("createLocation: %w: %v", errmgr.ErrTenant, err)
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		err := quota.ReserveLocation(tx)
+		if err != nil {
+			return err
+		}
+		return tx.Create(&location).Error
+	})
+	if err != nil {
+		return nil, fmt.Errorf("createLocation: %w", err)
+	}
+
+	return &location, nil
+}
+
+// Replaces the name, address and contact details of a location.
+func (d *Domain) updateLocation(ctx context.Context, locationID uint, location schema.Location) (*schema.Location, error) {
+	db, err := d.params.DB.GetScopedDB(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("updateLocation: %w: %v", errmgr.ErrTenant, err)
+	}
+
+	var existing schema.Location
+	err = db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Where("id = ?", locationID).First(&existing).Error
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errmgr.ErrLocationNotFound
+			}
+			return err
+		}
+
+		err = tx.Model(&existing).Updates(map[string]interface{}{
+			"name":                location.Name,
+			"address_street":      location.Address.Street,
+			"address_city":        location.Address.City,
+			"address_country":     location.Address.Country,
+			"address_postal_code": location.Address.PostalCode,
+			"email":               location.Email,
+			"phone":               location.Phone,
+			"website":             location.Website,
+		}).Error
+		if err != nil {
+			return err
+		}
+
+		return tx.Where("id = ?", locationID).First(&existing).Error
+	})
+	if err != nil {
+		return nil, fmt.Errorf("updateLocation: %w", err)
+	}
+
+	return &existing, nil
+}
+
+/*
+Soft deletes a location, failing with errmgr.ErrLocationInUse while a position assignment at it
+has not ended. The location row is locked first, so assignments created concurrently, which lock
+it as well, either see the location deleted or block the deletion.
+*/
+func (d *Domain) deleteLocation(ctx context.Context, locationID uint) error {
+	db, err := d.params.DB.GetScopedDB(ctx)
+	if err != nil {
+		return fmt.Errorf("deleteLocation: %w: %v", errmgr.ErrTenant, err)
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		var location schema.Location
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id = ?", locationID).
+			First(&location).Error
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errmgr.ErrLocationNotFound
+			}
+			return err
+		}
+
+		var active int64
+		err = tx.Model(&schema.UserPosition{}).
+			Where("location_id = ?", locationID).
+			Where(schema.OpenUserPositions("user_positions", time.Now())).
+			Count(&active).Error
+		if err != nil {
+			return err
+		}
+		if active > 0 {
+			return fmt.Errorf("%w: %d active positions", errmgr.ErrLocationInUse, active)
+		}
+
+		return tx.Delete(&location).Error
+	})
+	if err != nil {
+		return fmt.Errorf("deleteLocation: %w", err)
+	}
+
+	return nil
+}
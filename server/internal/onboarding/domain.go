@@ -0,0 +1,120 @@
+package onboarding
+
+import (
+	"context"
+
+	"github.com/alsey89/people-matter/internal/common/util"
+	"github.com/alsey89/people-matter/internal/identity"
+	"github.com/alsey89/people-matter/internal/transmail"
+	"github.com/alsey89/people-matter/pkg/pgconn"
+	"github.com/alsey89/people-matter/pkg/server"
+
+	"github.com/spf13/viper"
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+)
+
+type Domain struct {
+	scope  string
+	logger *zap.Logger
+	config *Config
+	params Params
+}
+
+type Params struct {
+	fx.In
+	Lifecycle fx.Lifecycle
+	Logger    *zap.Logger
+	DB        *pgconn.Module
+	Server    *server.Module
+	Transmail *transmail.Domain
+	Identity  *identity.Domain
+}
+
+type Config struct {
+	clientDomain string
+
+	// template of the welcome email, which also carries the email verification link
+	welcomeTemplateID int
+}
+
+const (
+	defaultClientDomain = "localhost:3000"
+
+	defaultWelcomeTemplateID = 0
+)
+
+// ! Domain ---------------------------------------------------------------
+
+func InjectDomain(scope string) fx.Option {
+	return fx.Module(
+		scope,
+		fx.Provide(func(p Params) *Domain {
+			d := &Domain{scope: scope}
+			d.params = p
+			d.logger = d.setupLogger(scope, p)
+			d.config = d.setupConfig(scope)
+
+			return d
+		}),
+		fx.Invoke(func(d *Domain, p Params) {
+			d.registerRoutes()
+
+			p.Lifecycle.Append(
+				fx.Hook{
+					OnStart: d.onStart,
+					OnStop:  d.onStop,
+				},
+			)
+		}),
+	)
+}
+
+// ! Internal ---------------------------------------------------------------
+
+func (d *Domain) setupLogger(scope string, p Params) *zap.Logger {
+	logger := p.Logger.Named("[" + scope + "]")
+	return logger
+}
+
+func (d *Domain) setupConfig(scope string) *Config {
+	viper.SetDefault(util.GetConfigPath("global", "client_domain"), defaultClientDomain)
+	viper.SetDefault(util.GetConfigPath(scope, "welcome_template_id"), defaultWelcomeTemplateID)
+
+	return &Config{
+		clientDomain: viper.GetString(util.GetConfigPath("global", "client_domain")),
+
+		welcomeTemplateID: viper.GetInt(util.GetConfigPath(scope, "welcome_template_id")),
+	}
+}
+
+func (d *Domain) registerRoutes() {
+	e := d.params.Server.GetServer()
+
+	// public, the tenant does not exist yet
+	onboardingGroup := e.Group("/api/v1/onboarding")
+	onboardingGroup.GET("/tenant-ids/:tenantId", d.CheckTenantIDHandler)
+	onboardingGroup.POST("", d.OnboardHandler)
+}
+
+func (d *Domain) onStart(ctx context.Context) error {
+	d.logger.Info("Starting onboarding domain.")
+
+	if viper.GetString("global.log_level") == "DEBUG" || viper.GetString("global.log_level") == "debug" {
+		d.logConfigurations()
+	}
+
+	return nil
+}
+
+func (d *Domain) onStop(ctx context.Context) error {
+	d.logger.Info("Stopping onboarding domain.")
+	return nil
+}
+
+func (d *Domain) logConfigurations() {
+	d.logger.Debug("----- Onboarding Configuration -----")
+	d.logger.Debug("Client Domain", zap.String("client_domain", d.config.clientDomain))
+	d.logger.Debug("Welcome Template ID", zap.Int("welcome_template_id", d.config.welcomeTemplateID))
+	d.logger.Debug("------------------------------------")
+}
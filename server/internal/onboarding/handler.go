@@ -0,0 +1,85 @@
+package onboarding
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/alsey89/people-matter/internal/common/API"
+	"github.com/alsey89/people-matter/internal/common/errmgr"
+
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+type OnboardCompanyRequest struct {
+	Name    string `json:"name"    validate:"required,max=255"`
+	Email   string `json:"email"   validate:"required,email,max=255"`
+	Phone   string `json:"phone"   validate:"omitempty,max=255"`
+	Website string `json:"website" validate:"omitempty,url,max=255"`
+	// defaults to the location address
+	Address *API.AddressRequest `json:"address"`
+}
+
+type OnboardOwnerRequest struct {
+	Name     string `json:"name"     validate:"required,max=255"`
+	Email    string `json:"email"    validate:"required,email,max=255"`
+	Password string `json:"password" validate:"required,min=8,max=72"`
+}
+
+type OnboardLocationRequest struct {
+	Name    string             `json:"name"    validate:"required,max=255"`
+	Address API.AddressRequest `json:"address"`
+}
+
+type OnboardRequest struct {
+	TenantID string                 `json:"tenantId" validate:"required,max=63"`
+	Company  OnboardCompanyRequest  `json:"company"`
+	Owner    OnboardOwnerRequest    `json:"owner"`
+	Location OnboardLocationRequest `json:"location"`
+}
+
+// Reports whether a tenant ID is available, so the signup form can check it before submitting.
+func (d *Domain) CheckTenantIDHandler(c echo.Context) error {
+	tenantID := normalizeTenantID(c.Param("tenantId"))
+
+	available, reason, err := d.checkTenantID(c.Request().Context(), tenantID)
+	if err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("CheckTenantIDHandler: %w", err))
+	}
+
+	return c.JSON(http.StatusOK, API.Response{
+		Message: "Tenant ID checked.",
+		Data: map[string]interface{}{
+			"tenantId":  tenantID,
+			"available": available,
+			"reason":    reason,
+		},
+	})
+}
+
+// Creates a new tenant with its owner and initial location, and emails the owner a welcome and verification link.
+func (d *Domain) OnboardHandler(c echo.Context) error {
+	var req OnboardRequest
+	if err := c.Bind(&req); err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("OnboardHandler: %w: %v", errmgr.ErrPayload, err))
+	}
+	if err := c.Validate(&req); err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("OnboardHandler: %w: %v", errmgr.ErrPayload, err))
+	}
+
+	result, err := d.onboard(c.Request().Context(), req)
+	if err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("OnboardHandler: %w", err))
+	}
+
+	// the tenant exists at this point, so a failed email is logged instead of failing the request
+	err = d.params.Identity.SendVerificationEmail(result.Owner, d.config.welcomeTemplateID)
+	if err != nil {
+		d.logger.Error("OnboardHandler: error sending welcome email", zap.Error(err))
+	}
+
+	return c.JSON(http.StatusCreated, API.Response{
+		Message: "Company created. Please verify your email.",
+		Data:    result,
+	})
+}
@@ -0,0 +1,165 @@
+package onboarding
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/alsey89/people-matter/internal/common/errmgr"
+	"github.com/alsey89/people-matter/internal/common/quota"
+	"github.com/alsey89/people-matter/internal/common/rbac"
+	"github.com/alsey89/people-matter/internal/common/util"
+	"github.com/alsey89/people-matter/internal/identity"
+	"github.com/alsey89/people-matter/internal/schema"
+	"github.com/alsey89/people-matter/pkg/pgconn"
+)
+
+// Everything created for a new tenant, returned to the client after onboarding.
+type OnboardingResult struct {
+	TenantID string           `json:"tenantId"`
+	URL      string           `json:"url"`
+	Company  *schema.Company  `json:"company"`
+	Owner    *schema.User     `json:"owner"`
+	Location *schema.Location `json:"location"`
+}
+
+/*
+Returns whether the tenant ID can be used for a new company, and the reason if it cannot.
+Soft deleted companies keep their tenant ID, so that old subdomains never point to a new tenant.
+*/
+func (d *Domain) checkTenantID(ctx context.Context, tenantID string) (bool, string, error) {
+	tenantID = normalizeTenantID(tenantID)
+	if problem := tenantIDProblem(tenantID); problem != "" {
+		return false, problem, nil
+	}
+
+	var count int64
+	err := d.params.DB.GetUnscopedDB(ctx).
+		Unscoped().
+		Model(&schema.Company{}).
+		Where("tenant_id = ?", tenantID).
+		Count(&count).Error
+	if err != nil {
+		return false, "", fmt.Errorf("checkTenantID: %w", err)
+	}
+	if count > 0 {
+		return false, "is already in use", nil
+	}
+
+	return true, "", nil
+}
+
+/*
+Creates the company with its permission catalog and system positions, the owner account,
+the initial location and the owner's position at it, all in one transaction.
+The tenant ID is reserved by the unique index on companies.tenant_id, so concurrent requests
+for the same tenant ID cannot both succeed. The owner still has to verify their email.
+*/
+func (d *Domain) onboard(ctx context.Context, req OnboardRequest) (*OnboardingResult, error) {
+	tenantID := normalizeTenantID(req.TenantID)
+	if problem := tenantIDProblem(tenantID); problem != "" {
+		return nil, fmt.Errorf("onboard: %w: %s %s", errmgr.ErrTenantIDInvalid, tenantID, problem)
+	}
+
+	passwordHash, err := identity.HashPassword(req.Owner.Password)
+	if err != nil {
+		return nil, fmt.Errorf("onboard: %w", err)
+	}
+
+	companyAddress := req.Location.Address.ToSchema()
+	if req.Company.Address != nil {
+		companyAddress = req.Company.Address.ToSchema()
+	}
+
+	company := schema.Company{
+		TenantID:       tenantID,
+		Name:           strings.TrimSpace(req.Company.Name),
+		Email:          identity.NormalizeEmail(req.Company.Email),
+		Phone:          strings.TrimSpace(req.Company.Phone),
+		Website:        strings.TrimSpace(req.Company.Website),
+		ContactAddress: companyAddress,
+		BillingAddress: companyAddress,
+	}
+	location := schema.Location{
+		Name:    strings.TrimSpace(req.Location.Name),
+		Address: req.Location.Address.ToSchema(),
+	}
+	owner := schema.User{
+		Name:          strings.TrimSpace(req.Owner.Name),
+		Email:         identity.NormalizeEmail(req.Owner.Email),
+		PasswordHash:  passwordHash,
+		EmailVerified: false,
+	}
+
+	err = d.params.DB.WithTransaction(ctx, func(ctx context.Context) error {
+		err := d.params.DB.GetUnscopedDB(ctx).Create(&company).Error
+		if err != nil {
+			if pgconn.IsUniqueViolation(err, "idx_companies_tenant_id") {
+				return errmgr.ErrTenantIDTaken
+			}
+			return err
+		}
+
+		ctx, err = d.params.DB.BindCompany(ctx, company.ID)
+		if err != nil {
+			return err
+		}
+		tx, err := d.params.DB.GetScopedDB(ctx)
+		if err != nil {
+			return err
+		}
+
+		err = rbac.SeedCompany(tx)
+		if err != nil {
+			return err
+		}
+
+		err = quota.ReserveLocation(tx)
+		if err != nil {
+			return err
+		}
+		err = tx.Create(&location).Error
+		if err != nil {
+			return err
+		}
+
+		err = quota.ReserveEmployee(tx)
+		if err != nil {
+			return err
+		}
+		err = tx.Create(&owner).Error
+		if err != nil {
+			return err
+		}
+
+		var ownerPosition schema.Position
+		err = tx.Where("system_key = ?", rbac.OwnerPositionKey).First(&ownerPosition).Error
+		if err != nil {
+			return fmt.Errorf("owner position: %w", err)
+		}
+
+		return tx.Create(&schema.UserPosition{
+			UserID:     owner.ID,
+			PositionID: ownerPosition.ID,
+			LocationID: location.ID,
+			StartedAt:  time.Now().UTC(),
+		}).Error
+	})
+	if err != nil {
+		return nil, fmt.Errorf("onboard: %w", err)
+	}
+
+	url, err := util.PathToFullURL("/", tenantID, d.config.clientDomain)
+	if err != nil {
+		return nil, fmt.Errorf("onboard: %w", err)
+	}
+
+	return &OnboardingResult{
+		TenantID: tenantID,
+		URL:      *url,
+		Company:  &company,
+		Owner:    &owner,
+		Location: &location,
+	}, nil
+}
@@ -0,0 +1,52 @@
+package onboarding
+
+import (
+	"strings"
+
+	"github.com/alsey89/people-matter/internal/common/util"
+)
+
+// Subdomains used by the platform itself, which can never become tenant IDs.
+var reservedTenantIDs = map[string]bool{
+	"admin":      true,
+	"api":        true,
+	"app":        true,
+	"assets":     true,
+	"auth":       true,
+	"billing":    true,
+	"blog":       true,
+	"cdn":        true,
+	"dashboard":  true,
+	"dev":        true,
+	"docs":       true,
+	"help":       true,
+	"mail":       true,
+	"onboarding": true,
+	"signup":     true,
+	"smtp":       true,
+	"staging":    true,
+	"static":     true,
+	"status":     true,
+	"support":    true,
+	"test":       true,
+	"www":        true,
+}
+
+func normalizeTenantID(tenantID string) string {
+	return strings.ToLower(strings.TrimSpace(tenantID))
+}
+
+/*
+Returns an empty string if the normalized tenant ID can be used as a subdomain,
+otherwise the reason why it cannot, for display to the user.
+Whether it is already taken is checked against the database separately.
+*/
+func tenantIDProblem(tenantID string) string {
+	if !util.IsValidSubdomain(tenantID) {
+		return "must be 3-63 lowercase letters, digits or hyphens, and cannot start or end with a hyphen"
+	}
+	if reservedTenantIDs[tenantID] {
+		return "is reserved"
+	}
+	return ""
+}
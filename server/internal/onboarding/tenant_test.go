@@ -0,0 +1,35 @@
+package onboarding
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizeTenantID(t *testing.T) {
+	assert.Equal(t, "acme", normalizeTenantID("  ACME "))
+}
+
+func TestTenantIDProblem(t *testing.T) {
+	tests := []struct {
+		name     string
+		tenantID string
+		valid    bool
+	}{
+		{"valid", "acme", true},
+		{"valid with digits and hyphen", "acme-2", true},
+		{"too short", "ac", false},
+		{"uppercase", "Acme", false},
+		{"leading hyphen", "-acme", false},
+		{"trailing hyphen", "acme-", false},
+		{"dot", "acme.corp", false},
+		{"reserved", "www", false},
+		{"reserved api", "api", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.valid, tenantIDProblem(tt.tenantID) == "")
+		})
+	}
+}
@@ -0,0 +1,139 @@
+package payroll
+
+import (
+	"context"
+
+	"github.com/alsey89/people-matter/internal/common/middleware"
+	"github.com/alsey89/people-matter/internal/common/rbac"
+	"github.com/alsey89/people-matter/internal/common/util"
+	"github.com/alsey89/people-matter/internal/identity"
+	"github.com/alsey89/people-matter/internal/timekeeping"
+	"github.com/alsey89/people-matter/pkg/pgconn"
+	"github.com/alsey89/people-matter/pkg/server"
+	"github.com/alsey89/people-matter/pkg/token"
+
+	"github.com/labstack/echo/v4"
+	"github.com/spf13/viper"
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+)
+
+type Domain struct {
+	scope  string
+	logger *zap.Logger
+	config *Config
+	params Params
+}
+
+type Params struct {
+	fx.In
+	Lifecycle   fx.Lifecycle
+	Logger      *zap.Logger
+	DB          *pgconn.Module
+	Server      *server.Module
+	Token       *token.Module
+	Timekeeping *timekeeping.Domain
+}
+
+type Config struct {
+	clientDomain string
+}
+
+const (
+	defaultClientDomain = "localhost:3000"
+)
+
+// ! Domain ---------------------------------------------------------------
+
+func InjectDomain(scope string) fx.Option {
+	return fx.Module(
+		scope,
+		fx.Provide(func(p Params) *Domain {
+			d := &Domain{scope: scope}
+			d.params = p
+			d.logger = d.setupLogger(scope, p)
+			d.config = d.setupConfig(scope)
+
+			return d
+		}),
+		fx.Invoke(func(d *Domain, p Params) {
+			d.registerRoutes()
+
+			p.Lifecycle.Append(
+				fx.Hook{
+					OnStart: d.onStart,
+					OnStop:  d.onStop,
+				},
+			)
+		}),
+	)
+}
+
+// ! Internal ---------------------------------------------------------------
+
+func (d *Domain) setupLogger(scope string, p Params) *zap.Logger {
+	logger := p.Logger.Named("[" + scope + "]")
+	return logger
+}
+
+func (d *Domain) setupConfig(scope string) *Config {
+	viper.SetDefault(util.GetConfigPath("global", "client_domain"), defaultClientDomain)
+
+	return &Config{
+		clientDomain: viper.GetString(util.GetConfigPath("global", "client_domain")),
+	}
+}
+
+func (d *Domain) registerRoutes() {
+	e := d.params.Server.GetServer()
+
+	resolveTenant := middleware.ResolveTenant(d.params.DB, d.logger, d.config.clientDomain)
+	requireAuth := d.params.Token.GetJWTMiddleware(identity.AuthTokenScope)
+	requireSameTenant := middleware.RequireSameTenant(d.logger)
+	requireActiveSession := middleware.RequireActiveSession(d.params.DB, d.logger)
+
+	// runs pay the whole company, handlers require the permission company wide
+	runGroup := e.Group("/api/v1/payroll/runs", resolveTenant, requireAuth, requireSameTenant, requireActiveSession)
+	runGroup.GET("", d.ListPayrollRunsHandler, d.requirePermission(rbac.PayrollRead))
+	runGroup.POST("", d.CreatePayrollRunHandler, d.requirePermission(rbac.PayrollWrite))
+	runGroup.GET("/:id", d.GetPayrollRunHandler, d.requirePermission(rbac.PayrollRead))
+	runGroup.DELETE("/:id", d.DeletePayrollRunHandler, d.requirePermission(rbac.PayrollWrite))
+	runGroup.POST("/:id/recalculate", d.RecalculatePayrollRunHandler, d.requirePermission(rbac.PayrollWrite))
+	runGroup.POST("/:id/approve", d.ApprovePayrollRunHandler, d.requirePermission(rbac.PayrollWrite))
+	runGroup.POST("/:id/pay", d.PayPayrollRunHandler, d.requirePermission(rbac.PayrollWrite))
+	runGroup.POST("/:id/void", d.VoidPayrollRunHandler, d.requirePermission(rbac.PayrollWrite))
+
+	// handlers scope the employee to the locations the caller holds the permission at
+	employeeGroup := e.Group("/api/v1/employees/:id", resolveTenant, requireAuth, requireSameTenant, requireActiveSession)
+	for _, kind := range itemKinds {
+		employeeGroup.GET("/"+kind, d.listItemsHandler(kind), d.requirePermission(rbac.PayrollRead))
+		employeeGroup.POST("/"+kind, d.createItemHandler(kind), d.requirePermission(rbac.PayrollWrite))
+		employeeGroup.PUT("/"+kind+"/:itemId", d.updateItemHandler(kind), d.requirePermission(rbac.PayrollWrite))
+		employeeGroup.DELETE("/"+kind+"/:itemId", d.deleteItemHandler(kind), d.requirePermission(rbac.PayrollWrite))
+	}
+}
+
+func (d *Domain) requirePermission(permission string) echo.MiddlewareFunc {
+	return middleware.RequirePermission(d.params.DB, d.logger, permission)
+}
+
+func (d *Domain) onStart(ctx context.Context) error {
+	d.logger.Info("Starting payroll domain.")
+
+	if viper.GetString("global.log_level") == "DEBUG" || viper.GetString("global.log_level") == "debug" {
+		d.logConfigurations()
+	}
+
+	return nil
+}
+
+func (d *Domain) onStop(ctx context.Context) error {
+	d.logger.Info("Stopping payroll domain.")
+	return nil
+}
+
+func (d *Domain) logConfigurations() {
+	d.logger.Debug("----- Payroll Configuration -----")
+	d.logger.Debug("Client Domain", zap.String("client_domain", d.config.clientDomain))
+	d.logger.Debug("---------------------------------")
+}
@@ -0,0 +1,323 @@
+package payroll
+
+import (
+	"fmt"
+	"math/big"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/alsey89/people-matter/internal/common/errmgr"
+	"github.com/alsey89/people-matter/internal/common/money"
+	"github.com/alsey89/people-matter/internal/schema"
+	"github.com/alsey89/people-matter/internal/timekeeping"
+
+	"gorm.io/gorm"
+)
+
+// Pay periods are whole days, like timesheets: a run covers PeriodStart through PeriodEnd,
+// which is the half open period [PeriodStart, PeriodEnd + 1 day) in time.
+
+const dateLayout = "2006-01-02"
+
+/*
+Returns the last day of the pay period of the interval starting on start. Hourly compensations have no
+fixed pay period, their runs need an explicit end. Monthly and annual periods cannot start after the 28th,
+so that every period ends on the day before the same day of the next month or year.
+*/
+func defaultPeriodEnd(interval string, start time.Time) (time.Time, error) {
+	switch interval {
+	case schema.CompensationIntervalWeekly:
+		return start.AddDate(0, 0, 6), nil
+	case schema.CompensationIntervalBiWeekly:
+		return start.AddDate(0, 0, 13), nil
+	case schema.CompensationIntervalMonthly, schema.CompensationIntervalAnnually:
+		if start.Day() > 28 {
+			return time.Time{}, fmt.Errorf("defaultPeriodEnd: %w: %s periods cannot start after the 28th", errmgr.ErrPayload, interval)
+		}
+		if interval == schema.CompensationIntervalMonthly {
+			return start.AddDate(0, 1, -1), nil
+		}
+		return start.AddDate(1, 0, -1), nil
+	}
+	return time.Time{}, fmt.Errorf("defaultPeriodEnd: %w: %s runs need a period end", errmgr.ErrPayload, interval)
+}
+
+/*
+Fails with errmgr.ErrPayload unless the period ends on or after its start and is not paid before it starts.
+Runs of salaried intervals must cover exactly one pay period, see defaultPeriodEnd, as salaries are paid
+per pay period and prorated by the share of it each compensation covers.
+*/
+func validatePeriod(interval string, start time.Time, end time.Time, payDate time.Time) error {
+	if end.Before(start) {
+		return fmt.Errorf("validatePeriod: %w: end %s is before start %s", errmgr.ErrPayload, end.Format(dateLayout), start.Format(dateLayout))
+	}
+	if interval != schema.CompensationIntervalHourly {
+		periodEnd, err := defaultPeriodEnd(interval, start)
+		if err != nil {
+			return fmt.Errorf("validatePeriod: %w", err)
+		}
+		if !end.Equal(periodEnd) {
+			return fmt.Errorf("validatePeriod: %w: %s periods starting %s end %s, not %s", errmgr.ErrPayload, interval, start.Format(dateLayout), periodEnd.Format(dateLayout), end.Format(dateLayout))
+		}
+	}
+	if payDate.Before(start) {
+		return fmt.Errorf("validatePeriod: %w: pay date %s is before start %s", errmgr.ErrPayload, payDate.Format(dateLayout), start.Format(dateLayout))
+	}
+	return nil
+}
+
+// Returns the run's period as [start, end) in time.
+func runPeriod(run schema.PayrollRun) (time.Time, time.Time) {
+	return run.PeriodStart, run.PeriodEnd.AddDate(0, 0, 1)
+}
+
+// Returns the part [from, until) of the period [start, end) the compensation covers, ok is false if it covers none of it.
+func coverage(compensation schema.Compensation, start time.Time, end time.Time) (from time.Time, until time.Time, ok bool) {
+	from, until = start, end
+	if compensation.StartedAt.After(from) {
+		from = compensation.StartedAt
+	}
+	if compensation.EndedAt != nil && compensation.EndedAt.Before(until) {
+		until = *compensation.EndedAt
+	}
+	return from, until, from.Before(until)
+}
+
+// Prorates the amount of a salaried compensation by the share of the period [start, end) it covers with [from, until).
+func proratedAmount(amount money.Money, from time.Time, until time.Time, start time.Time, end time.Time) (money.Money, error) {
+	if from.Equal(start) && until.Equal(end) {
+		return amount, nil
+	}
+	prorated, err := amount.Scale(int64(until.Sub(from)/time.Second), int64(end.Sub(start)/time.Second))
+	if err != nil {
+		return money.Money{}, fmt.Errorf("proratedAmount: %w", err)
+	}
+	return prorated, nil
+}
+
+// Pays the hourly rate for the hours, rounded to the currency's minor unit.
+func hourlyAmount(rate money.Money, hours float64) (money.Money, error) {
+	factor, ok := new(big.Rat).SetString(strconv.FormatFloat(hours, 'f', -1, 64))
+	if !ok {
+		return money.Money{}, fmt.Errorf("hourlyAmount: invalid hours %v", hours)
+	}
+	amount, err := rate.Mul(factor)
+	if err != nil {
+		return money.Money{}, fmt.Errorf("hourlyAmount: %w", err)
+	}
+	return amount, nil
+}
+
+/*
+Sums the approved hours of the timesheets ending within [from, until). Hours are paid by the run
+whose period holds the last day of their timesheet, as timesheets are approved after they end.
+*/
+func coveredHours(hours []timekeeping.ApprovedHours, from time.Time, until time.Time) (float64, error) {
+	var total float64
+	for _, h := range hours {
+		lastDay, err := time.Parse(dateLayout, h.PeriodEnd)
+		if err != nil {
+			return 0, fmt.Errorf("coveredHours: timesheet %d: %w", h.TimesheetID, err)
+		}
+		// the last day counts as a whole, so a compensation starting during it still covers it
+		if lastDay.AddDate(0, 0, 1).After(from) && lastDay.Before(until) {
+			total += h.Hours
+		}
+	}
+	return total, nil
+}
+
+/*
+Builds the draft payments of the run, one per compensation covering part of its period. Salaried compensations
+are prorated by the time they cover, hourly ones pay the approved hours of the employee's timesheets ending
+while they are effective. Net amounts start out as the base, bonuses, expenses and adjustments are added once
+attached.
+*/
+func buildPayments(run schema.PayrollRun, compensations []schema.Compensation, hours []timekeeping.ApprovedHours) ([]schema.Payment, error) {
+	start, end := runPeriod(run)
+
+	hoursByUser := map[uint][]timekeeping.ApprovedHours{}
+	for _, h := range hours {
+		hoursByUser[h.UserID] = append(hoursByUser[h.UserID], h)
+	}
+
+	payments := []schema.Payment{}
+	for _, compensation := range compensations {
+		from, until, ok := coverage(compensation, start, end)
+		if !ok {
+			continue
+		}
+
+		payment := schema.Payment{
+			UserID:         compensation.UserID,
+			CompensationID: compensation.ID,
+			PayrollRunID:   &run.ID,
+			Currency:       compensation.Currency,
+			PaidAt:         run.PayDate,
+			Status:         schema.PaymentStatusDraft,
+			PeriodStart:    &from,
+			PeriodEnd:      &until,
+		}
+
+		var base money.Money
+		var err error
+		if compensation.Interval == schema.CompensationIntervalHourly {
+			var covered float64
+			covered, err = coveredHours(hoursByUser[compensation.UserID], from, until)
+			if err != nil {
+				return nil, fmt.Errorf("buildPayments: compensation %d: %w", compensation.ID, err)
+			}
+			payment.Hours = &covered
+			base, err = hourlyAmount(compensation.Amount, covered)
+		} else {
+			base, err = proratedAmount(compensation.Amount, from, until, start, end)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("buildPayments: compensation %d: %w", compensation.ID, err)
+		}
+		payment.BaseAmount = base
+		payment.NetAmount = base
+
+		payments = append(payments, payment)
+	}
+
+	return payments, nil
+}
+
+// Sums the net amounts of the payments per currency, ordered by currency.
+func netTotals(payments []schema.Payment) ([]money.Money, error) {
+	totals := map[string]money.Money{}
+	for _, payment := range payments {
+		total, ok := totals[payment.Currency]
+		if !ok {
+			total = money.New(0, payment.Currency)
+		}
+		total, err := total.Add(payment.NetAmount)
+		if err != nil {
+			return nil, fmt.Errorf("netTotals: payment %d: %w", payment.ID, err)
+		}
+		totals[payment.Currency] = total
+	}
+
+	result := make([]money.Money, 0, len(totals))
+	for _, total := range totals {
+		result = append(result, total)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Currency < result[j].Currency })
+	return result, nil
+}
+
+/*
+Reports whether approving a run with the totals needs a second approver: the totals, converted into the threshold's
+currency, add up to more than the threshold. Without a threshold no run does. A total that cannot be converted
+for lack of an exchange rate needs a second approver, as it may be above.
+*/
+func needsSecondApproval(totals []money.Money, threshold *money.Money, rates money.Rates) (bool, error) {
+	if threshold == nil {
+		return false, nil
+	}
+
+	sum := money.New(0, threshold.Currency)
+	for _, total := range totals {
+		rate, ok := rates.Rate(total.Currency, threshold.Currency)
+		if !ok {
+			return true, nil
+		}
+		converted, err := total.Convert(rate, threshold.Currency)
+		if err != nil {
+			return false, fmt.Errorf("needsSecondApproval: %w", err)
+		}
+		sum, err = sum.Add(converted)
+		if err != nil {
+			return false, fmt.Errorf("needsSecondApproval: %w", err)
+		}
+	}
+
+	cmp, err := sum.Cmp(*threshold)
+	if err != nil {
+		return false, fmt.Errorf("needsSecondApproval: %w", err)
+	}
+	return cmp > 0, nil
+}
+
+/*
+Returns the pending adjustments offsetting the paid payments of a voided run, each the negated net amount
+of its payment, so that the next run paying the employee recovers it. Payments that were not paid need no offset.
+*/
+func reversalAdjustments(run schema.PayrollRun, payments []schema.Payment) []schema.Adjustment {
+	adjustments := []schema.Adjustment{}
+	for _, payment := range payments {
+		if payment.Status != schema.PaymentStatusPaid || payment.NetAmount.IsZero() {
+			continue
+		}
+		paymentID := payment.ID
+		adjustments = append(adjustments, schema.Adjustment{
+			UserID:            payment.UserID,
+			Amount:            payment.NetAmount.Neg(),
+			Currency:          payment.Currency,
+			Description:       fmt.Sprintf("Reversal of payment %d of voided payroll run %d", payment.ID, run.ID),
+			ReversedPaymentID: &paymentID,
+		})
+	}
+	return adjustments
+}
+
+// Payroll items are bonuses, expenses and adjustments. Their models share their columns, so they are handled
+// as a PayrollItem in the table of their kind. Kinds are the path segments of their routes.
+const (
+	ItemKindBonuses     = "bonuses"
+	ItemKindExpenses    = "expenses"
+	ItemKindAdjustments = "adjustments"
+)
+
+var itemKinds = []string{ItemKindBonuses, ItemKindExpenses, ItemKindAdjustments}
+
+// Tables of the item kinds, as named by GORM for schema.Bonus, schema.Expense and schema.Adjustment.
+var itemTables = map[string]string{
+	ItemKindBonuses:     "bonus",
+	ItemKindExpenses:    "expenses",
+	ItemKindAdjustments: "adjustments",
+}
+
+// A bonus, expense or adjustment of an employee. Items without a payment are pending.
+type PayrollItem struct {
+	gorm.Model
+	CompanyID   uint        `json:"companyId"`
+	UserID      uint        `json:"userId"`
+	PaymentID   *uint       `json:"paymentId"`
+	Amount      money.Money `json:"amount"      gorm:"currency:Currency"`
+	Currency    string      `json:"currency"`
+	Description string      `json:"description"`
+}
+
+// Returns db on the table of the item kind.
+func itemTable(db *gorm.DB, kind string) *gorm.DB {
+	return db.Model(&PayrollItem{}).Table(itemTables[kind])
+}
+
+/*
+Assigns items to the payments of their user in the same currency, the latest covered one if there are several,
+e.g. after a raise. Items without such a payment stay pending. Returns the items by the index of their payment.
+*/
+func assignItems(payments []schema.Payment, items []PayrollItem) map[int][]PayrollItem {
+	type key struct {
+		userID   uint
+		currency string
+	}
+	latest := map[key]int{}
+	for i, payment := range payments {
+		k := key{payment.UserID, payment.Currency}
+		if j, ok := latest[k]; !ok || payments[j].PeriodStart.Before(*payment.PeriodStart) {
+			latest[k] = i
+		}
+	}
+
+	assigned := map[int][]PayrollItem{}
+	for _, item := range items {
+		if i, ok := latest[key{item.UserID, item.Currency}]; ok {
+			assigned[i] = append(assigned[i], item)
+		}
+	}
+	return assigned
+}
@@ -0,0 +1,182 @@
+package payroll
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alsey89/people-matter/internal/common/errmgr"
+	"github.com/alsey89/people-matter/internal/common/money"
+	"github.com/alsey89/people-matter/internal/schema"
+	"github.com/alsey89/people-matter/internal/timekeeping"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func date(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+func TestDefaultPeriodEnd(t *testing.T) {
+	end, err := defaultPeriodEnd(schema.CompensationIntervalMonthly, date(2024, 2, 1))
+	require.NoError(t, err)
+	assert.Equal(t, date(2024, 2, 29), end)
+
+	end, err = defaultPeriodEnd(schema.CompensationIntervalMonthly, date(2024, 1, 15))
+	require.NoError(t, err)
+	assert.Equal(t, date(2024, 2, 14), end)
+
+	end, err = defaultPeriodEnd(schema.CompensationIntervalBiWeekly, date(2024, 3, 4))
+	require.NoError(t, err)
+	assert.Equal(t, date(2024, 3, 17), end)
+
+	_, err = defaultPeriodEnd(schema.CompensationIntervalMonthly, date(2024, 1, 31))
+	assert.ErrorIs(t, err, errmgr.ErrPayload)
+	_, err = defaultPeriodEnd(schema.CompensationIntervalHourly, date(2024, 3, 1))
+	assert.ErrorIs(t, err, errmgr.ErrPayload)
+}
+
+func TestValidatePeriod(t *testing.T) {
+	hourly, monthly := schema.CompensationIntervalHourly, schema.CompensationIntervalMonthly
+
+	assert.NoError(t, validatePeriod(hourly, date(2024, 3, 1), date(2024, 3, 1), date(2024, 3, 1)))
+	assert.NoError(t, validatePeriod(hourly, date(2024, 3, 1), date(2024, 5, 31), date(2024, 5, 31)))
+	assert.ErrorIs(t, validatePeriod(hourly, date(2024, 3, 1), date(2024, 2, 29), date(2024, 3, 1)), errmgr.ErrPayload)
+	assert.ErrorIs(t, validatePeriod(hourly, date(2024, 3, 1), date(2024, 3, 31), date(2024, 2, 29)), errmgr.ErrPayload)
+
+	assert.NoError(t, validatePeriod(monthly, date(2024, 3, 1), date(2024, 3, 31), date(2024, 3, 31)))
+	// salaries are paid per pay period, a run covering any other length would pay a full one
+	assert.ErrorIs(t, validatePeriod(monthly, date(2024, 3, 1), date(2024, 3, 1), date(2024, 3, 1)), errmgr.ErrPayload, "one day")
+	assert.ErrorIs(t, validatePeriod(monthly, date(2024, 3, 1), date(2024, 5, 31), date(2024, 5, 31)), errmgr.ErrPayload, "three months")
+	assert.ErrorIs(t, validatePeriod(monthly, date(2024, 1, 31), date(2024, 2, 29), date(2024, 2, 29)), errmgr.ErrPayload, "starts after the 28th")
+}
+
+func TestBuildPayments(t *testing.T) {
+	// April has 30 days
+	run := schema.PayrollRun{PeriodStart: date(2024, 4, 1), PeriodEnd: date(2024, 4, 30), PayDate: date(2024, 4, 30)}
+	run.ID = 9
+	raise := date(2024, 4, 11)
+	compensations := []schema.Compensation{
+		{UserID: 1, Amount: money.New(300000, "USD"), Currency: "USD", Interval: schema.CompensationIntervalMonthly, StartedAt: date(2024, 1, 1), EndedAt: &raise},
+		{UserID: 1, Amount: money.New(600000, "USD"), Currency: "USD", Interval: schema.CompensationIntervalMonthly, StartedAt: raise},
+		{UserID: 2, Amount: money.New(100000, "EUR"), Currency: "EUR", Interval: schema.CompensationIntervalMonthly, StartedAt: date(2024, 5, 1)},
+		{UserID: 3, Amount: money.New(100000, "EUR"), Currency: "EUR", Interval: schema.CompensationIntervalMonthly, StartedAt: date(2024, 3, 1)},
+	}
+	compensations[0].ID, compensations[1].ID, compensations[2].ID, compensations[3].ID = 1, 2, 3, 4
+
+	payments, err := buildPayments(run, compensations, nil)
+	require.NoError(t, err)
+	require.Len(t, payments, 3, "compensation starting after the period is not paid")
+
+	assert.Equal(t, money.New(100000, "USD"), payments[0].BaseAmount, "10 of 30 days")
+	assert.Equal(t, date(2024, 4, 1), *payments[0].PeriodStart)
+	assert.Equal(t, raise, *payments[0].PeriodEnd)
+	assert.Equal(t, money.New(400000, "USD"), payments[1].BaseAmount, "20 of 30 days")
+	assert.Equal(t, date(2024, 5, 1), *payments[1].PeriodEnd)
+	assert.Equal(t, money.New(100000, "EUR"), payments[2].BaseAmount, "whole period")
+
+	for _, payment := range payments {
+		assert.Equal(t, schema.PaymentStatusDraft, payment.Status)
+		assert.Equal(t, uint(9), *payment.PayrollRunID)
+		assert.Equal(t, payment.BaseAmount, payment.NetAmount)
+		assert.Equal(t, run.PayDate, payment.PaidAt)
+	}
+}
+
+func TestBuildPaymentsHourly(t *testing.T) {
+	run := schema.PayrollRun{Interval: schema.CompensationIntervalHourly, PeriodStart: date(2024, 3, 1), PeriodEnd: date(2024, 3, 14)}
+	raise := date(2024, 3, 8)
+	compensations := []schema.Compensation{
+		{UserID: 1, Amount: money.New(2000, "USD"), Currency: "USD", Interval: schema.CompensationIntervalHourly, StartedAt: date(2024, 1, 1), EndedAt: &raise},
+		{UserID: 1, Amount: money.New(2550, "USD"), Currency: "USD", Interval: schema.CompensationIntervalHourly, StartedAt: raise},
+	}
+	hours := []timekeeping.ApprovedHours{
+		{TimesheetID: 1, UserID: 1, PeriodStart: "2024-02-26", PeriodEnd: "2024-03-03", Hours: 10},  // ends in the period
+		{TimesheetID: 2, UserID: 1, PeriodStart: "2024-03-04", PeriodEnd: "2024-03-08", Hours: 7.5}, // ends on the day of the raise
+		{TimesheetID: 3, UserID: 1, PeriodStart: "2024-03-11", PeriodEnd: "2024-03-17", Hours: 40},  // ends after the period
+		{TimesheetID: 4, UserID: 2, PeriodStart: "2024-03-04", PeriodEnd: "2024-03-08", Hours: 40},
+	}
+
+	payments, err := buildPayments(run, compensations, hours)
+	require.NoError(t, err)
+	require.Len(t, payments, 2)
+
+	assert.Equal(t, 10.0, *payments[0].Hours)
+	assert.Equal(t, money.New(20000, "USD"), payments[0].BaseAmount)
+	assert.Equal(t, 7.5, *payments[1].Hours)
+	assert.Equal(t, money.New(19125, "USD"), payments[1].BaseAmount)
+}
+
+func TestAssignItems(t *testing.T) {
+	march, april := date(2024, 3, 1), date(2024, 4, 1)
+	payments := []schema.Payment{
+		{UserID: 1, Currency: "USD", PeriodStart: &april},
+		{UserID: 1, Currency: "USD", PeriodStart: &march},
+		{UserID: 2, Currency: "EUR", PeriodStart: &march},
+	}
+	items := []PayrollItem{
+		{UserID: 1, Currency: "USD"},
+		{UserID: 2, Currency: "EUR"},
+		{UserID: 2, Currency: "USD"},
+		{UserID: 3, Currency: "USD"},
+	}
+
+	assigned := assignItems(payments, items)
+	assert.Equal(t, map[int][]PayrollItem{
+		0: {items[0]},
+		2: {items[1]},
+	}, assigned, "latest payment in the item's currency, others stay pending")
+}
+
+func TestNetTotals(t *testing.T) {
+	totals, err := netTotals([]schema.Payment{
+		{Currency: "USD", NetAmount: money.New(1000, "USD")},
+		{Currency: "EUR", NetAmount: money.New(500, "EUR")},
+		{Currency: "USD", NetAmount: money.New(-250, "USD")},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []money.Money{money.New(500, "EUR"), money.New(750, "USD")}, totals)
+}
+
+func TestNeedsSecondApproval(t *testing.T) {
+	threshold := money.New(100000, "USD")
+	rates := money.Rates{}
+	rates.SetFloat("EUR", "USD", 1.1)
+	totals := []money.Money{money.New(50000, "EUR"), money.New(40000, "USD")}
+
+	needed, err := needsSecondApproval(totals, nil, rates)
+	require.NoError(t, err)
+	assert.False(t, needed, "no threshold")
+
+	needed, err = needsSecondApproval(totals, &threshold, rates)
+	require.NoError(t, err)
+	assert.False(t, needed, "550 + 400 USD is not above 1000 USD")
+
+	totals = append(totals, money.New(6000, "USD"))
+	needed, err = needsSecondApproval(totals, &threshold, rates)
+	require.NoError(t, err)
+	assert.True(t, needed, "1010 USD is above 1000 USD")
+
+	needed, err = needsSecondApproval([]money.Money{money.New(100, "GBP")}, &threshold, rates)
+	require.NoError(t, err)
+	assert.True(t, needed, "without an exchange rate the total may be above")
+}
+
+func TestReversalAdjustments(t *testing.T) {
+	run := schema.PayrollRun{}
+	run.ID = 7
+	payments := []schema.Payment{
+		{UserID: 1, Currency: "USD", Status: schema.PaymentStatusPaid, NetAmount: money.New(250000, "USD")},
+		{UserID: 2, Currency: "USD", Status: schema.PaymentStatusScheduled, NetAmount: money.New(100000, "USD")},
+		{UserID: 3, Currency: "EUR", Status: schema.PaymentStatusPaid, NetAmount: money.New(0, "EUR")},
+	}
+	payments[0].ID = 11
+
+	adjustments := reversalAdjustments(run, payments)
+	require.Len(t, adjustments, 1)
+	assert.Equal(t, uint(1), adjustments[0].UserID)
+	assert.Equal(t, money.New(-250000, "USD"), adjustments[0].Amount)
+	assert.Equal(t, "USD", adjustments[0].Currency)
+	assert.Equal(t, uint(11), *adjustments[0].ReversedPaymentID)
+	assert.Nil(t, adjustments[0].PaymentID, "pending until the next run")
+}
@@ -0,0 +1,459 @@
+package payroll
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/alsey89/people-matter/internal/common/API"
+	"github.com/alsey89/people-matter/internal/common/errmgr"
+	"github.com/alsey89/people-matter/internal/common/extractor"
+	"github.com/alsey89/people-matter/internal/common/middleware"
+	"github.com/alsey89/people-matter/internal/common/money"
+	"github.com/alsey89/people-matter/internal/common/rbac"
+	"github.com/alsey89/people-matter/internal/schema"
+
+	"github.com/labstack/echo/v4"
+)
+
+type ListPayrollRunsQuery struct {
+	API.PageQuery
+}
+
+type CreatePayrollRunRequest struct {
+	Interval    string  `json:"interval"    validate:"required,oneof=hourly weekly bi-weekly monthly annually"`
+	PeriodStart string  `json:"periodStart" validate:"required"` // YYYY-MM-DD
+	PeriodEnd   *string `json:"periodEnd"`                       // inclusive, required for hourly runs, others must end with their pay period, the default
+	PayDate     string  `json:"payDate"     validate:"required"`
+}
+
+// Fails with errmgr.ErrPayload unless the dates are valid, see defaultPeriodEnd and validatePeriod.
+func (r CreatePayrollRunRequest) run() (schema.PayrollRun, error) {
+	start, err := time.Parse(dateLayout, r.PeriodStart)
+	if err != nil {
+		return schema.PayrollRun{}, fmt.Errorf("run: %w: invalid period start: %v", errmgr.ErrPayload, err)
+	}
+	payDate, err := time.Parse(dateLayout, r.PayDate)
+	if err != nil {
+		return schema.PayrollRun{}, fmt.Errorf("run: %w: invalid pay date: %v", errmgr.ErrPayload, err)
+	}
+
+	var end time.Time
+	if r.PeriodEnd != nil {
+		end, err = time.Parse(dateLayout, *r.PeriodEnd)
+		if err != nil {
+			return schema.PayrollRun{}, fmt.Errorf("run: %w: invalid period end: %v", errmgr.ErrPayload, err)
+		}
+	} else {
+		end, err = defaultPeriodEnd(r.Interval, start)
+		if err != nil {
+			return schema.PayrollRun{}, fmt.Errorf("run: %w", err)
+		}
+	}
+
+	err = validatePeriod(r.Interval, start, end, payDate)
+	if err != nil {
+		return schema.PayrollRun{}, fmt.Errorf("run: %w", err)
+	}
+
+	return schema.PayrollRun{
+		Interval:    r.Interval,
+		PeriodStart: start,
+		PeriodEnd:   end,
+		PayDate:     payDate,
+	}, nil
+}
+
+type PayrollItemRequest struct {
+	Amount      string `json:"amount"      validate:"required,max=32"` // decimal in major units, negative for deductions
+	Currency    string `json:"currency"    validate:"required,len=3,uppercase"`
+	Description string `json:"description" validate:"max=1000"`
+}
+
+// Fails with errmgr.ErrPayload unless the amount is valid: adjustments can be negative but not zero, other items must be positive.
+func (r PayrollItemRequest) item(kind string) (PayrollItem, error) {
+	amount, err := money.Parse(r.Amount, r.Currency)
+	if err != nil {
+		return PayrollItem{}, fmt.Errorf("item: %w: %v", errmgr.ErrPayload, err)
+	}
+	if amount.IsZero() || (amount.IsNegative() && kind != ItemKindAdjustments) {
+		return PayrollItem{}, fmt.Errorf("item: %w: amount of %s must be positive", errmgr.ErrPayload, kind)
+	}
+
+	return PayrollItem{
+		Amount:      amount,
+		Currency:    r.Currency,
+		Description: r.Description,
+	}, nil
+}
+
+// ! Runs ---------------------------------------------------------------
+
+// Lists the payroll runs, most recent period first.
+func (d *Domain) ListPayrollRunsHandler(c echo.Context) error {
+	err := d.requireCompanyWide(c, rbac.PayrollRead)
+	if err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("ListPayrollRunsHandler: %w", err))
+	}
+
+	var query ListPayrollRunsQuery
+	if err := c.Bind(&query); err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("ListPayrollRunsHandler: %w: %v", errmgr.ErrPayload, err))
+	}
+	if err := c.Validate(&query); err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("ListPayrollRunsHandler: %w: %v", errmgr.ErrPayload, err))
+	}
+
+	runs, total, err := d.listRuns(c.Request().Context(), query.PageQuery)
+	if err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("ListPayrollRunsHandler: %w", err))
+	}
+
+	return c.JSON(http.StatusOK, API.Response{
+		Message:    "Payroll runs retrieved.",
+		Data:       runs,
+		Pagination: query.Pagination(total),
+	})
+}
+
+// Creates a draft payroll run for a pay period, with a payment for every compensation of the interval.
+func (d *Domain) CreatePayrollRunHandler(c echo.Context) error {
+	err := d.requireCompanyWide(c, rbac.PayrollWrite)
+	if err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("CreatePayrollRunHandler: %w", err))
+	}
+
+	actorID, _, err := extractor.ExtractUserAndCompanyIDFromContext(c)
+	if err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("CreatePayrollRunHandler: %w: %v", errmgr.ErrInvalidToken, err))
+	}
+
+	var req CreatePayrollRunRequest
+	if err := c.Bind(&req); err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("CreatePayrollRunHandler: %w: %v", errmgr.ErrPayload, err))
+	}
+	if err := c.Validate(&req); err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("CreatePayrollRunHandler: %w: %v", errmgr.ErrPayload, err))
+	}
+
+	run, err := req.run()
+	if err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("CreatePayrollRunHandler: %w", err))
+	}
+
+	result, err := d.createRun(c.Request().Context(), actorID, run)
+	if err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("CreatePayrollRunHandler: %w", err))
+	}
+
+	return c.JSON(http.StatusCreated, API.Response{
+		Message: "Payroll run created.",
+		Data:    result,
+	})
+}
+
+// Returns a payroll run with its payments for review.
+func (d *Domain) GetPayrollRunHandler(c echo.Context) error {
+	err := d.requireCompanyWide(c, rbac.PayrollRead)
+	if err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("GetPayrollRunHandler: %w", err))
+	}
+
+	var runID uint
+	if _, err := extractor.ExtractFromPathParamAs(c, "id", &runID); err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("GetPayrollRunHandler: %w: %v", errmgr.ErrPayload, err))
+	}
+
+	result, err := d.getRun(c.Request().Context(), runID)
+	if err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("GetPayrollRunHandler: %w", err))
+	}
+
+	return c.JSON(http.StatusOK, API.Response{
+		Message: "Payroll run retrieved.",
+		Data:    result,
+	})
+}
+
+// Regenerates the payments of a draft payroll run.
+func (d *Domain) RecalculatePayrollRunHandler(c echo.Context) error {
+	err := d.requireCompanyWide(c, rbac.PayrollWrite)
+	if err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("RecalculatePayrollRunHandler: %w", err))
+	}
+
+	var runID uint
+	if _, err := extractor.ExtractFromPathParamAs(c, "id", &runID); err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("RecalculatePayrollRunHandler: %w: %v", errmgr.ErrPayload, err))
+	}
+
+	result, err := d.recalculateRun(c.Request().Context(), runID)
+	if err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("RecalculatePayrollRunHandler: %w", err))
+	}
+
+	return c.JSON(http.StatusOK, API.Response{
+		Message: "Payroll run recalculated.",
+		Data:    result,
+	})
+}
+
+// Approves a payroll run, scheduling its payments and locking it. Runs above the company's
+// payroll approval threshold stay drafts until a second user approves them. Approving it again has no effect.
+func (d *Domain) ApprovePayrollRunHandler(c echo.Context) error {
+	err := d.requireCompanyWide(c, rbac.PayrollWrite)
+	if err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("ApprovePayrollRunHandler: %w", err))
+	}
+
+	actorID, _, err := extractor.ExtractUserAndCompanyIDFromContext(c)
+	if err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("ApprovePayrollRunHandler: %w: %v", errmgr.ErrInvalidToken, err))
+	}
+
+	var runID uint
+	if _, err := extractor.ExtractFromPathParamAs(c, "id", &runID); err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("ApprovePayrollRunHandler: %w: %v", errmgr.ErrPayload, err))
+	}
+
+	result, err := d.approveRun(c.Request().Context(), actorID, runID)
+	if err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("ApprovePayrollRunHandler: %w", err))
+	}
+
+	message := "Payroll run approved."
+	if result.Status == schema.PayrollRunStatusDraft {
+		message = "Payroll run approval recorded, a second approver is required."
+	}
+
+	return c.JSON(http.StatusOK, API.Response{
+		Message: message,
+		Data:    result,
+	})
+}
+
+// Marks an approved payroll run and its payments as paid. Paying it again has no effect.
+func (d *Domain) PayPayrollRunHandler(c echo.Context) error {
+	err := d.requireCompanyWide(c, rbac.PayrollWrite)
+	if err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("PayPayrollRunHandler: %w", err))
+	}
+
+	actorID, _, err := extractor.ExtractUserAndCompanyIDFromContext(c)
+	if err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("PayPayrollRunHandler: %w: %v", errmgr.ErrInvalidToken, err))
+	}
+
+	var runID uint
+	if _, err := extractor.ExtractFromPathParamAs(c, "id", &runID); err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("PayPayrollRunHandler: %w: %v", errmgr.ErrPayload, err))
+	}
+
+	result, err := d.payRun(c.Request().Context(), actorID, runID)
+	if err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("PayPayrollRunHandler: %w", err))
+	}
+
+	return c.JSON(http.StatusOK, API.Response{
+		Message: "Payroll run paid.",
+		Data:    result,
+	})
+}
+
+// Voids an approved or paid payroll run, offsetting its paid payments with adjustments
+// and reissuing its bonuses, expenses and adjustments. Voiding it again has no effect.
+func (d *Domain) VoidPayrollRunHandler(c echo.Context) error {
+	err := d.requireCompanyWide(c, rbac.PayrollWrite)
+	if err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("VoidPayrollRunHandler: %w", err))
+	}
+
+	actorID, _, err := extractor.ExtractUserAndCompanyIDFromContext(c)
+	if err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("VoidPayrollRunHandler: %w: %v", errmgr.ErrInvalidToken, err))
+	}
+
+	var runID uint
+	if _, err := extractor.ExtractFromPathParamAs(c, "id", &runID); err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("VoidPayrollRunHandler: %w: %v", errmgr.ErrPayload, err))
+	}
+
+	result, err := d.voidRun(c.Request().Context(), actorID, runID)
+	if err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("VoidPayrollRunHandler: %w", err))
+	}
+
+	return c.JSON(http.StatusOK, API.Response{
+		Message: "Payroll run voided.",
+		Data:    result,
+	})
+}
+
+// Deletes a draft payroll run, its bonuses, expenses and adjustments become pending again.
+func (d *Domain) DeletePayrollRunHandler(c echo.Context) error {
+	err := d.requireCompanyWide(c, rbac.PayrollWrite)
+	if err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("DeletePayrollRunHandler: %w", err))
+	}
+
+	var runID uint
+	if _, err := extractor.ExtractFromPathParamAs(c, "id", &runID); err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("DeletePayrollRunHandler: %w: %v", errmgr.ErrPayload, err))
+	}
+
+	err = d.deleteRun(c.Request().Context(), runID)
+	if err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("DeletePayrollRunHandler: %w", err))
+	}
+
+	return c.JSON(http.StatusOK, API.Response{
+		Message: "Payroll run deleted.",
+	})
+}
+
+// ! Items ---------------------------------------------------------------
+
+// Lists the bonuses, expenses or adjustments of an employee the caller may read payroll of.
+func (d *Domain) listItemsHandler(kind string) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		permissions, err := middleware.LoadPermissions(c, d.params.DB)
+		if err != nil {
+			return API.RespondWithError(c, d.logger, fmt.Errorf("listItemsHandler: %w", err))
+		}
+
+		var userID uint
+		if _, err := extractor.ExtractFromPathParamAs(c, "id", &userID); err != nil {
+			return API.RespondWithError(c, d.logger, fmt.Errorf("listItemsHandler: %w: %v", errmgr.ErrPayload, err))
+		}
+
+		items, err := d.listItems(c.Request().Context(), kind, userID, permissions.ScopeUsers(rbac.PayrollRead, "users.id"))
+		if err != nil {
+			return API.RespondWithError(c, d.logger, fmt.Errorf("listItemsHandler: %w", err))
+		}
+
+		return c.JSON(http.StatusOK, API.Response{
+			Message: "Payroll items retrieved.",
+			Data:    items,
+		})
+	}
+}
+
+// Adds a pending bonus, expense or adjustment for an employee.
+func (d *Domain) createItemHandler(kind string) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		permissions, err := middleware.LoadPermissions(c, d.params.DB)
+		if err != nil {
+			return API.RespondWithError(c, d.logger, fmt.Errorf("createItemHandler: %w", err))
+		}
+
+		var userID uint
+		if _, err := extractor.ExtractFromPathParamAs(c, "id", &userID); err != nil {
+			return API.RespondWithError(c, d.logger, fmt.Errorf("createItemHandler: %w: %v", errmgr.ErrPayload, err))
+		}
+
+		var req PayrollItemRequest
+		if err := c.Bind(&req); err != nil {
+			return API.RespondWithError(c, d.logger, fmt.Errorf("createItemHandler: %w: %v", errmgr.ErrPayload, err))
+		}
+		if err := c.Validate(&req); err != nil {
+			return API.RespondWithError(c, d.logger, fmt.Errorf("createItemHandler: %w: %v", errmgr.ErrPayload, err))
+		}
+
+		item, err := req.item(kind)
+		if err != nil {
+			return API.RespondWithError(c, d.logger, fmt.Errorf("createItemHandler: %w", err))
+		}
+
+		result, err := d.createItem(c.Request().Context(), kind, userID, item, permissions.ScopeUsers(rbac.PayrollWrite, "users.id"))
+		if err != nil {
+			return API.RespondWithError(c, d.logger, fmt.Errorf("createItemHandler: %w", err))
+		}
+
+		return c.JSON(http.StatusCreated, API.Response{
+			Message: "Payroll item created.",
+			Data:    result,
+		})
+	}
+}
+
+// Updates a bonus, expense or adjustment that is pending or part of a draft payroll run.
+func (d *Domain) updateItemHandler(kind string) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		permissions, err := middleware.LoadPermissions(c, d.params.DB)
+		if err != nil {
+			return API.RespondWithError(c, d.logger, fmt.Errorf("updateItemHandler: %w", err))
+		}
+
+		var userID, itemID uint
+		if _, err := extractor.ExtractFromPathParamAs(c, "id", &userID); err != nil {
+			return API.RespondWithError(c, d.logger, fmt.Errorf("updateItemHandler: %w: %v", errmgr.ErrPayload, err))
+		}
+		if _, err := extractor.ExtractFromPathParamAs(c, "itemId", &itemID); err != nil {
+			return API.RespondWithError(c, d.logger, fmt.Errorf("updateItemHandler: %w: %v", errmgr.ErrPayload, err))
+		}
+
+		var req PayrollItemRequest
+		if err := c.Bind(&req); err != nil {
+			return API.RespondWithError(c, d.logger, fmt.Errorf("updateItemHandler: %w: %v", errmgr.ErrPayload, err))
+		}
+		if err := c.Validate(&req); err != nil {
+			return API.RespondWithError(c, d.logger, fmt.Errorf("updateItemHandler: %w: %v", errmgr.ErrPayload, err))
+		}
+
+		update, err := req.item(kind)
+		if err != nil {
+			return API.RespondWithError(c, d.logger, fmt.Errorf("updateItemHandler: %w", err))
+		}
+
+		result, err := d.updateItem(c.Request().Context(), kind, userID, itemID, update, permissions.ScopeUsers(rbac.PayrollWrite, "users.id"))
+		if err != nil {
+			return API.RespondWithError(c, d.logger, fmt.Errorf("updateItemHandler: %w", err))
+		}
+
+		return c.JSON(http.StatusOK, API.Response{
+			Message: "Payroll item updated.",
+			Data:    result,
+		})
+	}
+}
+
+// Deletes a bonus, expense or adjustment that is pending or part of a draft payroll run.
+func (d *Domain) deleteItemHandler(kind string) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		permissions, err := middleware.LoadPermissions(c, d.params.DB)
+		if err != nil {
+			return API.RespondWithError(c, d.logger, fmt.Errorf("deleteItemHandler: %w", err))
+		}
+
+		var userID, itemID uint
+		if _, err := extractor.ExtractFromPathParamAs(c, "id", &userID); err != nil {
+			return API.RespondWithError(c, d.logger, fmt.Errorf("deleteItemHandler: %w: %v", errmgr.ErrPayload, err))
+		}
+		if _, err := extractor.ExtractFromPathParamAs(c, "itemId", &itemID); err != nil {
+			return API.RespondWithError(c, d.logger, fmt.Errorf("deleteItemHandler: %w: %v", errmgr.ErrPayload, err))
+		}
+
+		err = d.deleteItem(c.Request().Context(), kind, userID, itemID, permissions.ScopeUsers(rbac.PayrollWrite, "users.id"))
+		if err != nil {
+			return API.RespondWithError(c, d.logger, fmt.Errorf("deleteItemHandler: %w", err))
+		}
+
+		return c.JSON(http.StatusOK, API.Response{
+			Message: "Payroll item deleted.",
+		})
+	}
+}
+
+// ! Helpers ---------------------------------------------------------------
+
+// Payroll runs pay every location, so managing them needs the permission company wide.
+func (d *Domain) requireCompanyWide(c echo.Context, permission string) error {
+	permissions, err := middleware.LoadPermissions(c, d.params.DB)
+	if err != nil {
+		return fmt.Errorf("requireCompanyWide: %w", err)
+	}
+	if !permissions.HasCompanyWide(permission) {
+		return fmt.Errorf("requireCompanyWide: %w: missing company wide %s", errmgr.ErrPermission, permission)
+	}
+	return nil
+}
@@ -0,0 +1,846 @@
+package payroll
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/alsey89/people-matter/internal/common/API"
+	"github.com/alsey89/people-matter/internal/common/errmgr"
+	"github.com/alsey89/people-matter/internal/common/lookup"
+	"github.com/alsey89/people-matter/internal/common/money"
+	"github.com/alsey89/people-matter/internal/schema"
+	"github.com/alsey89/people-matter/internal/timekeeping"
+	"github.com/alsey89/people-matter/pkg/pgconn"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ! Runs ---------------------------------------------------------------
+
+// Constraint named by the triggers refusing changes to approved runs and their payments and items.
+const payrollLockConstraint = "payroll_run_locked"
+
+// A payroll run with its payments and the sum of their net amounts per currency.
+type PayrollRunResult struct {
+	schema.PayrollRun
+	Totals []money.Money `json:"totals"`
+}
+
+/*
+Creates a draft run paying the compensations of the run's interval for its period, see calculateRun.
+Fails with errmgr.ErrPayrollRunOverlap if another run of the interval, that was not voided, covers part of the period.
+*/
+func (d *Domain) createRun(ctx context.Context, actorID uint, run schema.PayrollRun) (*PayrollRunResult, error) {
+	db, err := d.params.DB.GetScopedDB(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("createRun: %w: %v", errmgr.ErrTenant, err)
+	}
+
+	hours, err := d.approvedHours(ctx, run)
+	if err != nil {
+		return nil, fmt.Errorf("createRun: %w", err)
+	}
+
+	run.Status = schema.PayrollRunStatusDraft
+	run.CreatedByID = actorID
+	run.CalculatedAt = time.Now().UTC()
+	err = db.Transaction(func(tx *gorm.DB) error {
+		// serializes run creation per company, so the overlap check cannot race
+		err := lockCompany(ctx, tx)
+		if err != nil {
+			return err
+		}
+
+		var overlapping int64
+		err = tx.Model(&schema.PayrollRun{}).
+			Where("payroll_runs.interval = ? AND period_start <= ? AND period_end >= ?", run.Interval, run.PeriodEnd, run.PeriodStart).
+			Where("status <> ?", schema.PayrollRunStatusVoided).
+			Count(&overlapping).Error
+		if err != nil {
+			return err
+		}
+		if overlapping > 0 {
+			return errmgr.ErrPayrollRunOverlap
+		}
+
+		err = tx.Omit(clause.Associations).Create(&run).Error
+		if err != nil {
+			return err
+		}
+
+		return calculateRun(tx, &run, hours)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("createRun: %w", err)
+	}
+
+	return d.getRun(ctx, run.ID)
+}
+
+// Returns one page of the runs without their payments, most recent period first.
+func (d *Domain) listRuns(ctx context.Context, page API.PageQuery) ([]schema.PayrollRun, int64, error) {
+	db, err := d.params.DB.GetScopedDB(ctx)
+	if err != nil {
+		return nil, 0, fmt.Errorf("listRuns: %w: %v", errmgr.ErrTenant, err)
+	}
+
+	var total int64
+	err = db.Model(&schema.PayrollRun{}).Count(&total).Error
+	if err != nil {
+		return nil, 0, fmt.Errorf("listRuns: %w", err)
+	}
+
+	var runs []schema.PayrollRun
+	err = db.
+		Order("period_start DESC, id DESC").
+		Limit(page.Limit()).
+		Offset(page.Offset()).
+		Find(&runs).Error
+	if err != nil {
+		return nil, 0, fmt.Errorf("listRuns: %w", err)
+	}
+
+	return runs, total, nil
+}
+
+// Returns the run with its payments, their bonuses, expenses and adjustments, and the totals per currency.
+func (d *Domain) getRun(ctx context.Context, runID uint) (*PayrollRunResult, error) {
+	db, err := d.params.DB.GetScopedDB(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("getRun: %w: %v", errmgr.ErrTenant, err)
+	}
+
+	var run schema.PayrollRun
+	err = db.
+		Preload("Payments", func(db *gorm.DB) *gorm.DB {
+			return db.Order("user_id, period_start")
+		}).
+		Preload("Payments.Bonuses").
+		Preload("Payments.Expenses").
+		Preload("Payments.Adjustments").
+		Where("id = ?", runID).
+		First(&run).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("getRun: %w", errmgr.ErrPayrollRunNotFound)
+		}
+		return nil, fmt.Errorf("getRun: %w", err)
+	}
+
+	totals, err := netTotals(run.Payments)
+	if err != nil {
+		return nil, fmt.Errorf("getRun: %w", err)
+	}
+
+	return &PayrollRunResult{PayrollRun: run, Totals: totals}, nil
+}
+
+/*
+Regenerates the payments of a draft run from the current compensations, approved hours and pending items,
+fails with errmgr.ErrPayrollRunLocked once the run is approved. A first approval is withdrawn, as the totals may change.
+*/
+func (d *Domain) recalculateRun(ctx context.Context, runID uint) (*PayrollRunResult, error) {
+	db, err := d.params.DB.GetScopedDB(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("recalculateRun: %w: %v", errmgr.ErrTenant, err)
+	}
+
+	run, err := findRun(db, runID, false)
+	if err != nil {
+		return nil, fmt.Errorf("recalculateRun: %w", err)
+	}
+	hours, err := d.approvedHours(ctx, *run)
+	if err != nil {
+		return nil, fmt.Errorf("recalculateRun: %w", err)
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		run, err := findRun(tx, runID, true)
+		if err != nil {
+			return err
+		}
+		err = requireDraft(run)
+		if err != nil {
+			return err
+		}
+
+		err = clearRun(tx, run.ID)
+		if err != nil {
+			return err
+		}
+		err = calculateRun(tx, run, hours)
+		if err != nil {
+			return err
+		}
+
+		return tx.Model(run).Updates(map[string]interface{}{
+			"calculated_at":        time.Now().UTC(),
+			"first_approved_at":    nil,
+			"first_approved_by_id": nil,
+		}).Error
+	})
+	if err != nil {
+		return nil, fmt.Errorf("recalculateRun: %w", err)
+	}
+
+	return d.getRun(ctx, runID)
+}
+
+/*
+Approves a draft run, which schedules its payments for the pay date and locks the run, see schema.PayrollRun.
+Runs above the company's payroll approval threshold need the approval of two different users, see needsSecondApproval:
+the first approval is only recorded, and the same user approving again fails with errmgr.ErrSecondApprover.
+Approving an approved or paid run again leaves it as it is, so retries are safe.
+*/
+func (d *Domain) approveRun(ctx context.Context, actorID uint, runID uint) (*PayrollRunResult, error) {
+	db, err := d.params.DB.GetScopedDB(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("approveRun: %w: %v", errmgr.ErrTenant, err)
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		run, err := findRun(tx, runID, true)
+		if err != nil {
+			return err
+		}
+		switch run.Status {
+		case schema.PayrollRunStatusApproved, schema.PayrollRunStatusPaid:
+			return nil
+		case schema.PayrollRunStatusVoided:
+			return fmt.Errorf("%w: run %d is %s", errmgr.ErrPayrollRunStatus, run.ID, run.Status)
+		}
+
+		needsSecond, err := requiresSecondApproval(tx, run)
+		if err != nil {
+			return err
+		}
+		now := time.Now().UTC()
+		if needsSecond && run.FirstApprovedByID == nil {
+			return tx.Model(run).Updates(map[string]interface{}{
+				"first_approved_at":    now,
+				"first_approved_by_id": actorID,
+			}).Error
+		}
+		if needsSecond && *run.FirstApprovedByID == actorID {
+			return fmt.Errorf("%w: run %d was first approved by user %d", errmgr.ErrSecondApprover, run.ID, actorID)
+		}
+
+		err = tx.Model(run).Updates(map[string]interface{}{
+			"status":         schema.PayrollRunStatusApproved,
+			"approved_at":    now,
+			"approved_by_id": actorID,
+		}).Error
+		if err != nil {
+			return err
+		}
+
+		return tx.Model(&schema.Payment{}).
+			Where("payroll_run_id = ?", run.ID).
+			Update("status", schema.PaymentStatusScheduled).Error
+	})
+	if err != nil {
+		return nil, fmt.Errorf("approveRun: %w", err)
+	}
+
+	return d.getRun(ctx, runID)
+}
+
+// Marks an approved run and its payments as paid once they went out. Paying a paid run again leaves it as it is.
+func (d *Domain) payRun(ctx context.Context, actorID uint, runID uint) (*PayrollRunResult, error) {
+	db, err := d.params.DB.GetScopedDB(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("payRun: %w: %v", errmgr.ErrTenant, err)
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		run, err := findRun(tx, runID, true)
+		if err != nil {
+			return err
+		}
+		if run.Status == schema.PayrollRunStatusPaid {
+			return nil
+		}
+		if run.Status != schema.PayrollRunStatusApproved {
+			return fmt.Errorf("%w: run %d is %s", errmgr.ErrPayrollRunStatus, run.ID, run.Status)
+		}
+
+		err = tx.Model(run).Updates(map[string]interface{}{
+			"status":     schema.PayrollRunStatusPaid,
+			"paid_at":    time.Now().UTC(),
+			"paid_by_id": actorID,
+		}).Error
+		if err != nil {
+			return err
+		}
+
+		return tx.Model(&schema.Payment{}).
+			Where("payroll_run_id = ? AND status = ?", run.ID, schema.PaymentStatusScheduled).
+			Update("status", schema.PaymentStatusPaid).Error
+	})
+	if err != nil {
+		return nil, fmt.Errorf("payRun: %w", err)
+	}
+
+	return d.getRun(ctx, runID)
+}
+
+/*
+Voids an approved or paid run, which keeps it and its payments as history instead of deleting them.
+Scheduled payments are voided, paid ones are offset by pending adjustments, see reversalAdjustments.
+The bonuses, expenses and adjustments of the run's payments are reissued as pending items, so that the run
+replacing the voided one for its period pays them again. Voiding a voided run again leaves it as it is.
+*/
+func (d *Domain) voidRun(ctx context.Context, actorID uint, runID uint) (*PayrollRunResult, error) {
+	db, err := d.params.DB.GetScopedDB(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("voidRun: %w: %v", errmgr.ErrTenant, err)
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		run, err := findRun(tx, runID, true)
+		if err != nil {
+			return err
+		}
+		if run.Status == schema.PayrollRunStatusVoided {
+			return nil
+		}
+		// drafts were never approved, they are deleted instead
+		if run.Status == schema.PayrollRunStatusDraft {
+			return fmt.Errorf("%w: run %d is %s", errmgr.ErrPayrollRunStatus, run.ID, run.Status)
+		}
+
+		var payments []schema.Payment
+		err = tx.Where("payroll_run_id = ?", run.ID).Find(&payments).Error
+		if err != nil {
+			return err
+		}
+
+		err = tx.Model(run).Updates(map[string]interface{}{
+			"status":       schema.PayrollRunStatusVoided,
+			"voided_at":    time.Now().UTC(),
+			"voided_by_id": actorID,
+		}).Error
+		if err != nil {
+			return err
+		}
+		err = tx.Model(&schema.Payment{}).
+			Where("payroll_run_id = ? AND status = ?", run.ID, schema.PaymentStatusScheduled).
+			Update("status", schema.PaymentStatusVoided).Error
+		if err != nil {
+			return err
+		}
+
+		adjustments := reversalAdjustments(*run, payments)
+		if len(adjustments) > 0 {
+			err = tx.Create(&adjustments).Error
+			if err != nil {
+				return err
+			}
+		}
+
+		return reissueItems(tx, run.ID)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("voidRun: %w", err)
+	}
+
+	return d.getRun(ctx, runID)
+}
+
+// Deletes a draft run and its payments, its items become pending again.
+func (d *Domain) deleteRun(ctx context.Context, runID uint) error {
+	db, err := d.params.DB.GetScopedDB(ctx)
+	if err != nil {
+		return fmt.Errorf("deleteRun: %w: %v", errmgr.ErrTenant, err)
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		run, err := findRun(tx, runID, true)
+		if err != nil {
+			return err
+		}
+		err = requireDraft(run)
+		if err != nil {
+			return err
+		}
+
+		err = clearRun(tx, run.ID)
+		if err != nil {
+			return err
+		}
+
+		return tx.Delete(run).Error
+	})
+	if err != nil {
+		return fmt.Errorf("deleteRun: %w", err)
+	}
+
+	return nil
+}
+
+/*
+Generates the payments of a draft run, see buildPayments, and attaches the pending bonuses, expenses and
+adjustments of the employees to them, see assignItems. A final payment scheduled by offboarding for one of
+the compensations is taken into the run instead of adding another payment, and keeps its payment date.
+*/
+func calculateRun(tx *gorm.DB, run *schema.PayrollRun, hours []timekeeping.ApprovedHours) error {
+	start, end := runPeriod(*run)
+
+	var compensations []schema.Compensation
+	err := tx.
+		Where("compensations.interval = ? AND started_at < ? AND (ended_at IS NULL OR ended_at > ?)", run.Interval, end, start).
+		Order("user_id, started_at").
+		Find(&compensations).Error
+	if err != nil {
+		return fmt.Errorf("calculateRun: %w", err)
+	}
+
+	payments, err := buildPayments(*run, compensations, hours)
+	if err != nil {
+		return fmt.Errorf("calculateRun: %w", err)
+	}
+	if len(payments) == 0 {
+		return nil
+	}
+
+	compensationIDs := make([]uint, 0, len(payments))
+	userIDs := make([]uint, 0, len(payments))
+	for _, payment := range payments {
+		compensationIDs = append(compensationIDs, payment.CompensationID)
+		userIDs = append(userIDs, payment.UserID)
+	}
+
+	var finals []schema.Payment
+	err = tx.
+		Where("final AND payroll_run_id IS NULL AND status = ?", schema.PaymentStatusScheduled).
+		Where("compensation_id IN ?", compensationIDs).
+		Find(&finals).Error
+	if err != nil {
+		return fmt.Errorf("calculateRun: %w", err)
+	}
+	finalByCompensation := map[uint]schema.Payment{}
+	for _, final := range finals {
+		finalByCompensation[final.CompensationID] = final
+	}
+
+	paymentIDs := make([]uint, 0, len(payments))
+	for i := range payments {
+		if final, ok := finalByCompensation[payments[i].CompensationID]; ok {
+			payments[i].Model = final.Model
+			payments[i].CompanyID = final.CompanyID
+			payments[i].PaidAt = final.PaidAt
+			payments[i].Final = true
+		}
+		err = tx.Omit(clause.Associations).Save(&payments[i]).Error
+		if err != nil {
+			return fmt.Errorf("calculateRun: %w", err)
+		}
+		paymentIDs = append(paymentIDs, payments[i].ID)
+	}
+
+	for _, kind := range itemKinds {
+		var items []PayrollItem
+		err = itemTable(tx, kind).
+			Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("payment_id IS NULL AND user_id IN ?", userIDs).
+			Find(&items).Error
+		if err != nil {
+			return fmt.Errorf("calculateRun: %w", err)
+		}
+
+		for i, assigned := range assignItems(payments, items) {
+			itemIDs := make([]uint, 0, len(assigned))
+			for _, item := range assigned {
+				itemIDs = append(itemIDs, item.ID)
+			}
+			err = itemTable(tx, kind).Where("id IN ?", itemIDs).Update("payment_id", payments[i].ID).Error
+			if err != nil {
+				return fmt.Errorf("calculateRun: %w", err)
+			}
+		}
+	}
+
+	err = refreshNetAmounts(tx, paymentIDs)
+	if err != nil {
+		return fmt.Errorf("calculateRun: %w", err)
+	}
+
+	return nil
+}
+
+/*
+Removes the payments of a run before it is calculated again: their items become pending, final payments
+taken into the run are scheduled on their own again, and the other payments are deleted.
+*/
+func clearRun(tx *gorm.DB, runID uint) error {
+	var paymentIDs []uint
+	err := tx.Model(&schema.Payment{}).Where("payroll_run_id = ?", runID).Pluck("id", &paymentIDs).Error
+	if err != nil {
+		return fmt.Errorf("clearRun: %w", err)
+	}
+	if len(paymentIDs) == 0 {
+		return nil
+	}
+
+	for _, kind := range itemKinds {
+		err = itemTable(tx, kind).Where("payment_id IN ?", paymentIDs).Update("payment_id", nil).Error
+		if err != nil {
+			return fmt.Errorf("clearRun: %w", err)
+		}
+	}
+
+	err = tx.Model(&schema.Payment{}).
+		Where("payroll_run_id = ? AND final", runID).
+		Updates(map[string]interface{}{
+			"payroll_run_id": nil,
+			"status":         schema.PaymentStatusScheduled,
+			"period_start":   nil,
+			"period_end":     nil,
+			"hours":          nil,
+			"base_amount":    0,
+			"net_amount":     0,
+		}).Error
+	if err != nil {
+		return fmt.Errorf("clearRun: %w", err)
+	}
+
+	// drafts were never paid, nothing refers to them once their items are detached
+	err = tx.Unscoped().Where("payroll_run_id = ? AND NOT final", runID).Delete(&schema.Payment{}).Error
+	if err != nil {
+		return fmt.Errorf("clearRun: %w", err)
+	}
+
+	return nil
+}
+
+// Sets the net amount of the payments to their base amount plus their bonuses, expenses and adjustments.
+func refreshNetAmounts(tx *gorm.DB, paymentIDs []uint) error {
+	if len(paymentIDs) == 0 {
+		return nil
+	}
+
+	sums := make([]string, 0, len(itemKinds))
+	for _, kind := range itemKinds {
+		sums = append(sums, fmt.Sprintf("COALESCE((SELECT SUM(amount) FROM %[1]s WHERE %[1]s.payment_id = payments.id AND %[1]s.deleted_at IS NULL), 0)", itemTables[kind]))
+	}
+
+	err := tx.Model(&schema.Payment{}).
+		Where("id IN ?", paymentIDs).
+		Update("net_amount", gorm.Expr("base_amount + "+strings.Join(sums, " + "))).Error
+	if err != nil {
+		return fmt.Errorf("refreshNetAmounts: %w", err)
+	}
+	return nil
+}
+
+// Copies the bonuses, expenses and adjustments of the run's payments into new pending items.
+func reissueItems(tx *gorm.DB, runID uint) error {
+	for _, kind := range itemKinds {
+		var items []PayrollItem
+		err := itemTable(tx, kind).
+			Where("payment_id IN (?)", tx.Model(&schema.Payment{}).Select("id").Where("payroll_run_id = ?", runID)).
+			Order("id").
+			Find(&items).Error
+		if err != nil {
+			return fmt.Errorf("reissueItems: %w", err)
+		}
+		if len(items) == 0 {
+			continue
+		}
+
+		copies := make([]PayrollItem, 0, len(items))
+		for _, item := range items {
+			copies = append(copies, PayrollItem{
+				UserID:      item.UserID,
+				Amount:      item.Amount,
+				Currency:    item.Currency,
+				Description: item.Description,
+			})
+		}
+		err = itemTable(tx, kind).Create(&copies).Error
+		if err != nil {
+			return fmt.Errorf("reissueItems: %w", err)
+		}
+	}
+	return nil
+}
+
+// Reports whether the run needs a second approver under the company's payroll approval threshold, see needsSecondApproval.
+func requiresSecondApproval(tx *gorm.DB, run *schema.PayrollRun) (bool, error) {
+	var company schema.Company
+	err := tx.Select("id", "payroll_approval_threshold", "payroll_approval_currency").Where("id = ?", run.CompanyID).First(&company).Error
+	if err != nil {
+		return false, fmt.Errorf("requiresSecondApproval: %w", err)
+	}
+	if company.PayrollApprovalThreshold == nil {
+		return false, nil
+	}
+
+	var payments []schema.Payment
+	err = tx.Where("payroll_run_id = ?", run.ID).Find(&payments).Error
+	if err != nil {
+		return false, fmt.Errorf("requiresSecondApproval: %w", err)
+	}
+	totals, err := netTotals(payments)
+	if err != nil {
+		return false, fmt.Errorf("requiresSecondApproval: %w", err)
+	}
+
+	exchangeRates, err := money.LoadRates(tx)
+	if err != nil {
+		return false, fmt.Errorf("requiresSecondApproval: %w", err)
+	}
+
+	needsSecond, err := needsSecondApproval(totals, company.PayrollApprovalThreshold, exchangeRates)
+	if err != nil {
+		return false, fmt.Errorf("requiresSecondApproval: %w", err)
+	}
+	return needsSecond, nil
+}
+
+// Returns the approved hours an hourly run pays, see coveredHours. Runs of other intervals pay no hours.
+func (d *Domain) approvedHours(ctx context.Context, run schema.PayrollRun) ([]timekeeping.ApprovedHours, error) {
+	if run.Interval != schema.CompensationIntervalHourly {
+		return nil, nil
+	}
+
+	// timesheets ending in the period may start before it, no timesheet is longer than a year
+	start, end := runPeriod(run)
+	hours, err := d.params.Timekeeping.ApprovedHours(ctx, start.AddDate(-1, 0, 0), end)
+	if err != nil {
+		return nil, fmt.Errorf("approvedHours: %w", err)
+	}
+	return hours, nil
+}
+
+// ! Items ---------------------------------------------------------------
+
+// Returns the bonuses, expenses or adjustments of the employee, most recent first. The employee is looked up within the scopes.
+func (d *Domain) listItems(ctx context.Context, kind string, userID uint, scopes ...func(*gorm.DB) *gorm.DB) ([]PayrollItem, error) {
+	db, err := d.params.DB.GetScopedDB(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("listItems: %w: %v", errmgr.ErrTenant, err)
+	}
+
+	_, err = lookup.FindEmployee(db, userID, false, scopes...)
+	if err != nil {
+		return nil, fmt.Errorf("listItems: %w", err)
+	}
+
+	items := []PayrollItem{}
+	err = itemTable(db, kind).Where("user_id = ?", userID).Order("created_at DESC, id DESC").Find(&items).Error
+	if err != nil {
+		return nil, fmt.Errorf("listItems: %w", err)
+	}
+
+	return items, nil
+}
+
+// Adds a pending bonus, expense or adjustment, paid by the next run paying the employee in its currency.
+func (d *Domain) createItem(ctx context.Context, kind string, userID uint, item PayrollItem, scopes ...func(*gorm.DB) *gorm.DB) (*PayrollItem, error) {
+	db, err := d.params.DB.GetScopedDB(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("createItem: %w: %v", errmgr.ErrTenant, err)
+	}
+
+	item.UserID = userID
+	err = db.Transaction(func(tx *gorm.DB) error {
+		_, err := lookup.FindEmployee(tx, userID, true, scopes...)
+		if err != nil {
+			return err
+		}
+		return itemTable(tx, kind).Create(&item).Error
+	})
+	if err != nil {
+		return nil, fmt.Errorf("createItem: %w", err)
+	}
+
+	return &item, nil
+}
+
+/*
+Replaces the amount, currency and description of an item that is pending or part of a draft run.
+The net amount of its payment is updated, an item changing currency becomes pending again.
+*/
+func (d *Domain) updateItem(ctx context.Context, kind string, userID uint, itemID uint, update PayrollItem, scopes ...func(*gorm.DB) *gorm.DB) (*PayrollItem, error) {
+	db, err := d.params.DB.GetScopedDB(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("updateItem: %w: %v", errmgr.ErrTenant, err)
+	}
+
+	var item *PayrollItem
+	err = db.Transaction(func(tx *gorm.DB) error {
+		_, err := lookup.FindEmployee(tx, userID, true, scopes...)
+		if err != nil {
+			return err
+		}
+		item, err = findEditableItem(tx, kind, userID, itemID)
+		if err != nil {
+			return err
+		}
+
+		paymentID := item.PaymentID
+		values := map[string]interface{}{
+			"amount":      update.Amount,
+			"currency":    update.Currency,
+			"description": update.Description,
+		}
+		if item.PaymentID != nil && update.Currency != item.Currency {
+			values["payment_id"] = nil
+			item.PaymentID = nil
+		}
+		err = itemTable(tx, kind).Where("id = ?", item.ID).Updates(values).Error
+		if err != nil {
+			return err
+		}
+		item.Amount, item.Currency, item.Description = update.Amount, update.Currency, update.Description
+
+		if paymentID != nil {
+			return refreshNetAmounts(tx, []uint{*paymentID})
+		}
+		return nil
+	})
+	if err != nil {
+		// the lock triggers of the tables back findEditableItem up, see the 0013 migration
+		if pgconn.IsCheckViolation(err, payrollLockConstraint) {
+			return nil, fmt.Errorf("updateItem: %w", errmgr.ErrPayrollItemLocked)
+		}
+		return nil, fmt.Errorf("updateItem: %w", err)
+	}
+
+	return item, nil
+}
+
+// Deletes an item that is pending or part of a draft run, updating the net amount of its payment.
+func (d *Domain) deleteItem(ctx context.Context, kind string, userID uint, itemID uint, scopes ...func(*gorm.DB) *gorm.DB) error {
+	db, err := d.params.DB.GetScopedDB(ctx)
+	if err != nil {
+		return fmt.Errorf("deleteItem: %w: %v", errmgr.ErrTenant, err)
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		_, err := lookup.FindEmployee(tx, userID, true, scopes...)
+		if err != nil {
+			return err
+		}
+		item, err := findEditableItem(tx, kind, userID, itemID)
+		if err != nil {
+			return err
+		}
+
+		err = itemTable(tx, kind).Where("id = ?", item.ID).Delete(&PayrollItem{}).Error
+		if err != nil {
+			return err
+		}
+
+		if item.PaymentID != nil {
+			return refreshNetAmounts(tx, []uint{*item.PaymentID})
+		}
+		return nil
+	})
+	if err != nil {
+		// the lock triggers of the tables back findEditableItem up, see the 0013 migration
+		if pgconn.IsCheckViolation(err, payrollLockConstraint) {
+			return fmt.Errorf("deleteItem: %w", errmgr.ErrPayrollItemLocked)
+		}
+		return fmt.Errorf("deleteItem: %w", err)
+	}
+
+	return nil
+}
+
+// ! Helpers ---------------------------------------------------------------
+
+// Locks the company of the context, serializing changes to its payroll runs.
+func lockCompany(ctx context.Context, tx *gorm.DB) error {
+	companyID, ok := pgconn.CompanyIDFromContext(ctx)
+	if !ok {
+		return fmt.Errorf("lockCompany: %w", errmgr.ErrTenant)
+	}
+
+	var company schema.Company
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").Where("id = ?", companyID).First(&company).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return fmt.Errorf("lockCompany: %w", errmgr.ErrTenant)
+		}
+		return fmt.Errorf("lockCompany: %w", err)
+	}
+	return nil
+}
+
+// Returns the run, failing with errmgr.ErrPayrollRunNotFound otherwise. If lock is set the row is locked.
+func findRun(db *gorm.DB, runID uint, lock bool) (*schema.PayrollRun, error) {
+	query := db.Where("id = ?", runID)
+	if lock {
+		query = query.Clauses(clause.Locking{Strength: "UPDATE"})
+	}
+
+	var run schema.PayrollRun
+	err := query.First(&run).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("findRun: %w", errmgr.ErrPayrollRunNotFound)
+		}
+		return nil, fmt.Errorf("findRun: %w", err)
+	}
+	return &run, nil
+}
+
+// Fails with errmgr.ErrPayrollRunLocked unless the run is a draft.
+func requireDraft(run *schema.PayrollRun) error {
+	if run.Status != schema.PayrollRunStatusDraft {
+		return fmt.Errorf("requireDraft: %w: run %d is %s", errmgr.ErrPayrollRunLocked, run.ID, run.Status)
+	}
+	return nil
+}
+
+/*
+Returns the item of the employee, locked, failing with errmgr.ErrPayrollItemNotFound otherwise.
+Items attached to a payment can only change while the payment's run is a draft, the run is locked
+so that it is not recalculated or approved meanwhile. Otherwise fails with errmgr.ErrPayrollItemLocked.
+As the change alters the run's totals, a first approval of the run is withdrawn.
+*/
+func findEditableItem(tx *gorm.DB, kind string, userID uint, itemID uint) (*PayrollItem, error) {
+	var item PayrollItem
+	err := itemTable(tx, kind).
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("id = ? AND user_id = ?", itemID, userID).
+		First(&item).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("findEditableItem: %w", errmgr.ErrPayrollItemNotFound)
+		}
+		return nil, fmt.Errorf("findEditableItem: %w", err)
+	}
+	if item.PaymentID == nil {
+		return &item, nil
+	}
+
+	var run schema.PayrollRun
+	err = tx.
+		Clauses(clause.Locking{Strength: "UPDATE", Table: clause.Table{Name: "payroll_runs"}}).
+		Joins("JOIN payments ON payments.payroll_run_id = payroll_runs.id").
+		Where("payments.id = ?", *item.PaymentID).
+		First(&run).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("findEditableItem: %w", err)
+	}
+	if run.ID == 0 || run.Status != schema.PayrollRunStatusDraft {
+		return nil, fmt.Errorf("findEditableItem: %w", errmgr.ErrPayrollItemLocked)
+	}
+	if run.FirstApprovedByID != nil {
+		err = tx.Model(&run).Updates(map[string]interface{}{
+			"first_approved_at":    nil,
+			"first_approved_by_id": nil,
+		}).Error
+		if err != nil {
+			return nil, fmt.Errorf("findEditableItem: %w", err)
+		}
+	}
+	return &item, nil
+}
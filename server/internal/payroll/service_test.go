@@ -0,0 +1,23 @@
+package payroll
+
+import (
+	"testing"
+
+	"github.com/alsey89/people-matter/pkg/pgconn/pgtest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRefreshNetAmounts(t *testing.T) {
+	db := pgtest.NewDryRunDB(t)
+	statements := pgtest.Capture(t, db)
+
+	require.NoError(t, refreshNetAmounts(db, []uint{4, 5}))
+
+	sql := statements.Last()
+	assert.Contains(t, sql, `UPDATE "payments" SET "net_amount"=base_amount + COALESCE((SELECT SUM(amount) FROM bonus WHERE bonus.payment_id = payments.id AND bonus.deleted_at IS NULL), 0)`)
+	assert.Contains(t, sql, "FROM expenses WHERE expenses.payment_id = payments.id")
+	assert.Contains(t, sql, "FROM adjustments WHERE adjustments.payment_id = payments.id")
+	assert.Contains(t, sql, "WHERE id IN (4,5)")
+}
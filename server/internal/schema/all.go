@@ -3,16 +3,25 @@ package schema
 import (
 	"time"
 
+	"github.com/alsey89/people-matter/internal/common/money"
+
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // ======================
 //  COMPANY & USER
 // ======================
 
+// Salary band policies, whether an out of band compensation is rejected or accepted with a warning
+const (
+	SalaryBandPolicyWarning = "warning"
+	SalaryBandPolicyError   = "error"
+)
+
 type Company struct {
 	gorm.Model
-	TenantID string `json:"-" gorm:"not null;index"`
+	TenantID string `json:"-" gorm:"not null;uniqueIndex"`
 
 	Name    string `json:"name"    gorm:"type:varchar(255);not null"`
 	LogoURL string `json:"logoUrl" gorm:"type:text"`
@@ -30,6 +39,12 @@ type Company struct {
 	LocationQuota int `json:"branchQuota"  gorm:"default:1"`
 	EmployeeQuota int `json:"employeeQuota" gorm:"default:10"`
 
+	// How compensations outside the salary band of the employee's position are treated, see SalaryBandPolicy constants
+	SalaryBandPolicy string `json:"salaryBandPolicy" gorm:"type:varchar(20);not null;default:'warning'"`
+	// Payroll runs with a total above the threshold, in its currency, need a second approver. Nil if none do.
+	PayrollApprovalThreshold *money.Money `json:"payrollApprovalThreshold" gorm:"currency:PayrollApprovalCurrency"`
+	PayrollApprovalCurrency  *string      `json:"payrollApprovalCurrency"  gorm:"type:varchar(3)"`
+
 	// Associations
 	Users     []User     `json:"users"     gorm:"foreignKey:CompanyID"`
 	Documents []Document `json:"documents" gorm:"polymorphic:Documentable;"`
@@ -47,9 +62,47 @@ type User struct {
 	// Could be null if a user doesn’t have an active compensation
 	ActiveCompensationID *uint `json:"-" gorm:"index;default:null"`
 
+	// JWTs issued up to this time are rejected, set when the user is offboarded
+	SessionsRevokedAt *time.Time `json:"-" gorm:"default:null"`
+
 	Documents []Document `json:"documents" gorm:"polymorphic:Documentable;"`
 }
 
+// PasswordReset records each issued password reset token so that it can be used only once.
+// TokenID is stored in the token as the "jti" claim.
+type PasswordReset struct {
+	gorm.Model
+	CompanyID uint       `json:"companyId" gorm:"not null;index"`
+	UserID    uint       `json:"userId"    gorm:"not null;index"`
+	TokenID   string     `json:"-"         gorm:"type:varchar(36);not null;uniqueIndex"`
+	UsedAt    *time.Time `json:"usedAt"    gorm:"default:null"`
+}
+
+// Invitation statuses
+const (
+	InvitationStatusPending  = "pending"
+	InvitationStatusAccepted = "accepted"
+	InvitationStatusRevoked  = "revoked"
+)
+
+// Invitation to join a company, sent to a pending user that sets their password on acceptance.
+// TokenID is stored in the invite token as the "jti" claim and rotated on resend, so only the latest link works.
+type Invitation struct {
+	gorm.Model
+	CompanyID   uint       `json:"companyId"   gorm:"not null;index"`
+	UserID      uint       `json:"userId"      gorm:"not null;index;uniqueIndex:idx_invitations_pending_user,where:status = 'pending' AND deleted_at IS NULL"`
+	InvitedByID uint       `json:"invitedById" gorm:"not null"`
+	Email       string     `json:"email"       gorm:"type:varchar(255);not null"`
+	TokenID     string     `json:"-"           gorm:"type:varchar(36);not null;uniqueIndex"`
+	Status      string     `json:"status"      gorm:"type:varchar(20);not null;default:'pending'"`
+	ExpiresAt   time.Time  `json:"expiresAt"   gorm:"not null"`
+	SentAt      time.Time  `json:"sentAt"      gorm:"not null"`
+	AcceptedAt  *time.Time `json:"acceptedAt"  gorm:"default:null"`
+	RevokedAt   *time.Time `json:"revokedAt"   gorm:"default:null"`
+
+	User User `json:"-" gorm:"foreignKey:UserID"`
+}
+
 // ======================
 //  LOCATION
 // ======================
@@ -79,15 +132,20 @@ type Location struct {
 
 type Position struct {
 	gorm.Model
-	CompanyID        uint   `json:"companyId" gorm:"not null;index"`
-	Name             string `json:"name"`
-	Description      string `json:"description"`
-	Qualifications   string `json:"qualifications"`
-	Responsibilities string `json:"responsibilities"`
+	CompanyID        uint    `json:"companyId" gorm:"not null;index;uniqueIndex:idx_positions_company_system_key,where:deleted_at IS NULL"`
+	SystemKey        *string `json:"systemKey" gorm:"type:varchar(50);uniqueIndex:idx_positions_company_system_key,where:deleted_at IS NULL"` // Set for seeded positions, e.g. "owner"
+	Name             string  `json:"name"`
+	Description      string  `json:"description"`
+	Qualifications   string  `json:"qualifications"`
+	Responsibilities string  `json:"responsibilities"`
+
+	SalaryMin      *money.Money `json:"salaryMin"      gorm:"currency:SalaryCurrency"`
+	SalaryMax      *money.Money `json:"salaryMax"      gorm:"currency:SalaryCurrency"`
+	SalaryCurrency *string      `json:"salaryCurrency"`
+	SalaryInterval *string      `json:"salaryInterval" gorm:"type:varchar(20)"` // one of the CompensationInterval constants, annually if not set
 
-	SalaryMin      *float64 `json:"salaryMin"`
-	SalaryMax      *float64 `json:"salaryMax"`
-	SalaryCurrency *string  `json:"salaryCurrency"`
+	// Grants the permissions at every location instead of only the location of the assignment, e.g. for company admins
+	CompanyWide bool `json:"companyWide" gorm:"not null;default:false"`
 
 	Permissions []Permission `gorm:"many2many:position_permissions;"`
 
@@ -96,8 +154,8 @@ type Position struct {
 
 type Permission struct {
 	gorm.Model
-	CompanyID   uint   `json:"companyId" gorm:"not null;index"`
-	Name        string `json:"name"`
+	CompanyID   uint   `json:"companyId" gorm:"not null;index;uniqueIndex:idx_permissions_company_name,where:deleted_at IS NULL"`
+	Name        string `json:"name"      gorm:"uniqueIndex:idx_permissions_company_name,where:deleted_at IS NULL"`
 	Description string `json:"description"`
 
 	Documents []Document `json:"documents" gorm:"polymorphic:Documentable;"`
@@ -106,8 +164,8 @@ type Permission struct {
 type PositionPermission struct {
 	gorm.Model
 	CompanyID    uint `json:"companyId" gorm:"not null;index"`
-	PositionID   uint `json:"positionId"   gorm:"not null;index"`
-	PermissionID uint `json:"permissionId" gorm:"not null;index"`
+	PositionID   uint `json:"positionId"   gorm:"not null;index;uniqueIndex:idx_position_permissions_pair,where:deleted_at IS NULL"`
+	PermissionID uint `json:"permissionId" gorm:"not null;index;uniqueIndex:idx_position_permissions_pair,where:deleted_at IS NULL"`
 
 	Documents []Document `json:"documents" gorm:"polymorphic:Documentable;"`
 }
@@ -130,18 +188,56 @@ type UserPosition struct {
 	Documents []Document `json:"documents" gorm:"polymorphic:Documentable;"`
 }
 
+/*
+Returns the condition matching the user positions of table, e.g. "up", that have not ended at the time,
+including those that only start later. Positions can be given an end date ahead of time, so ended_at IS NULL
+alone misses positions that are still held.
+e.g. db.Where("EXISTS (SELECT 1 FROM user_positions up WHERE up.user_id = users.id AND ?)", schema.OpenUserPositions("up", now))
+*/
+func OpenUserPositions(table string, at time.Time) clause.Expr {
+	return gorm.Expr("("+table+".ended_at IS NULL OR "+table+".ended_at > ?)", at)
+}
+
+// Like OpenUserPositions, for the user positions held at the time: started and not ended.
+func ActiveUserPositions(table string, at time.Time) clause.Expr {
+	return gorm.Expr("("+table+".started_at <= ? AND ?)", at, OpenUserPositions(table, at))
+}
+
 // ======================
 //  COMPENSATION & PAYROLL
 // ======================
 
+// Amounts are money.Money, stored as integer minor units next to a currency column named by the "currency" tag.
+
+// Exchange rate of a company used to compare amounts in different currencies, 1 BaseCurrency = Rate QuoteCurrency.
+// Also used in the inverse direction if only that pair is defined.
+type ExchangeRate struct {
+	gorm.Model
+	CompanyID     uint    `json:"companyId"     gorm:"not null;index;uniqueIndex:idx_exchange_rates_company_pair,where:deleted_at IS NULL"`
+	BaseCurrency  string  `json:"baseCurrency"  gorm:"type:varchar(3);not null;uniqueIndex:idx_exchange_rates_company_pair,where:deleted_at IS NULL"`
+	QuoteCurrency string  `json:"quoteCurrency" gorm:"type:varchar(3);not null;uniqueIndex:idx_exchange_rates_company_pair,where:deleted_at IS NULL"`
+	Rate          float64 `json:"rate"          gorm:"not null"`
+}
+
+// Compensation intervals, the period the amount is paid for
+const (
+	CompensationIntervalHourly   = "hourly"
+	CompensationIntervalWeekly   = "weekly"
+	CompensationIntervalBiWeekly = "bi-weekly"
+	CompensationIntervalMonthly  = "monthly"
+	CompensationIntervalAnnually = "annually"
+)
+
+// Compensations of a user are consecutive periods [StartedAt, EndedAt), a new one ends the previous one.
+// User.ActiveCompensationID points at the one covering the current time.
 type Compensation struct {
 	gorm.Model
 	CompanyID uint `json:"companyId" gorm:"not null;index"`
 	UserID    uint `json:"userId"    gorm:"not null;index"`
 
-	Amount   float64 `json:"amount"   gorm:"not null"`
-	Currency string  `json:"currency" gorm:"not null"`
-	Interval string  `json:"interval" gorm:"not null"` // e.g., "monthly", "bi-weekly"
+	Amount   money.Money `json:"amount"   gorm:"not null;currency:Currency"`
+	Currency string      `json:"currency" gorm:"not null"`
+	Interval string      `json:"interval" gorm:"not null"` // one of the CompensationInterval constants
 
 	ChannelType string  `json:"channelType"    gorm:"not null"`
 	ChannelCode *string `json:"channelId"` // e.g. bank code
@@ -157,13 +253,71 @@ type Compensation struct {
 	Documents []Document `json:"documents" gorm:"polymorphic:Documentable;"`
 }
 
+// Payroll run statuses
+const (
+	PayrollRunStatusDraft    = "draft"
+	PayrollRunStatusApproved = "approved"
+	PayrollRunStatusPaid     = "paid"
+	PayrollRunStatusVoided   = "voided"
+)
+
+/*
+A payroll run pays the compensations of one interval for one pay period, e.g. all monthly compensations for March.
+Drafts can be recalculated, which regenerates their payments. Approving schedules the payments, from then on the run,
+its payments and their bonuses, expenses and adjustments cannot change. Voiding reverses the run with offsetting
+adjustments, and frees its period for another run.
+*/
+type PayrollRun struct {
+	gorm.Model
+	CompanyID    uint      `json:"companyId"    gorm:"not null;index;uniqueIndex:idx_payroll_runs_company_period,where:deleted_at IS NULL AND status <> 'voided'"`
+	Interval     string    `json:"interval"     gorm:"type:varchar(20);not null;uniqueIndex:idx_payroll_runs_company_period,where:deleted_at IS NULL AND status <> 'voided'"`
+	PeriodStart  time.Time `json:"periodStart"  gorm:"type:date;not null;uniqueIndex:idx_payroll_runs_company_period,where:deleted_at IS NULL AND status <> 'voided'"`
+	PeriodEnd    time.Time `json:"periodEnd"    gorm:"type:date;not null"` // Inclusive
+	PayDate      time.Time `json:"payDate"      gorm:"type:date;not null"`
+	Status       string    `json:"status"       gorm:"type:varchar(20);not null;default:'draft'"`
+	CreatedByID  uint      `json:"createdById"  gorm:"not null"`
+	CalculatedAt time.Time `json:"calculatedAt" gorm:"not null"`
+
+	// First of two approvals, for runs above the company's payroll approval threshold
+	FirstApprovedAt   *time.Time `json:"firstApprovedAt"   gorm:"default:null"`
+	FirstApprovedByID *uint      `json:"firstApprovedById" gorm:"default:null"`
+	ApprovedAt        *time.Time `json:"approvedAt"        gorm:"default:null"`
+	ApprovedByID      *uint      `json:"approvedById"      gorm:"default:null"`
+	PaidAt            *time.Time `json:"paidAt"            gorm:"default:null"`
+	PaidByID          *uint      `json:"paidById"          gorm:"default:null"`
+	VoidedAt          *time.Time `json:"voidedAt"          gorm:"default:null"`
+	VoidedByID        *uint      `json:"voidedById"        gorm:"default:null"`
+
+	// Associations
+	Payments []Payment `json:"payments,omitempty" gorm:"foreignKey:PayrollRunID"`
+}
+
+// Payment statuses
+const (
+	PaymentStatusDraft     = "draft" // part of a draft payroll run
+	PaymentStatusScheduled = "scheduled"
+	PaymentStatusPaid      = "paid"
+	PaymentStatusVoided    = "voided" // scheduled by a payroll run that was voided before paying it
+)
+
 type Payment struct {
 	gorm.Model
 	CompanyID      uint      `json:"companyId"      gorm:"not null;index"`
 	UserID         uint      `json:"userId"         gorm:"not null;index"`
 	CompensationID uint      `json:"compensationId" gorm:"not null;index"`
+	PayrollRunID   *uint     `json:"payrollRunId"   gorm:"index;default:null"`
 	Currency       string    `json:"currency"       gorm:"not null"`
-	PaidAt         time.Time `json:"paidAt"         gorm:"not null"`
+	PaidAt         time.Time `json:"paidAt"         gorm:"not null"` // planned payment date while scheduled
+	Status         string    `json:"status"         gorm:"type:varchar(20);not null;default:'paid'"`
+	Final          bool      `json:"final"          gorm:"not null;default:false"` // final payment of an offboarded employee
+
+	// The part [PeriodStart, PeriodEnd) of the payroll run's period the compensation covers
+	PeriodStart *time.Time `json:"periodStart" gorm:"default:null"`
+	PeriodEnd   *time.Time `json:"periodEnd"   gorm:"default:null"`
+	Hours       *float64   `json:"hours"       gorm:"default:null"` // approved hours paid, for hourly compensations
+
+	BaseAmount money.Money `json:"baseAmount" gorm:"not null;default:0;currency:Currency"` // prorated compensation
+	NetAmount  money.Money `json:"netAmount"  gorm:"not null;default:0;currency:Currency"` // base plus bonuses, expenses and adjustments
 
 	// Associations
 	Compensation Compensation `gorm:"foreignKey:CompensationID"` //base compensation
@@ -174,32 +328,42 @@ type Payment struct {
 	Documents []Document `json:"documents" gorm:"polymorphic:Documentable;"`
 }
 
+// Bonuses, adjustments and expenses are pending until a payroll run attaches them to a payment of the user.
+
 type Bonus struct {
 	gorm.Model
-	CompanyID   uint    `json:"companyId" gorm:"not null;index"`
-	PaymentID   uint    `json:"paymentId" gorm:"not null;index"`
-	Amount      float64 `json:"amount"    gorm:"not null"`
-	Description string  `json:"description"`
+	CompanyID   uint        `json:"companyId" gorm:"not null;index"`
+	UserID      uint        `json:"userId"    gorm:"not null;index"`
+	PaymentID   *uint       `json:"paymentId" gorm:"index;default:null"`
+	Amount      money.Money `json:"amount"    gorm:"not null;currency:Currency"`
+	Currency    string      `json:"currency"  gorm:"not null"`
+	Description string      `json:"description"`
 
 	Documents []Document `json:"documents" gorm:"polymorphic:Documentable;"`
 }
 
 type Adjustment struct {
 	gorm.Model
-	CompanyID   uint    `json:"companyId" gorm:"not null;index"`
-	PaymentID   uint    `json:"paymentId" gorm:"not null;index"`
-	Amount      float64 `json:"amount"    gorm:"not null"`
-	Description string  `json:"description"`
+	CompanyID   uint        `json:"companyId" gorm:"not null;index"`
+	UserID      uint        `json:"userId"    gorm:"not null;index"`
+	PaymentID   *uint       `json:"paymentId" gorm:"index;default:null"`
+	Amount      money.Money `json:"amount"    gorm:"not null;currency:Currency"`
+	Currency    string      `json:"currency"  gorm:"not null"`
+	Description string      `json:"description"`
+	// The paid payment of a voided payroll run this adjustment offsets
+	ReversedPaymentID *uint `json:"reversedPaymentId" gorm:"index;default:null"`
 
 	Documents []Document `json:"documents" gorm:"polymorphic:Documentable;"`
 }
 
 type Expense struct {
 	gorm.Model
-	CompanyID   uint    `json:"companyId" gorm:"not null;index"`
-	PaymentID   uint    `json:"paymentId" gorm:"not null;index"`
-	Amount      float64 `json:"amount"    gorm:"not null"`
-	Description string  `json:"description"`
+	CompanyID   uint        `json:"companyId" gorm:"not null;index"`
+	UserID      uint        `json:"userId"    gorm:"not null;index"`
+	PaymentID   *uint       `json:"paymentId" gorm:"index;default:null"`
+	Amount      money.Money `json:"amount"    gorm:"not null;currency:Currency"`
+	Currency    string      `json:"currency"  gorm:"not null"`
+	Description string      `json:"description"`
 
 	Documents []Document `json:"documents" gorm:"polymorphic:Documentable;"`
 }
@@ -228,13 +392,15 @@ type Document struct {
 type TimeLog struct {
 	gorm.Model
 	CompanyID  uint       `json:"companyId" gorm:"not null;index"`
-	UserID     uint       `json:"userId"    gorm:"not null;index"`
-	LocationID *uint      `json:"locationId" gorm:"index"` // Optional if you track location
-	PositionID *uint      `json:"positionId" gorm:"index"` // Added to associate with a position, if needed
+	UserID     uint       `json:"userId"    gorm:"not null;index;uniqueIndex:idx_time_logs_open_per_user,where:clock_out IS NULL AND deleted_at IS NULL"` // At most one open log per user
+	LocationID *uint      `json:"locationId" gorm:"index"`                                                                                                // Optional if you track location
+	PositionID *uint      `json:"positionId" gorm:"index"`                                                                                                // Added to associate with a position, if needed
 	ClockIn    time.Time  `json:"clockIn"   gorm:"not null"`
 	ClockOut   *time.Time `json:"clockOut"` // Nil if not yet clocked out
 	Notes      *string    `json:"notes"`
 
+	TimesheetID *uint `json:"timesheetId" gorm:"index;default:null"` // Set once the log is attached to a timesheet
+
 	// Associations
 	User     User      `gorm:"foreignKey:UserID"`
 	Position *Position `gorm:"foreignKey:PositionID"`
@@ -243,6 +409,78 @@ type TimeLog struct {
 	Documents []Document `json:"documents" gorm:"polymorphic:Documentable;"`
 }
 
+// Timesheet statuses
+const (
+	TimesheetStatusDraft     = "draft"
+	TimesheetStatusSubmitted = "submitted"
+	TimesheetStatusApproved  = "approved"
+	TimesheetStatusRejected  = "rejected"
+)
+
+// Timesheet groups the time logs of a user for a pay period for manager review.
+// Logs of submitted and approved timesheets cannot be edited.
+type Timesheet struct {
+	gorm.Model
+	CompanyID   uint      `json:"companyId"   gorm:"not null;index"`
+	UserID      uint      `json:"userId"      gorm:"not null;index;uniqueIndex:idx_timesheets_user_period,where:deleted_at IS NULL"`
+	PeriodStart time.Time `json:"periodStart" gorm:"type:date;not null;uniqueIndex:idx_timesheets_user_period,where:deleted_at IS NULL"`
+	PeriodEnd   time.Time `json:"periodEnd"   gorm:"type:date;not null"` // Inclusive
+
+	Status      string     `json:"status"      gorm:"type:varchar(20);not null;default:draft;index"` // e.g., "draft", "approved"
+	SubmittedAt *time.Time `json:"submittedAt" gorm:"default:null"`
+
+	ReviewerID    *uint      `json:"reviewerId"    gorm:"index;default:null"`
+	ReviewedAt    *time.Time `json:"reviewedAt"    gorm:"default:null"`
+	ReviewComment *string    `json:"reviewComment"`
+
+	// Snapshot of the rules engine breakdown taken on approval, ApprovedHours is regular + overtime
+	ApprovedHours         float64 `json:"approvedHours"         gorm:"not null;default:0"`
+	ApprovedRegularHours  float64 `json:"approvedRegularHours"  gorm:"not null;default:0"`
+	ApprovedOvertimeHours float64 `json:"approvedOvertimeHours" gorm:"not null;default:0"`
+
+	// Associations
+	User     User             `json:"-"        gorm:"foreignKey:UserID"`
+	TimeLogs []TimeLog        `json:"timeLogs" gorm:"foreignKey:TimesheetID"`
+	Events   []TimesheetEvent `json:"events"   gorm:"foreignKey:TimesheetID"`
+}
+
+// TimesheetEvent is the audit trail of timesheet status changes.
+type TimesheetEvent struct {
+	gorm.Model
+	CompanyID   uint    `json:"companyId"   gorm:"not null;index"`
+	TimesheetID uint    `json:"timesheetId" gorm:"not null;index"`
+	ActorID     uint    `json:"actorId"     gorm:"not null;index"`
+	FromStatus  string  `json:"fromStatus"  gorm:"type:varchar(20);not null"`
+	ToStatus    string  `json:"toStatus"    gorm:"type:varchar(20);not null"`
+	Comment     *string `json:"comment"` // Manager comment or reason for reopening
+
+	// Associations
+	Actor User `json:"-" gorm:"foreignKey:ActorID"`
+}
+
+// TimeRule configures how time logs are turned into paid regular and overtime hours.
+// A rule applies to logs at its location and/or position, the most specific rule wins.
+// A rule with neither location nor position is the company default.
+type TimeRule struct {
+	gorm.Model
+	CompanyID  uint  `json:"companyId"  gorm:"not null;index"`
+	LocationID *uint `json:"locationId" gorm:"index;default:null"`
+	PositionID *uint `json:"positionId" gorm:"index;default:null"`
+
+	Name string `json:"name" gorm:"type:varchar(255);not null"`
+
+	DailyOvertimeAfterMinutes  *int `json:"dailyOvertimeAfterMinutes"`  // Nil disables daily overtime
+	WeeklyOvertimeAfterMinutes *int `json:"weeklyOvertimeAfterMinutes"` // Nil disables weekly overtime
+
+	BreakAfterMinutes int `json:"breakAfterMinutes" gorm:"not null;default:0"` // Shift length from which the break is deducted
+	BreakMinutes      int `json:"breakMinutes"      gorm:"not null;default:0"` // Unpaid break, 0 disables
+	RoundingMinutes   int `json:"roundingMinutes"   gorm:"not null;default:0"` // Clock in/out rounded to the nearest increment, 0 disables
+
+	// Associations
+	Location *Location `json:"-" gorm:"foreignKey:LocationID"`
+	Position *Position `json:"-" gorm:"foreignKey:PositionID"`
+}
+
 // ======================
 // EMBEDDED NON-TABLE STRUCTS
 // ======================
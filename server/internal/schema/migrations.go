@@ -0,0 +1,11 @@
+package schema
+
+import "embed"
+
+// Versioned SQL migrations, loaded with pgconn.LoadMigrations(Migrations, MigrationsDir).
+// Every change to the models in this package needs a new migration.
+//
+//go:embed migrations/*.sql
+var Migrations embed.FS
+
+const MigrationsDir = "migrations"
@@ -0,0 +1,16 @@
+-- Drops every table of the baseline schema, in reverse dependency order.
+
+DROP TABLE IF EXISTS "password_resets";
+DROP TABLE IF EXISTS "expenses";
+DROP TABLE IF EXISTS "adjustments";
+DROP TABLE IF EXISTS "bonus";
+DROP TABLE IF EXISTS "payments";
+DROP TABLE IF EXISTS "compensations";
+DROP TABLE IF EXISTS "user_positions";
+DROP TABLE IF EXISTS "position_permissions";
+DROP TABLE IF EXISTS "permissions";
+DROP TABLE IF EXISTS "positions";
+DROP TABLE IF EXISTS "locations";
+DROP TABLE IF EXISTS "documents";
+DROP TABLE IF EXISTS "users";
+DROP TABLE IF EXISTS "companies";
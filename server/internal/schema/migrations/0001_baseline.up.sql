@@ -0,0 +1,251 @@
+-- Baseline schema, equivalent to what AutoMigrate created before versioned migrations.
+-- IF NOT EXISTS lets databases previously created by AutoMigrate adopt the baseline.
+
+CREATE TABLE IF NOT EXISTS "companies" (
+    "id" bigserial,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    "deleted_at" timestamptz,
+    "tenant_id" text NOT NULL,
+    "name" varchar(255) NOT NULL,
+    "logo_url" text,
+    "email" varchar(255) NOT NULL,
+    "phone" varchar(255) NOT NULL,
+    "website" varchar(255) NOT NULL,
+    "contact_street" text NOT NULL,
+    "contact_city" varchar(255) NOT NULL,
+    "contact_country" varchar(255) NOT NULL,
+    "contact_postal_code" varchar(255) NOT NULL,
+    "billing_street" text NOT NULL,
+    "billing_city" varchar(255) NOT NULL,
+    "billing_country" varchar(255) NOT NULL,
+    "billing_postal_code" varchar(255) NOT NULL,
+    "location_quota" bigint DEFAULT 1,
+    "employee_quota" bigint DEFAULT 10,
+    PRIMARY KEY ("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_companies_tenant_id" ON "companies" ("tenant_id");
+CREATE INDEX IF NOT EXISTS "idx_companies_deleted_at" ON "companies" ("deleted_at");
+
+CREATE TABLE IF NOT EXISTS "users" (
+    "id" bigserial,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    "deleted_at" timestamptz,
+    "company_id" bigint NOT NULL,
+    "name" text,
+    "email" text NOT NULL,
+    "password_hash" text,
+    "email_verified" boolean DEFAULT false,
+    "active_compensation_id" bigint DEFAULT null,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_companies_users" FOREIGN KEY ("company_id") REFERENCES "companies"("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_users_active_compensation_id" ON "users" ("active_compensation_id");
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_company_email" ON "users" ("company_id","email");
+CREATE INDEX IF NOT EXISTS "idx_users_deleted_at" ON "users" ("deleted_at");
+
+CREATE TABLE IF NOT EXISTS "documents" (
+    "id" bigserial,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    "deleted_at" timestamptz,
+    "name" text NOT NULL,
+    "url" text NOT NULL,
+    "description" text,
+    "documentable_id" bigint,
+    "documentable_type" varchar(255),
+    PRIMARY KEY ("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_documents_deleted_at" ON "documents" ("deleted_at");
+
+CREATE TABLE IF NOT EXISTS "locations" (
+    "id" bigserial,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    "deleted_at" timestamptz,
+    "company_id" bigint NOT NULL,
+    "name" varchar(255) NOT NULL,
+    "address_street" text NOT NULL,
+    "address_city" varchar(255) NOT NULL,
+    "address_country" varchar(255) NOT NULL,
+    "address_postal_code" varchar(255) NOT NULL,
+    "email" varchar(255),
+    "phone" varchar(255),
+    "website" varchar(255),
+    PRIMARY KEY ("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_locations_company_id" ON "locations" ("company_id");
+CREATE INDEX IF NOT EXISTS "idx_locations_deleted_at" ON "locations" ("deleted_at");
+
+CREATE TABLE IF NOT EXISTS "positions" (
+    "id" bigserial,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    "deleted_at" timestamptz,
+    "company_id" bigint NOT NULL,
+    "name" text,
+    "description" text,
+    "qualifications" text,
+    "responsibilities" text,
+    "salary_min" decimal,
+    "salary_max" decimal,
+    "salary_currency" text,
+    PRIMARY KEY ("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_positions_company_id" ON "positions" ("company_id");
+CREATE INDEX IF NOT EXISTS "idx_positions_deleted_at" ON "positions" ("deleted_at");
+
+CREATE TABLE IF NOT EXISTS "permissions" (
+    "id" bigserial,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    "deleted_at" timestamptz,
+    "company_id" bigint NOT NULL,
+    "name" text,
+    "description" text,
+    PRIMARY KEY ("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_permissions_deleted_at" ON "permissions" ("deleted_at");
+CREATE INDEX IF NOT EXISTS "idx_permissions_company_id" ON "permissions" ("company_id");
+
+CREATE TABLE IF NOT EXISTS "position_permissions" (
+    "id" bigserial,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    "deleted_at" timestamptz,
+    "company_id" bigint NOT NULL,
+    "position_id" bigint NOT NULL,
+    "permission_id" bigint NOT NULL,
+    PRIMARY KEY ("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_position_permissions_permission_id" ON "position_permissions" ("permission_id");
+CREATE INDEX IF NOT EXISTS "idx_position_permissions_position_id" ON "position_permissions" ("position_id");
+CREATE INDEX IF NOT EXISTS "idx_position_permissions_company_id" ON "position_permissions" ("company_id");
+CREATE INDEX IF NOT EXISTS "idx_position_permissions_deleted_at" ON "position_permissions" ("deleted_at");
+
+CREATE TABLE IF NOT EXISTS "user_positions" (
+    "id" bigserial,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    "deleted_at" timestamptz,
+    "company_id" bigint NOT NULL,
+    "user_id" bigint NOT NULL,
+    "position_id" bigint NOT NULL,
+    "location_id" bigint NOT NULL,
+    "started_at" timestamptz NOT NULL,
+    "ended_at" timestamptz DEFAULT null,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_locations_user_positions" FOREIGN KEY ("location_id") REFERENCES "locations"("id"),
+    CONSTRAINT "fk_user_positions_user" FOREIGN KEY ("user_id") REFERENCES "users"("id"),
+    CONSTRAINT "fk_user_positions_position" FOREIGN KEY ("position_id") REFERENCES "positions"("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_user_positions_deleted_at" ON "user_positions" ("deleted_at");
+CREATE INDEX IF NOT EXISTS "idx_user_positions_location_id" ON "user_positions" ("location_id");
+CREATE INDEX IF NOT EXISTS "idx_user_positions_position_id" ON "user_positions" ("position_id");
+CREATE INDEX IF NOT EXISTS "idx_user_positions_user_id" ON "user_positions" ("user_id");
+CREATE INDEX IF NOT EXISTS "idx_user_positions_company_id" ON "user_positions" ("company_id");
+
+CREATE TABLE IF NOT EXISTS "compensations" (
+    "id" bigserial,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    "deleted_at" timestamptz,
+    "company_id" bigint NOT NULL,
+    "user_id" bigint NOT NULL,
+    "amount" decimal NOT NULL,
+    "currency" text NOT NULL,
+    "interval" text NOT NULL,
+    "channel_type" text NOT NULL,
+    "channel_code" text,
+    "account" text NOT NULL,
+    "started_at" timestamptz NOT NULL,
+    "ended_at" timestamptz DEFAULT null,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_compensations_user" FOREIGN KEY ("user_id") REFERENCES "users"("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_compensations_user_id" ON "compensations" ("user_id");
+CREATE INDEX IF NOT EXISTS "idx_compensations_company_id" ON "compensations" ("company_id");
+CREATE INDEX IF NOT EXISTS "idx_compensations_deleted_at" ON "compensations" ("deleted_at");
+
+CREATE TABLE IF NOT EXISTS "payments" (
+    "id" bigserial,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    "deleted_at" timestamptz,
+    "company_id" bigint NOT NULL,
+    "user_id" bigint NOT NULL,
+    "compensation_id" bigint NOT NULL,
+    "currency" text NOT NULL,
+    "paid_at" timestamptz NOT NULL,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_compensations_payments" FOREIGN KEY ("compensation_id") REFERENCES "compensations"("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_payments_user_id" ON "payments" ("user_id");
+CREATE INDEX IF NOT EXISTS "idx_payments_company_id" ON "payments" ("company_id");
+CREATE INDEX IF NOT EXISTS "idx_payments_deleted_at" ON "payments" ("deleted_at");
+CREATE INDEX IF NOT EXISTS "idx_payments_compensation_id" ON "payments" ("compensation_id");
+
+CREATE TABLE IF NOT EXISTS "bonus" (
+    "id" bigserial,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    "deleted_at" timestamptz,
+    "company_id" bigint NOT NULL,
+    "payment_id" bigint NOT NULL,
+    "amount" decimal NOT NULL,
+    "description" text,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_payments_bonuses" FOREIGN KEY ("payment_id") REFERENCES "payments"("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_bonus_company_id" ON "bonus" ("company_id");
+CREATE INDEX IF NOT EXISTS "idx_bonus_deleted_at" ON "bonus" ("deleted_at");
+CREATE INDEX IF NOT EXISTS "idx_bonus_payment_id" ON "bonus" ("payment_id");
+
+CREATE TABLE IF NOT EXISTS "adjustments" (
+    "id" bigserial,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    "deleted_at" timestamptz,
+    "company_id" bigint NOT NULL,
+    "payment_id" bigint NOT NULL,
+    "amount" decimal NOT NULL,
+    "description" text,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_payments_adjustments" FOREIGN KEY ("payment_id") REFERENCES "payments"("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_adjustments_payment_id" ON "adjustments" ("payment_id");
+CREATE INDEX IF NOT EXISTS "idx_adjustments_company_id" ON "adjustments" ("company_id");
+CREATE INDEX IF NOT EXISTS "idx_adjustments_deleted_at" ON "adjustments" ("deleted_at");
+
+CREATE TABLE IF NOT EXISTS "expenses" (
+    "id" bigserial,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    "deleted_at" timestamptz,
+    "company_id" bigint NOT NULL,
+    "payment_id" bigint NOT NULL,
+    "amount" decimal NOT NULL,
+    "description" text,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_payments_expenses" FOREIGN KEY ("payment_id") REFERENCES "payments"("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_expenses_payment_id" ON "expenses" ("payment_id");
+CREATE INDEX IF NOT EXISTS "idx_expenses_company_id" ON "expenses" ("company_id");
+CREATE INDEX IF NOT EXISTS "idx_expenses_deleted_at" ON "expenses" ("deleted_at");
+
+CREATE TABLE IF NOT EXISTS "password_resets" (
+    "id" bigserial,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    "deleted_at" timestamptz,
+    "company_id" bigint NOT NULL,
+    "user_id" bigint NOT NULL,
+    "token_id" varchar(36) NOT NULL,
+    "used_at" timestamptz DEFAULT null,
+    PRIMARY KEY ("id")
+);
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_password_resets_token_id" ON "password_resets" ("token_id");
+CREATE INDEX IF NOT EXISTS "idx_password_resets_user_id" ON "password_resets" ("user_id");
+CREATE INDEX IF NOT EXISTS "idx_password_resets_company_id" ON "password_resets" ("company_id");
+CREATE INDEX IF NOT EXISTS "idx_password_resets_deleted_at" ON "password_resets" ("deleted_at");
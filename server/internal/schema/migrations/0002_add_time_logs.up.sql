@@ -0,0 +1,25 @@
+-- Time logs for clock-in/clock-out, with at most one open log per user.
+
+CREATE TABLE "time_logs" (
+    "id" bigserial,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    "deleted_at" timestamptz,
+    "company_id" bigint NOT NULL,
+    "user_id" bigint NOT NULL,
+    "location_id" bigint,
+    "position_id" bigint,
+    "clock_in" timestamptz NOT NULL,
+    "clock_out" timestamptz,
+    "notes" text,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_time_logs_user" FOREIGN KEY ("user_id") REFERENCES "users"("id"),
+    CONSTRAINT "fk_time_logs_position" FOREIGN KEY ("position_id") REFERENCES "positions"("id"),
+    CONSTRAINT "fk_time_logs_location" FOREIGN KEY ("location_id") REFERENCES "locations"("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_time_logs_position_id" ON "time_logs" ("position_id");
+CREATE INDEX IF NOT EXISTS "idx_time_logs_location_id" ON "time_logs" ("location_id");
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_time_logs_open_per_user" ON "time_logs" ("user_id") WHERE clock_out IS NULL AND deleted_at IS NULL;
+CREATE INDEX IF NOT EXISTS "idx_time_logs_user_id" ON "time_logs" ("user_id");
+CREATE INDEX IF NOT EXISTS "idx_time_logs_company_id" ON "time_logs" ("company_id");
+CREATE INDEX IF NOT EXISTS "idx_time_logs_deleted_at" ON "time_logs" ("deleted_at");
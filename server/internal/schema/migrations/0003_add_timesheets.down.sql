@@ -0,0 +1,5 @@
+ALTER TABLE "time_logs" DROP CONSTRAINT IF EXISTS "fk_timesheets_time_logs";
+DROP INDEX IF EXISTS "idx_time_logs_timesheet_id";
+ALTER TABLE "time_logs" DROP COLUMN IF EXISTS "timesheet_id";
+DROP TABLE IF EXISTS "timesheet_events";
+DROP TABLE IF EXISTS "timesheets";
@@ -0,0 +1,50 @@
+-- Timesheets grouping time logs per user and pay period, with an audit trail of status changes.
+
+CREATE TABLE "timesheets" (
+    "id" bigserial,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    "deleted_at" timestamptz,
+    "company_id" bigint NOT NULL,
+    "user_id" bigint NOT NULL,
+    "period_start" date NOT NULL,
+    "period_end" date NOT NULL,
+    "status" varchar(20) NOT NULL DEFAULT 'draft',
+    "submitted_at" timestamptz DEFAULT null,
+    "reviewer_id" bigint DEFAULT null,
+    "reviewed_at" timestamptz DEFAULT null,
+    "review_comment" text,
+    "approved_hours" decimal NOT NULL DEFAULT 0,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_timesheets_user" FOREIGN KEY ("user_id") REFERENCES "users"("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_timesheets_reviewer_id" ON "timesheets" ("reviewer_id");
+CREATE INDEX IF NOT EXISTS "idx_timesheets_status" ON "timesheets" ("status");
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_timesheets_user_period" ON "timesheets" ("user_id","period_start") WHERE deleted_at IS NULL;
+CREATE INDEX IF NOT EXISTS "idx_timesheets_user_id" ON "timesheets" ("user_id");
+CREATE INDEX IF NOT EXISTS "idx_timesheets_company_id" ON "timesheets" ("company_id");
+CREATE INDEX IF NOT EXISTS "idx_timesheets_deleted_at" ON "timesheets" ("deleted_at");
+
+CREATE TABLE "timesheet_events" (
+    "id" bigserial,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    "deleted_at" timestamptz,
+    "company_id" bigint NOT NULL,
+    "timesheet_id" bigint NOT NULL,
+    "actor_id" bigint NOT NULL,
+    "from_status" varchar(20) NOT NULL,
+    "to_status" varchar(20) NOT NULL,
+    "comment" text,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_timesheet_events_actor" FOREIGN KEY ("actor_id") REFERENCES "users"("id"),
+    CONSTRAINT "fk_timesheets_events" FOREIGN KEY ("timesheet_id") REFERENCES "timesheets"("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_timesheet_events_actor_id" ON "timesheet_events" ("actor_id");
+CREATE INDEX IF NOT EXISTS "idx_timesheet_events_timesheet_id" ON "timesheet_events" ("timesheet_id");
+CREATE INDEX IF NOT EXISTS "idx_timesheet_events_company_id" ON "timesheet_events" ("company_id");
+CREATE INDEX IF NOT EXISTS "idx_timesheet_events_deleted_at" ON "timesheet_events" ("deleted_at");
+
+ALTER TABLE "time_logs" ADD COLUMN IF NOT EXISTS "timesheet_id" bigint DEFAULT null;
+CREATE INDEX IF NOT EXISTS "idx_time_logs_timesheet_id" ON "time_logs" ("timesheet_id");
+ALTER TABLE "time_logs" ADD CONSTRAINT "fk_timesheets_time_logs" FOREIGN KEY ("timesheet_id") REFERENCES "timesheets"("id");
@@ -0,0 +1,3 @@
+ALTER TABLE "timesheets" DROP COLUMN IF EXISTS "approved_overtime_hours";
+ALTER TABLE "timesheets" DROP COLUMN IF EXISTS "approved_regular_hours";
+DROP TABLE IF EXISTS "time_rules";
@@ -0,0 +1,27 @@
+-- Overtime, break and rounding rules per location or position, and the approved hours breakdown of timesheets.
+
+CREATE TABLE "time_rules" (
+    "id" bigserial,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    "deleted_at" timestamptz,
+    "company_id" bigint NOT NULL,
+    "location_id" bigint DEFAULT null,
+    "position_id" bigint DEFAULT null,
+    "name" varchar(255) NOT NULL,
+    "daily_overtime_after_minutes" bigint,
+    "weekly_overtime_after_minutes" bigint,
+    "break_after_minutes" bigint NOT NULL DEFAULT 0,
+    "break_minutes" bigint NOT NULL DEFAULT 0,
+    "rounding_minutes" bigint NOT NULL DEFAULT 0,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_time_rules_location" FOREIGN KEY ("location_id") REFERENCES "locations"("id"),
+    CONSTRAINT "fk_time_rules_position" FOREIGN KEY ("position_id") REFERENCES "positions"("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_time_rules_position_id" ON "time_rules" ("position_id");
+CREATE INDEX IF NOT EXISTS "idx_time_rules_location_id" ON "time_rules" ("location_id");
+CREATE INDEX IF NOT EXISTS "idx_time_rules_company_id" ON "time_rules" ("company_id");
+CREATE INDEX IF NOT EXISTS "idx_time_rules_deleted_at" ON "time_rules" ("deleted_at");
+
+ALTER TABLE "timesheets" ADD COLUMN IF NOT EXISTS "approved_regular_hours" decimal NOT NULL DEFAULT 0;
+ALTER TABLE "timesheets" ADD COLUMN IF NOT EXISTS "approved_overtime_hours" decimal NOT NULL DEFAULT 0;
@@ -0,0 +1,3 @@
+-- Positions whose permissions apply at every location, e.g. company admins.
+
+ALTER TABLE "positions" ADD COLUMN IF NOT EXISTS "company_wide" boolean NOT NULL DEFAULT false;
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS "idx_position_permissions_pair";
+DROP INDEX IF EXISTS "idx_permissions_company_name";
+DROP INDEX IF EXISTS "idx_positions_company_system_key";
+ALTER TABLE "positions" DROP COLUMN IF EXISTS "system_key";
@@ -0,0 +1,6 @@
+-- Keys that make seeding the permission catalog and system positions idempotent.
+
+ALTER TABLE "positions" ADD COLUMN IF NOT EXISTS "system_key" varchar(50);
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_positions_company_system_key" ON "positions" ("company_id","system_key") WHERE deleted_at IS NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_permissions_company_name" ON "permissions" ("company_id","name") WHERE deleted_at IS NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_position_permissions_pair" ON "position_permissions" ("position_id","permission_id") WHERE deleted_at IS NULL;
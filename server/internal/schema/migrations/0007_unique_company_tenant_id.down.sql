@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS "idx_companies_tenant_id";
+CREATE INDEX IF NOT EXISTS "idx_companies_tenant_id" ON "companies" ("tenant_id");
@@ -0,0 +1,4 @@
+-- Tenant IDs are used as subdomains and must be unique, including soft deleted companies.
+
+DROP INDEX IF EXISTS "idx_companies_tenant_id";
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_companies_tenant_id" ON "companies" ("tenant_id");
@@ -0,0 +1,25 @@
+-- Invitations of pending users, accepted by setting a password.
+
+CREATE TABLE "invitations" (
+    "id" bigserial,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    "deleted_at" timestamptz,
+    "company_id" bigint NOT NULL,
+    "user_id" bigint NOT NULL,
+    "invited_by_id" bigint NOT NULL,
+    "email" varchar(255) NOT NULL,
+    "token_id" varchar(36) NOT NULL,
+    "status" varchar(20) NOT NULL DEFAULT 'pending',
+    "expires_at" timestamptz NOT NULL,
+    "sent_at" timestamptz NOT NULL,
+    "accepted_at" timestamptz DEFAULT null,
+    "revoked_at" timestamptz DEFAULT null,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_invitations_user" FOREIGN KEY ("user_id") REFERENCES "users"("id")
+);
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_invitations_token_id" ON "invitations" ("token_id");
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_invitations_pending_user" ON "invitations" ("user_id") WHERE status = 'pending' AND deleted_at IS NULL;
+CREATE INDEX IF NOT EXISTS "idx_invitations_user_id" ON "invitations" ("user_id");
+CREATE INDEX IF NOT EXISTS "idx_invitations_company_id" ON "invitations" ("company_id");
+CREATE INDEX IF NOT EXISTS "idx_invitations_deleted_at" ON "invitations" ("deleted_at");
@@ -0,0 +1,3 @@
+ALTER TABLE "payments" DROP COLUMN IF EXISTS "final";
+ALTER TABLE "payments" DROP COLUMN IF EXISTS "status";
+ALTER TABLE "users" DROP COLUMN IF EXISTS "sessions_revoked_at";
@@ -0,0 +1,5 @@
+-- Session revocation of offboarded users and scheduled final payments.
+
+ALTER TABLE "users" ADD COLUMN IF NOT EXISTS "sessions_revoked_at" timestamptz DEFAULT null;
+ALTER TABLE "payments" ADD COLUMN IF NOT EXISTS "status" varchar(20) NOT NULL DEFAULT 'paid';
+ALTER TABLE "payments" ADD COLUMN IF NOT EXISTS "final" boolean NOT NULL DEFAULT false;
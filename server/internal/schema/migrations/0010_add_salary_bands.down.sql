@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS "exchange_rates";
+ALTER TABLE "positions" DROP COLUMN IF EXISTS "salary_interval";
+ALTER TABLE "companies" DROP COLUMN IF EXISTS "salary_band_policy";
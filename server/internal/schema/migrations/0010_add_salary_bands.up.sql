@@ -0,0 +1,18 @@
+-- Salary band policy, position salary interval and exchange rates for salary band checks.
+
+CREATE TABLE "exchange_rates" (
+    "id" bigserial,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    "deleted_at" timestamptz,
+    "company_id" bigint NOT NULL,
+    "base_currency" varchar(3) NOT NULL,
+    "quote_currency" varchar(3) NOT NULL,
+    "rate" decimal NOT NULL,
+    PRIMARY KEY ("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_exchange_rates_company_id" ON "exchange_rates" ("company_id");
+CREATE INDEX IF NOT EXISTS "idx_exchange_rates_deleted_at" ON "exchange_rates" ("deleted_at");
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_exchange_rates_company_pair" ON "exchange_rates" ("company_id","base_currency","quote_currency") WHERE deleted_at IS NULL;
+ALTER TABLE "companies" ADD COLUMN IF NOT EXISTS "salary_band_policy" varchar(20) NOT NULL DEFAULT 'warning';
+ALTER TABLE "positions" ADD COLUMN IF NOT EXISTS "salary_interval" varchar(20);
@@ -0,0 +1,20 @@
+SET LOCAL row_security = off;
+
+CREATE FUNCTION pg_temp.currency_exponent(code text) RETURNS integer AS $$
+    SELECT CASE
+        WHEN code IN ('BIF','CLP','DJF','GNF','ISK','JPY','KMF','KRW','PYG','RWF','UGX','UYI','VND','VUV','XAF','XOF','XPF') THEN 0
+        WHEN code IN ('BHD','IQD','JOD','KWD','LYD','OMR','TND') THEN 3
+        WHEN code IN ('CLF','UYW') THEN 4
+        ELSE 2
+    END
+$$ LANGUAGE sql IMMUTABLE;
+
+ALTER TABLE "expenses" ALTER COLUMN "amount" TYPE decimal USING amount / 10::numeric ^ pg_temp.currency_exponent(currency);
+ALTER TABLE "expenses" DROP COLUMN IF EXISTS "currency";
+ALTER TABLE "adjustments" ALTER COLUMN "amount" TYPE decimal USING amount / 10::numeric ^ pg_temp.currency_exponent(currency);
+ALTER TABLE "adjustments" DROP COLUMN IF EXISTS "currency";
+ALTER TABLE "bonus" ALTER COLUMN "amount" TYPE decimal USING amount / 10::numeric ^ pg_temp.currency_exponent(currency);
+ALTER TABLE "bonus" DROP COLUMN IF EXISTS "currency";
+ALTER TABLE "positions" ALTER COLUMN "salary_max" TYPE decimal USING salary_max / 10::numeric ^ pg_temp.currency_exponent(salary_currency);
+ALTER TABLE "positions" ALTER COLUMN "salary_min" TYPE decimal USING salary_min / 10::numeric ^ pg_temp.currency_exponent(salary_currency);
+ALTER TABLE "compensations" ALTER COLUMN "amount" TYPE decimal USING amount / 10::numeric ^ pg_temp.currency_exponent(currency);
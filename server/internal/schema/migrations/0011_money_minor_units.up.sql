@@ -0,0 +1,48 @@
+-- Amounts as integer minor units of their currency instead of decimals, e.g. 10.50 USD becomes 1050.
+-- Bonuses, adjustments and expenses get the currency of their payment.
+-- Aborts instead of rounding if an amount has more decimals than its currency allows.
+
+-- row level security would silently hide rows from the checks and updates below, fail instead
+SET LOCAL row_security = off;
+
+CREATE FUNCTION pg_temp.currency_exponent(code text) RETURNS integer AS $$
+    SELECT CASE
+        WHEN code IN ('BIF','CLP','DJF','GNF','ISK','JPY','KMF','KRW','PYG','RWF','UGX','UYI','VND','VUV','XAF','XOF','XPF') THEN 0
+        WHEN code IN ('BHD','IQD','JOD','KWD','LYD','OMR','TND') THEN 3
+        WHEN code IN ('CLF','UYW') THEN 4
+        ELSE 2
+    END
+$$ LANGUAGE sql IMMUTABLE;
+
+DO $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM compensations WHERE amount * 10::numeric ^ pg_temp.currency_exponent(currency) <> trunc(amount * 10::numeric ^ pg_temp.currency_exponent(currency)))
+        OR EXISTS (SELECT 1 FROM positions WHERE salary_min * 10::numeric ^ pg_temp.currency_exponent(salary_currency) <> trunc(salary_min * 10::numeric ^ pg_temp.currency_exponent(salary_currency)))
+        OR EXISTS (SELECT 1 FROM positions WHERE salary_max * 10::numeric ^ pg_temp.currency_exponent(salary_currency) <> trunc(salary_max * 10::numeric ^ pg_temp.currency_exponent(salary_currency)))
+        OR EXISTS (SELECT 1 FROM bonus b JOIN payments p ON p.id = b.payment_id WHERE b.amount * 10::numeric ^ pg_temp.currency_exponent(p.currency) <> trunc(b.amount * 10::numeric ^ pg_temp.currency_exponent(p.currency)))
+        OR EXISTS (SELECT 1 FROM adjustments a JOIN payments p ON p.id = a.payment_id WHERE a.amount * 10::numeric ^ pg_temp.currency_exponent(p.currency) <> trunc(a.amount * 10::numeric ^ pg_temp.currency_exponent(p.currency)))
+        OR EXISTS (SELECT 1 FROM expenses e JOIN payments p ON p.id = e.payment_id WHERE e.amount * 10::numeric ^ pg_temp.currency_exponent(p.currency) <> trunc(e.amount * 10::numeric ^ pg_temp.currency_exponent(p.currency)))
+    THEN
+        RAISE EXCEPTION 'amounts with more decimals than their currency allows, fix them before migrating';
+    END IF;
+END
+$$;
+
+ALTER TABLE "compensations" ALTER COLUMN "amount" TYPE bigint USING (amount * 10::numeric ^ pg_temp.currency_exponent(currency))::bigint;
+ALTER TABLE "positions" ALTER COLUMN "salary_min" TYPE bigint USING (salary_min * 10::numeric ^ pg_temp.currency_exponent(salary_currency))::bigint;
+ALTER TABLE "positions" ALTER COLUMN "salary_max" TYPE bigint USING (salary_max * 10::numeric ^ pg_temp.currency_exponent(salary_currency))::bigint;
+
+ALTER TABLE "bonus" ADD COLUMN IF NOT EXISTS "currency" text;
+UPDATE "bonus" SET "currency" = p.currency FROM payments p WHERE p.id = "bonus".payment_id;
+ALTER TABLE "bonus" ALTER COLUMN "currency" SET NOT NULL;
+ALTER TABLE "bonus" ALTER COLUMN "amount" TYPE bigint USING (amount * 10::numeric ^ pg_temp.currency_exponent(currency))::bigint;
+
+ALTER TABLE "adjustments" ADD COLUMN IF NOT EXISTS "currency" text;
+UPDATE "adjustments" SET "currency" = p.currency FROM payments p WHERE p.id = "adjustments".payment_id;
+ALTER TABLE "adjustments" ALTER COLUMN "currency" SET NOT NULL;
+ALTER TABLE "adjustments" ALTER COLUMN "amount" TYPE bigint USING (amount * 10::numeric ^ pg_temp.currency_exponent(currency))::bigint;
+
+ALTER TABLE "expenses" ADD COLUMN IF NOT EXISTS "currency" text;
+UPDATE "expenses" SET "currency" = p.currency FROM payments p WHERE p.id = "expenses".payment_id;
+ALTER TABLE "expenses" ALTER COLUMN "currency" SET NOT NULL;
+ALTER TABLE "expenses" ALTER COLUMN "amount" TYPE bigint USING (amount * 10::numeric ^ pg_temp.currency_exponent(currency))::bigint;
@@ -0,0 +1,25 @@
+SET LOCAL row_security = off;
+
+-- Pending items have no payment to go back to
+DELETE FROM "expenses" WHERE "payment_id" IS NULL;
+ALTER TABLE "expenses" ALTER COLUMN "payment_id" SET NOT NULL;
+ALTER TABLE "expenses" DROP COLUMN IF EXISTS "user_id";
+
+DELETE FROM "adjustments" WHERE "payment_id" IS NULL;
+ALTER TABLE "adjustments" ALTER COLUMN "payment_id" SET NOT NULL;
+ALTER TABLE "adjustments" DROP COLUMN IF EXISTS "user_id";
+
+DELETE FROM "bonus" WHERE "payment_id" IS NULL;
+ALTER TABLE "bonus" ALTER COLUMN "payment_id" SET NOT NULL;
+ALTER TABLE "bonus" DROP COLUMN IF EXISTS "user_id";
+
+UPDATE "payments" SET "status" = 'scheduled' WHERE "status" = 'draft';
+ALTER TABLE "payments" DROP CONSTRAINT IF EXISTS "fk_payroll_runs_payments";
+ALTER TABLE "payments" DROP COLUMN IF EXISTS "net_amount";
+ALTER TABLE "payments" DROP COLUMN IF EXISTS "base_amount";
+ALTER TABLE "payments" DROP COLUMN IF EXISTS "hours";
+ALTER TABLE "payments" DROP COLUMN IF EXISTS "period_end";
+ALTER TABLE "payments" DROP COLUMN IF EXISTS "period_start";
+ALTER TABLE "payments" DROP COLUMN IF EXISTS "payroll_run_id";
+
+DROP TABLE IF EXISTS "payroll_runs";
@@ -0,0 +1,48 @@
+-- Payroll runs group payments by pay period. Bonuses, adjustments and expenses stay pending until a run attaches them.
+
+CREATE TABLE "payroll_runs" (
+    "id" bigserial,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    "deleted_at" timestamptz,
+    "company_id" bigint NOT NULL,
+    "interval" varchar(20) NOT NULL,
+    "period_start" date NOT NULL,
+    "period_end" date NOT NULL,
+    "pay_date" date NOT NULL,
+    "status" varchar(20) NOT NULL DEFAULT 'draft',
+    "created_by_id" bigint NOT NULL,
+    "calculated_at" timestamptz NOT NULL,
+    "finalized_at" timestamptz DEFAULT null,
+    "finalized_by_id" bigint DEFAULT null,
+    PRIMARY KEY ("id")
+);
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_payroll_runs_company_period" ON "payroll_runs" ("company_id","interval","period_start") WHERE deleted_at IS NULL;
+CREATE INDEX IF NOT EXISTS "idx_payroll_runs_company_id" ON "payroll_runs" ("company_id");
+CREATE INDEX IF NOT EXISTS "idx_payroll_runs_deleted_at" ON "payroll_runs" ("deleted_at");
+ALTER TABLE "payments" ADD COLUMN IF NOT EXISTS "payroll_run_id" bigint DEFAULT null;
+ALTER TABLE "payments" ADD COLUMN IF NOT EXISTS "period_start" timestamptz DEFAULT null;
+ALTER TABLE "payments" ADD COLUMN IF NOT EXISTS "period_end" timestamptz DEFAULT null;
+ALTER TABLE "payments" ADD COLUMN IF NOT EXISTS "hours" decimal DEFAULT null;
+ALTER TABLE "payments" ADD COLUMN IF NOT EXISTS "base_amount" bigint NOT NULL DEFAULT 0;
+ALTER TABLE "payments" ADD COLUMN IF NOT EXISTS "net_amount" bigint NOT NULL DEFAULT 0;
+ALTER TABLE "payments" ADD CONSTRAINT "fk_payroll_runs_payments" FOREIGN KEY ("payroll_run_id") REFERENCES "payroll_runs"("id");
+CREATE INDEX IF NOT EXISTS "idx_payments_payroll_run_id" ON "payments" ("payroll_run_id");
+
+ALTER TABLE "bonus" ADD COLUMN IF NOT EXISTS "user_id" bigint;
+UPDATE "bonus" SET "user_id" = p.user_id FROM payments p WHERE p.id = "bonus".payment_id;
+ALTER TABLE "bonus" ALTER COLUMN "user_id" SET NOT NULL;
+ALTER TABLE "bonus" ALTER COLUMN "payment_id" DROP NOT NULL;
+CREATE INDEX IF NOT EXISTS "idx_bonus_user_id" ON "bonus" ("user_id");
+
+ALTER TABLE "adjustments" ADD COLUMN IF NOT EXISTS "user_id" bigint;
+UPDATE "adjustments" SET "user_id" = p.user_id FROM payments p WHERE p.id = "adjustments".payment_id;
+ALTER TABLE "adjustments" ALTER COLUMN "user_id" SET NOT NULL;
+ALTER TABLE "adjustments" ALTER COLUMN "payment_id" DROP NOT NULL;
+CREATE INDEX IF NOT EXISTS "idx_adjustments_user_id" ON "adjustments" ("user_id");
+
+ALTER TABLE "expenses" ADD COLUMN IF NOT EXISTS "user_id" bigint;
+UPDATE "expenses" SET "user_id" = p.user_id FROM payments p WHERE p.id = "expenses".payment_id;
+ALTER TABLE "expenses" ALTER COLUMN "user_id" SET NOT NULL;
+ALTER TABLE "expenses" ALTER COLUMN "payment_id" DROP NOT NULL;
+CREATE INDEX IF NOT EXISTS "idx_expenses_user_id" ON "expenses" ("user_id");
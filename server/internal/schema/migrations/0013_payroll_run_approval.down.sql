@@ -0,0 +1,34 @@
+SET LOCAL row_security = off;
+
+DROP TRIGGER IF EXISTS payroll_items_lock ON "adjustments";
+DROP TRIGGER IF EXISTS payroll_items_lock ON "expenses";
+DROP TRIGGER IF EXISTS payroll_items_lock ON "bonus";
+DROP TRIGGER IF EXISTS payments_lock ON "payments";
+DROP TRIGGER IF EXISTS payroll_runs_lock ON "payroll_runs";
+DROP FUNCTION IF EXISTS payroll_items_lock();
+DROP FUNCTION IF EXISTS payments_lock();
+DROP FUNCTION IF EXISTS payroll_runs_lock();
+DROP FUNCTION IF EXISTS payroll_lock_violation(text);
+DROP FUNCTION IF EXISTS payroll_payment_locked(bigint);
+DROP FUNCTION IF EXISTS payroll_run_locked(bigint);
+
+DROP INDEX IF EXISTS "idx_adjustments_reversed_payment_id";
+ALTER TABLE "adjustments" DROP COLUMN IF EXISTS "reversed_payment_id";
+
+ALTER TABLE "companies" DROP COLUMN IF EXISTS "payroll_approval_currency";
+ALTER TABLE "companies" DROP COLUMN IF EXISTS "payroll_approval_threshold";
+
+-- Voided runs have no earlier status, they are kept out of the way of the runs replacing them
+UPDATE "payroll_runs" SET "deleted_at" = now() WHERE "status" = 'voided' AND "deleted_at" IS NULL;
+DROP INDEX IF EXISTS "idx_payroll_runs_company_period";
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_payroll_runs_company_period" ON "payroll_runs" ("company_id","interval","period_start") WHERE deleted_at IS NULL;
+
+ALTER TABLE "payroll_runs" DROP COLUMN IF EXISTS "voided_by_id";
+ALTER TABLE "payroll_runs" DROP COLUMN IF EXISTS "voided_at";
+ALTER TABLE "payroll_runs" DROP COLUMN IF EXISTS "paid_by_id";
+ALTER TABLE "payroll_runs" DROP COLUMN IF EXISTS "paid_at";
+ALTER TABLE "payroll_runs" DROP COLUMN IF EXISTS "first_approved_by_id";
+ALTER TABLE "payroll_runs" DROP COLUMN IF EXISTS "first_approved_at";
+UPDATE "payroll_runs" SET "status" = 'finalized' WHERE "status" IN ('approved', 'paid');
+ALTER TABLE "payroll_runs" RENAME COLUMN "approved_by_id" TO "finalized_by_id";
+ALTER TABLE "payroll_runs" RENAME COLUMN "approved_at" TO "finalized_at";
@@ -0,0 +1,105 @@
+-- Payroll runs go from draft through approved to paid, or are voided. Approved runs are immutable: only their status
+-- moves on, and their payments, bonuses, expenses and adjustments cannot change. Voided runs are kept as history.
+
+ALTER TABLE "payroll_runs" RENAME COLUMN "finalized_at" TO "approved_at";
+ALTER TABLE "payroll_runs" RENAME COLUMN "finalized_by_id" TO "approved_by_id";
+UPDATE "payroll_runs" SET "status" = 'approved' WHERE "status" = 'finalized';
+ALTER TABLE "payroll_runs" ADD COLUMN IF NOT EXISTS "first_approved_at" timestamptz DEFAULT null;
+ALTER TABLE "payroll_runs" ADD COLUMN IF NOT EXISTS "first_approved_by_id" bigint DEFAULT null;
+ALTER TABLE "payroll_runs" ADD COLUMN IF NOT EXISTS "paid_at" timestamptz DEFAULT null;
+ALTER TABLE "payroll_runs" ADD COLUMN IF NOT EXISTS "paid_by_id" bigint DEFAULT null;
+ALTER TABLE "payroll_runs" ADD COLUMN IF NOT EXISTS "voided_at" timestamptz DEFAULT null;
+ALTER TABLE "payroll_runs" ADD COLUMN IF NOT EXISTS "voided_by_id" bigint DEFAULT null;
+
+-- A voided run no longer holds its period, the run replacing it does
+DROP INDEX IF EXISTS "idx_payroll_runs_company_period";
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_payroll_runs_company_period" ON "payroll_runs" ("company_id","interval","period_start") WHERE deleted_at IS NULL AND status <> 'voided';
+
+ALTER TABLE "companies" ADD COLUMN IF NOT EXISTS "payroll_approval_threshold" bigint;
+ALTER TABLE "companies" ADD COLUMN IF NOT EXISTS "payroll_approval_currency" varchar(3);
+
+ALTER TABLE "adjustments" ADD COLUMN IF NOT EXISTS "reversed_payment_id" bigint DEFAULT null;
+CREATE INDEX IF NOT EXISTS "idx_adjustments_reversed_payment_id" ON "adjustments" ("reversed_payment_id");
+
+-- Locks, raised as check violations of the payroll_run_locked constraint
+
+CREATE OR REPLACE FUNCTION payroll_run_locked(run_id bigint) RETURNS boolean AS $$
+    SELECT EXISTS (SELECT 1 FROM payroll_runs WHERE id = $1 AND status <> 'draft');
+$$ LANGUAGE sql STABLE;
+
+CREATE OR REPLACE FUNCTION payroll_payment_locked(payment_id bigint) RETURNS boolean AS $$
+    SELECT payroll_run_locked((SELECT payroll_run_id FROM payments WHERE id = $1));
+$$ LANGUAGE sql STABLE;
+
+CREATE OR REPLACE FUNCTION payroll_lock_violation(message text) RETURNS void AS $$
+BEGIN
+    RAISE EXCEPTION '%', message USING ERRCODE = 'check_violation', CONSTRAINT = 'payroll_run_locked';
+END;
+$$ LANGUAGE plpgsql;
+
+-- Approved runs only move on to paid or voided
+CREATE OR REPLACE FUNCTION payroll_runs_lock() RETURNS trigger AS $$
+BEGIN
+    IF OLD.status = 'draft' THEN
+        IF TG_OP = 'DELETE' THEN
+            RETURN OLD;
+        END IF;
+        RETURN NEW;
+    END IF;
+    IF TG_OP = 'DELETE'
+        OR to_jsonb(NEW) - ARRAY['status', 'updated_at', 'paid_at', 'paid_by_id', 'voided_at', 'voided_by_id']
+            <> to_jsonb(OLD) - ARRAY['status', 'updated_at', 'paid_at', 'paid_by_id', 'voided_at', 'voided_by_id'] THEN
+        PERFORM payroll_lock_violation(format('payroll run %s is %s', OLD.id, OLD.status));
+    END IF;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER payroll_runs_lock BEFORE UPDATE OR DELETE ON "payroll_runs"
+    FOR EACH ROW EXECUTE FUNCTION payroll_runs_lock();
+
+-- Payments of approved runs only change their status, and no payment joins an approved run
+CREATE OR REPLACE FUNCTION payments_lock() RETURNS trigger AS $$
+BEGIN
+    IF TG_OP = 'DELETE' THEN
+        IF payroll_run_locked(OLD.payroll_run_id) THEN
+            PERFORM payroll_lock_violation(format('payment %s belongs to an approved payroll run', OLD.id));
+        END IF;
+        RETURN OLD;
+    END IF;
+    IF TG_OP = 'UPDATE' AND payroll_run_locked(OLD.payroll_run_id)
+        AND to_jsonb(NEW) - ARRAY['status', 'updated_at'] <> to_jsonb(OLD) - ARRAY['status', 'updated_at'] THEN
+        PERFORM payroll_lock_violation(format('payment %s belongs to an approved payroll run', OLD.id));
+    END IF;
+    IF (TG_OP = 'INSERT' OR NEW.payroll_run_id IS DISTINCT FROM OLD.payroll_run_id) AND payroll_run_locked(NEW.payroll_run_id) THEN
+        PERFORM payroll_lock_violation(format('payroll run %s is not a draft', NEW.payroll_run_id));
+    END IF;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER payments_lock BEFORE INSERT OR UPDATE OR DELETE ON "payments"
+    FOR EACH ROW EXECUTE FUNCTION payments_lock();
+
+-- Bonuses, expenses and adjustments of payments of approved runs cannot change, nor be added to them
+CREATE OR REPLACE FUNCTION payroll_items_lock() RETURNS trigger AS $$
+BEGIN
+    IF TG_OP IN ('UPDATE', 'DELETE') AND payroll_payment_locked(OLD.payment_id) THEN
+        PERFORM payroll_lock_violation(format('%s %s belongs to an approved payroll run', TG_TABLE_NAME, OLD.id));
+    END IF;
+    IF TG_OP = 'DELETE' THEN
+        RETURN OLD;
+    END IF;
+    IF payroll_payment_locked(NEW.payment_id) THEN
+        PERFORM payroll_lock_violation(format('payment %s belongs to an approved payroll run', NEW.payment_id));
+    END IF;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER payroll_items_lock BEFORE INSERT OR UPDATE OR DELETE ON "bonus"
+    FOR EACH ROW EXECUTE FUNCTION payroll_items_lock();
+CREATE TRIGGER payroll_items_lock BEFORE INSERT OR UPDATE OR DELETE ON "expenses"
+    FOR EACH ROW EXECUTE FUNCTION payroll_items_lock();
+CREATE TRIGGER payroll_items_lock BEFORE INSERT OR UPDATE OR DELETE ON "adjustments"
+    FOR EACH ROW EXECUTE FUNCTION payroll_items_lock();
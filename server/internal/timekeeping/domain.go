@@ -0,0 +1,152 @@
+package timekeeping
+
+import (
+	"context"
+
+	"github.com/alsey89/people-matter/internal/common/middleware"
+	"github.com/alsey89/people-matter/internal/common/rbac"
+	"github.com/alsey89/people-matter/internal/common/util"
+	"github.com/alsey89/people-matter/internal/identity"
+	"github.com/alsey89/people-matter/pkg/pgconn"
+	"github.com/alsey89/people-matter/pkg/server"
+	"github.com/alsey89/people-matter/pkg/token"
+
+	"github.com/labstack/echo/v4"
+	"github.com/spf13/viper"
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+)
+
+type Domain struct {
+	scope  string
+	logger *zap.Logger
+	config *Config
+	params Params
+}
+
+type Params struct {
+	fx.In
+	Lifecycle fx.Lifecycle
+	Logger    *zap.Logger
+	DB        *pgconn.Module
+	Server    *server.Module
+	Token     *token.Module
+}
+
+type Config struct {
+	clientDomain string
+
+	maxTimesheetDays int
+}
+
+const (
+	defaultClientDomain = "localhost:3000"
+
+	defaultMaxTimesheetDays = 93
+)
+
+// ! Domain ---------------------------------------------------------------
+
+func InjectDomain(scope string) fx.Option {
+	return fx.Module(
+		scope,
+		fx.Provide(func(p Params) *Domain {
+			d := &Domain{scope: scope}
+			d.params = p
+			d.logger = d.setupLogger(scope, p)
+			d.config = d.setupConfig(scope)
+
+			return d
+		}),
+		fx.Invoke(func(d *Domain, p Params) {
+			d.registerRoutes()
+
+			p.Lifecycle.Append(
+				fx.Hook{
+					OnStart: d.onStart,
+					OnStop:  d.onStop,
+				},
+			)
+		}),
+	)
+}
+
+// ! Internal ---------------------------------------------------------------
+
+func (d *Domain) setupLogger(scope string, p Params) *zap.Logger {
+	logger := p.Logger.Named("[" + scope + "]")
+	return logger
+}
+
+func (d *Domain) setupConfig(scope string) *Config {
+	viper.SetDefault(util.GetConfigPath("global", "client_domain"), defaultClientDomain)
+	viper.SetDefault(util.GetConfigPath(scope, "max_timesheet_days"), defaultMaxTimesheetDays)
+
+	return &Config{
+		clientDomain: viper.GetString(util.GetConfigPath("global", "client_domain")),
+
+		maxTimesheetDays: viper.GetInt(util.GetConfigPath(scope, "max_timesheet_days")),
+	}
+}
+
+func (d *Domain) registerRoutes() {
+	e := d.params.Server.GetServer()
+
+	resolveTenant := middleware.ResolveTenant(d.params.DB, d.logger, d.config.clientDomain)
+	requireAuth := d.params.Token.GetJWTMiddleware(identity.AuthTokenScope)
+	requireSameTenant := middleware.RequireSameTenant(d.logger)
+	requireActiveSession := middleware.RequireActiveSession(d.params.DB, d.logger)
+
+	timekeepingGroup := e.Group("/api/v1/timekeeping", resolveTenant, requireAuth, requireSameTenant, requireActiveSession)
+	timekeepingGroup.POST("/clock-in", d.ClockInHandler)
+	timekeepingGroup.POST("/clock-out", d.ClockOutHandler)
+	timekeepingGroup.GET("/timesheet", d.GetTimesheetSummaryHandler)
+	timekeepingGroup.PUT("/time-logs/:id", d.UpdateTimeLogHandler)
+
+	timekeepingGroup.POST("/timesheets", d.CreateTimesheetHandler)
+	timekeepingGroup.GET("/timesheets", d.ListOwnTimesheetsHandler)
+	timekeepingGroup.GET("/timesheets/:id", d.GetOwnTimesheetHandler)
+	timekeepingGroup.GET("/timesheets/:id/breakdown", d.GetOwnTimesheetBreakdownHandler)
+	timekeepingGroup.POST("/timesheets/:id/submit", d.SubmitTimesheetHandler)
+
+	reviewGroup := timekeepingGroup.Group("/review")
+	reviewGroup.GET("/timesheets", d.ListTimesheetsHandler, d.requirePermission(rbac.TimesheetsRead))
+	reviewGroup.GET("/timesheets/:id", d.GetTimesheetHandler, d.requirePermission(rbac.TimesheetsRead))
+	reviewGroup.GET("/timesheets/:id/breakdown", d.GetTimesheetBreakdownHandler, d.requirePermission(rbac.TimesheetsRead))
+	reviewGroup.POST("/timesheets/:id/approve", d.ApproveTimesheetHandler, d.requirePermission(rbac.TimesheetsApprove))
+	reviewGroup.POST("/timesheets/:id/reject", d.RejectTimesheetHandler, d.requirePermission(rbac.TimesheetsApprove))
+	reviewGroup.POST("/timesheets/:id/reopen", d.ReopenTimesheetHandler, d.requirePermission(rbac.TimesheetsApprove))
+	reviewGroup.GET("/approved-hours", d.GetApprovedHoursHandler, d.requirePermission(rbac.PayrollRead))
+
+	rulesGroup := timekeepingGroup.Group("/rules")
+	rulesGroup.GET("", d.ListTimeRulesHandler, d.requirePermission(rbac.TimesheetsRead))
+	rulesGroup.POST("", d.CreateTimeRuleHandler, d.requirePermission(rbac.TimekeepingWrite))
+	rulesGroup.PUT("/:id", d.UpdateTimeRuleHandler, d.requirePermission(rbac.TimekeepingWrite))
+	rulesGroup.DELETE("/:id", d.DeleteTimeRuleHandler, d.requirePermission(rbac.TimekeepingWrite))
+}
+
+func (d *Domain) requirePermission(permission string) echo.MiddlewareFunc {
+	return middleware.RequirePermission(d.params.DB, d.logger, permission)
+}
+
+func (d *Domain) onStart(ctx context.Context) error {
+	d.logger.Info("Starting timekeeping domain.")
+
+	if viper.GetString("global.log_level") == "DEBUG" || viper.GetString("global.log_level") == "debug" {
+		d.logConfigurations()
+	}
+
+	return nil
+}
+
+func (d *Domain) onStop(ctx context.Context) error {
+	d.logger.Info("Stopping timekeeping domain.")
+	return nil
+}
+
+func (d *Domain) logConfigurations() {
+	d.logger.Debug("----- Timekeeping Configuration -----")
+	d.logger.Debug("Client Domain", zap.String("client_domain", d.config.clientDomain))
+	d.logger.Debug("Max Timesheet Days", zap.Int("max_timesheet_days", d.config.maxTimesheetDays))
+	d.logger.Debug("-------------------------------------")
+}
@@ -0,0 +1,624 @@
+package timekeeping
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/alsey89/people-matter/internal/common/API"
+	"github.com/alsey89/people-matter/internal/common/errmgr"
+	"github.com/alsey89/people-matter/internal/common/extractor"
+	"github.com/alsey89/people-matter/internal/common/middleware"
+	"github.com/alsey89/people-matter/internal/common/rbac"
+	"github.com/alsey89/people-matter/internal/schema"
+
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+)
+
+type ClockInRequest struct {
+	PositionID uint    `json:"positionId" validate:"required"`
+	LocationID uint    `json:"locationId" validate:"required"`
+	Notes      *string `json:"notes"      validate:"omitempty,max=1000"`
+}
+
+type ClockOutRequest struct {
+	Notes *string `json:"notes" validate:"omitempty,max=1000"`
+}
+
+type TimesheetQuery struct {
+	From string `query:"from" validate:"required"`
+	To   string `query:"to"   validate:"required"`
+}
+
+type UpdateTimeLogRequest struct {
+	ClockIn  time.Time `json:"clockIn"  validate:"required"`
+	ClockOut time.Time `json:"clockOut" validate:"required"`
+	Notes    *string   `json:"notes"    validate:"omitempty,max=1000"`
+}
+
+type CreateTimesheetRequest struct {
+	From string `json:"from" validate:"required"`
+	To   string `json:"to"   validate:"required"`
+}
+
+type ListTimesheetsQuery struct {
+	UserID     *uint  `query:"userId"`
+	LocationID *uint  `query:"locationId"`
+	Status     string `query:"status"     validate:"omitempty,oneof=draft submitted approved rejected"`
+	From       string `query:"from"`
+	To         string `query:"to"`
+}
+
+type ReviewTimesheetRequest struct {
+	Comment *string `json:"comment" validate:"omitempty,max=2000"`
+}
+
+type RejectTimesheetRequest struct {
+	Comment string `json:"comment" validate:"required,max=2000"`
+}
+
+type TimeRuleRequest struct {
+	LocationID *uint  `json:"locationId"`
+	PositionID *uint  `json:"positionId"`
+	Name       string `json:"name"       validate:"required,max=255"`
+
+	DailyOvertimeAfterMinutes  *int `json:"dailyOvertimeAfterMinutes"  validate:"omitempty,min=0,max=1440"`
+	WeeklyOvertimeAfterMinutes *int `json:"weeklyOvertimeAfterMinutes" validate:"omitempty,min=0,max=10080"`
+
+	BreakAfterMinutes int `json:"breakAfterMinutes" validate:"min=0,max=1440"`
+	BreakMinutes      int `json:"breakMinutes"      validate:"min=0,max=1440"`
+	RoundingMinutes   int `json:"roundingMinutes"   validate:"min=0,max=60"`
+}
+
+type ReopenTimesheetRequest struct {
+	Reason string `json:"reason" validate:"required,max=2000"`
+}
+
+// Opens a time log for the current user at a position and location they hold.
+func (d *Domain) ClockInHandler(c echo.Context) error {
+	userID, _, err := extractor.ExtractUserAndCompanyIDFromContext(c)
+	if err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("ClockInHandler: %w: %v", errmgr.ErrInvalidToken, err))
+	}
+
+	var req ClockInRequest
+	if err := c.Bind(&req); err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("ClockInHandler: %w: %v", errmgr.ErrPayload, err))
+	}
+	if err := c.Validate(&req); err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("ClockInHandler: %w: %v", errmgr.ErrPayload, err))
+	}
+
+	timeLog, err := d.clockIn(c.Request().Context(), userID, req.PositionID, req.LocationID, req.Notes)
+	if err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("ClockInHandler: %w", err))
+	}
+
+	return c.JSON(http.StatusCreated, API.Response{
+		Message: "Clocked in.",
+		Data:    timeLog,
+	})
+}
+
+// Closes the open time log of the current user.
+func (d *Domain) ClockOutHandler(c echo.Context) error {
+	userID, _, err := extractor.ExtractUserAndCompanyIDFromContext(c)
+	if err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("ClockOutHandler: %w: %v", errmgr.ErrInvalidToken, err))
+	}
+
+	var req ClockOutRequest
+	if err := c.Bind(&req); err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("ClockOutHandler: %w: %v", errmgr.ErrPayload, err))
+	}
+	if err := c.Validate(&req); err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("ClockOutHandler: %w: %v", errmgr.ErrPayload, err))
+	}
+
+	timeLog, err := d.clockOut(c.Request().Context(), userID, req.Notes)
+	if err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("ClockOutHandler: %w", err))
+	}
+
+	return c.JSON(http.StatusOK, API.Response{
+		Message: "Clocked out.",
+		Data:    timeLog,
+	})
+}
+
+// Returns a summary of the current user's time logs for an inclusive from/to date range (YYYY-MM-DD).
+func (d *Domain) GetTimesheetSummaryHandler(c echo.Context) error {
+	userID, _, err := extractor.ExtractUserAndCompanyIDFromContext(c)
+	if err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("GetTimesheetSummaryHandler: %w: %v", errmgr.ErrInvalidToken, err))
+	}
+
+	var query TimesheetQuery
+	if err := c.Bind(&query); err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("GetTimesheetSummaryHandler: %w: %v", errmgr.ErrPayload, err))
+	}
+	if err := c.Validate(&query); err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("GetTimesheetSummaryHandler: %w: %v", errmgr.ErrPayload, err))
+	}
+
+	start, end, err := parsePeriod(query.From, query.To, d.config.maxTimesheetDays)
+	if err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("GetTimesheetSummaryHandler: %w: %v", errmgr.ErrPayload, err))
+	}
+
+	summary, err := d.getTimesheetSummary(c.Request().Context(), userID, start, end)
+	if err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("GetTimesheetSummaryHandler: %w", err))
+	}
+
+	return c.JSON(http.StatusOK, API.Response{
+		Message: "Timesheet retrieved.",
+		Data:    summary,
+	})
+}
+
+// Corrects a closed time log of the current user, unless its timesheet is submitted or approved.
+func (d *Domain) UpdateTimeLogHandler(c echo.Context) error {
+	userID, _, err := extractor.ExtractUserAndCompanyIDFromContext(c)
+	if err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("UpdateTimeLogHandler: %w: %v", errmgr.ErrInvalidToken, err))
+	}
+
+	var timeLogID uint
+	if _, err := extractor.ExtractFromPathParamAs(c, "id", &timeLogID); err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("UpdateTimeLogHandler: %w: %v", errmgr.ErrPayload, err))
+	}
+
+	var req UpdateTimeLogRequest
+	if err := c.Bind(&req); err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("UpdateTimeLogHandler: %w: %v", errmgr.ErrPayload, err))
+	}
+	if err := c.Validate(&req); err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("UpdateTimeLogHandler: %w: %v", errmgr.ErrPayload, err))
+	}
+
+	timeLog, err := d.updateTimeLog(c.Request().Context(), userID, timeLogID, req.ClockIn, req.ClockOut, req.Notes)
+	if err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("UpdateTimeLogHandler: %w", err))
+	}
+
+	return c.JSON(http.StatusOK, API.Response{
+		Message: "Time log updated.",
+		Data:    timeLog,
+	})
+}
+
+// Creates a draft timesheet of the current user for an inclusive from/to date range (YYYY-MM-DD).
+func (d *Domain) CreateTimesheetHandler(c echo.Context) error {
+	userID, _, err := extractor.ExtractUserAndCompanyIDFromContext(c)
+	if err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("CreateTimesheetHandler: %w: %v", errmgr.ErrInvalidToken, err))
+	}
+
+	var req CreateTimesheetRequest
+	if err := c.Bind(&req); err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("CreateTimesheetHandler: %w: %v", errmgr.ErrPayload, err))
+	}
+	if err := c.Validate(&req); err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("CreateTimesheetHandler: %w: %v", errmgr.ErrPayload, err))
+	}
+
+	start, end, err := parsePeriod(req.From, req.To, d.config.maxTimesheetDays)
+	if err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("CreateTimesheetHandler: %w: %v", errmgr.ErrPayload, err))
+	}
+
+	timesheet, err := d.createTimesheet(c.Request().Context(), userID, start, end)
+	if err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("CreateTimesheetHandler: %w", err))
+	}
+
+	return c.JSON(http.StatusCreated, API.Response{
+		Message: "Timesheet created.",
+		Data:    timesheet,
+	})
+}
+
+// Lists the current user's timesheets.
+func (d *Domain) ListOwnTimesheetsHandler(c echo.Context) error {
+	userID, _, err := extractor.ExtractUserAndCompanyIDFromContext(c)
+	if err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("ListOwnTimesheetsHandler: %w: %v", errmgr.ErrInvalidToken, err))
+	}
+
+	filter, err := d.bindTimesheetFilter(c)
+	if err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("ListOwnTimesheetsHandler: %w", err))
+	}
+	filter.UserID = &userID
+
+	timesheets, err := d.listTimesheets(c.Request().Context(), *filter)
+	if err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("ListOwnTimesheetsHandler: %w", err))
+	}
+
+	return c.JSON(http.StatusOK, API.Response{
+		Message: "Timesheets retrieved.",
+		Data:    timesheets,
+	})
+}
+
+// Returns a timesheet of the current user with its time logs and audit trail.
+func (d *Domain) GetOwnTimesheetHandler(c echo.Context) error {
+	userID, _, err := extractor.ExtractUserAndCompanyIDFromContext(c)
+	if err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("GetOwnTimesheetHandler: %w: %v", errmgr.ErrInvalidToken, err))
+	}
+
+	var timesheetID uint
+	if _, err := extractor.ExtractFromPathParamAs(c, "id", &timesheetID); err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("GetOwnTimesheetHandler: %w: %v", errmgr.ErrPayload, err))
+	}
+
+	timesheet, err := d.getTimesheet(c.Request().Context(), timesheetID, &userID)
+	if err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("GetOwnTimesheetHandler: %w", err))
+	}
+
+	return c.JSON(http.StatusOK, API.Response{
+		Message: "Timesheet retrieved.",
+		Data:    timesheet,
+	})
+}
+
+// Submits a draft or rejected timesheet of the current user for review.
+func (d *Domain) SubmitTimesheetHandler(c echo.Context) error {
+	return d.transitionTimesheetHandler(c, "SubmitTimesheetHandler", actionSubmit, nil, "Timesheet submitted.")
+}
+
+// Lists the timesheets of users at the locations the caller may read, e.g. ?status=submitted for the review queue.
+func (d *Domain) ListTimesheetsHandler(c echo.Context) error {
+	permissions, err := middleware.LoadPermissions(c, d.params.DB)
+	if err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("ListTimesheetsHandler: %w", err))
+	}
+
+	filter, err := d.bindTimesheetFilter(c)
+	if err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("ListTimesheetsHandler: %w", err))
+	}
+
+	timesheets, err := d.listTimesheets(c.Request().Context(), *filter, permissions.ScopeUsers(rbac.TimesheetsRead, "timesheets.user_id"))
+	if err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("ListTimesheetsHandler: %w", err))
+	}
+
+	return c.JSON(http.StatusOK, API.Response{
+		Message: "Timesheets retrieved.",
+		Data:    timesheets,
+	})
+}
+
+// Returns a timesheet of a user at a location the caller may read, with its time logs and audit trail.
+func (d *Domain) GetTimesheetHandler(c echo.Context) error {
+	permissions, err := middleware.LoadPermissions(c, d.params.DB)
+	if err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("GetTimesheetHandler: %w", err))
+	}
+
+	var timesheetID uint
+	if _, err := extractor.ExtractFromPathParamAs(c, "id", &timesheetID); err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("GetTimesheetHandler: %w: %v", errmgr.ErrPayload, err))
+	}
+
+	timesheet, err := d.getTimesheet(c.Request().Context(), timesheetID, nil, permissions.ScopeUsers(rbac.TimesheetsRead, "timesheets.user_id"))
+	if err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("GetTimesheetHandler: %w", err))
+	}
+
+	return c.JSON(http.StatusOK, API.Response{
+		Message: "Timesheet retrieved.",
+		Data:    timesheet,
+	})
+}
+
+// Approves a submitted timesheet, locking its time logs. The comment is optional.
+func (d *Domain) ApproveTimesheetHandler(c echo.Context) error {
+	var req ReviewTimesheetRequest
+	if err := c.Bind(&req); err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("ApproveTimesheetHandler: %w: %v", errmgr.ErrPayload, err))
+	}
+	if err := c.Validate(&req); err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("ApproveTimesheetHandler: %w: %v", errmgr.ErrPayload, err))
+	}
+
+	return d.transitionTimesheetHandler(c, "ApproveTimesheetHandler", actionApprove, req.Comment, "Timesheet approved.")
+}
+
+// Rejects a submitted timesheet back to its owner, a comment is required.
+func (d *Domain) RejectTimesheetHandler(c echo.Context) error {
+	var req RejectTimesheetRequest
+	if err := c.Bind(&req); err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("RejectTimesheetHandler: %w: %v", errmgr.ErrPayload, err))
+	}
+	if err := c.Validate(&req); err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("RejectTimesheetHandler: %w: %v", errmgr.ErrPayload, err))
+	}
+
+	return d.transitionTimesheetHandler(c, "RejectTimesheetHandler", actionReject, &req.Comment, "Timesheet rejected.")
+}
+
+// Reopens an approved timesheet as a draft so that its time logs can be corrected, a reason is required for the audit trail.
+func (d *Domain) ReopenTimesheetHandler(c echo.Context) error {
+	var req ReopenTimesheetRequest
+	if err := c.Bind(&req); err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("ReopenTimesheetHandler: %w: %v", errmgr.ErrPayload, err))
+	}
+	if err := c.Validate(&req); err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("ReopenTimesheetHandler: %w: %v", errmgr.ErrPayload, err))
+	}
+
+	return d.transitionTimesheetHandler(c, "ReopenTimesheetHandler", actionReopen, &req.Reason, "Timesheet reopened.")
+}
+
+// Returns the approved hours per timesheet for an inclusive from/to date range (YYYY-MM-DD), for payroll.
+func (d *Domain) GetApprovedHoursHandler(c echo.Context) error {
+	var query TimesheetQuery
+	if err := c.Bind(&query); err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("GetApprovedHoursHandler: %w: %v", errmgr.ErrPayload, err))
+	}
+	if err := c.Validate(&query); err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("GetApprovedHoursHandler: %w: %v", errmgr.ErrPayload, err))
+	}
+
+	// payroll periods can be longer than a timesheet, so the range is not capped
+	start, end, err := parsePeriod(query.From, query.To, 0)
+	if err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("GetApprovedHoursHandler: %w: %v", errmgr.ErrPayload, err))
+	}
+
+	permissions, err := middleware.LoadPermissions(c, d.params.DB)
+	if err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("GetApprovedHoursHandler: %w", err))
+	}
+
+	approvedHours, err := d.ApprovedHours(c.Request().Context(), start, end, permissions.ScopeUsers(rbac.PayrollRead, "timesheets.user_id"))
+	if err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("GetApprovedHoursHandler: %w", err))
+	}
+
+	return c.JSON(http.StatusOK, API.Response{
+		Message: "Approved hours retrieved.",
+		Data:    approvedHours,
+	})
+}
+
+// Returns the regular/overtime breakdown of a timesheet of the current user.
+func (d *Domain) GetOwnTimesheetBreakdownHandler(c echo.Context) error {
+	userID, _, err := extractor.ExtractUserAndCompanyIDFromContext(c)
+	if err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("GetOwnTimesheetBreakdownHandler: %w: %v", errmgr.ErrInvalidToken, err))
+	}
+
+	var timesheetID uint
+	if _, err := extractor.ExtractFromPathParamAs(c, "id", &timesheetID); err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("GetOwnTimesheetBreakdownHandler: %w: %v", errmgr.ErrPayload, err))
+	}
+
+	breakdown, err := d.getTimesheetBreakdown(c.Request().Context(), timesheetID, &userID)
+	if err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("GetOwnTimesheetBreakdownHandler: %w", err))
+	}
+
+	return c.JSON(http.StatusOK, API.Response{
+		Message: "Timesheet breakdown retrieved.",
+		Data:    breakdown,
+	})
+}
+
+// Returns the regular/overtime breakdown of a timesheet of a user at a location the caller may read.
+func (d *Domain) GetTimesheetBreakdownHandler(c echo.Context) error {
+	permissions, err := middleware.LoadPermissions(c, d.params.DB)
+	if err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("GetTimesheetBreakdownHandler: %w", err))
+	}
+
+	var timesheetID uint
+	if _, err := extractor.ExtractFromPathParamAs(c, "id", &timesheetID); err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("GetTimesheetBreakdownHandler: %w: %v", errmgr.ErrPayload, err))
+	}
+
+	breakdown, err := d.getTimesheetBreakdown(c.Request().Context(), timesheetID, nil, permissions.ScopeUsers(rbac.TimesheetsRead, "timesheets.user_id"))
+	if err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("GetTimesheetBreakdownHandler: %w", err))
+	}
+
+	return c.JSON(http.StatusOK, API.Response{
+		Message: "Timesheet breakdown retrieved.",
+		Data:    breakdown,
+	})
+}
+
+func (d *Domain) ListTimeRulesHandler(c echo.Context) error {
+	rules, err := d.listTimeRules(c.Request().Context())
+	if err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("ListTimeRulesHandler: %w", err))
+	}
+
+	return c.JSON(http.StatusOK, API.Response{
+		Message: "Time rules retrieved.",
+		Data:    rules,
+	})
+}
+
+// Creates a time rule for a location, a position, both, or neither for the company default.
+func (d *Domain) CreateTimeRuleHandler(c echo.Context) error {
+	var req TimeRuleRequest
+	if err := c.Bind(&req); err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("CreateTimeRuleHandler: %w: %v", errmgr.ErrPayload, err))
+	}
+	if err := c.Validate(&req); err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("CreateTimeRuleHandler: %w: %v", errmgr.ErrPayload, err))
+	}
+
+	err := d.authorizeTimeRuleTarget(c, req.LocationID)
+	if err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("CreateTimeRuleHandler: %w", err))
+	}
+
+	rule, err := d.createTimeRule(c.Request().Context(), req.toTimeRule())
+	if err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("CreateTimeRuleHandler: %w", err))
+	}
+
+	return c.JSON(http.StatusCreated, API.Response{
+		Message: "Time rule created.",
+		Data:    rule,
+	})
+}
+
+func (d *Domain) UpdateTimeRuleHandler(c echo.Context) error {
+	var ruleID uint
+	if _, err := extractor.ExtractFromPathParamAs(c, "id", &ruleID); err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("UpdateTimeRuleHandler: %w: %v", errmgr.ErrPayload, err))
+	}
+
+	var req TimeRuleRequest
+	if err := c.Bind(&req); err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("UpdateTimeRuleHandler: %w: %v", errmgr.ErrPayload, err))
+	}
+	if err := c.Validate(&req); err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("UpdateTimeRuleHandler: %w: %v", errmgr.ErrPayload, err))
+	}
+
+	err := d.authorizeTimeRuleTarget(c, req.LocationID)
+	if err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("UpdateTimeRuleHandler: %w", err))
+	}
+	permissions, err := middleware.LoadPermissions(c, d.params.DB)
+	if err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("UpdateTimeRuleHandler: %w", err))
+	}
+
+	rule, err := d.updateTimeRule(c.Request().Context(), ruleID, req.toTimeRule(), permissions.ScopeLocations(rbac.TimekeepingWrite, "time_rules.location_id"))
+	if err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("UpdateTimeRuleHandler: %w", err))
+	}
+
+	return c.JSON(http.StatusOK, API.Response{
+		Message: "Time rule updated.",
+		Data:    rule,
+	})
+}
+
+func (d *Domain) DeleteTimeRuleHandler(c echo.Context) error {
+	var ruleID uint
+	if _, err := extractor.ExtractFromPathParamAs(c, "id", &ruleID); err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("DeleteTimeRuleHandler: %w: %v", errmgr.ErrPayload, err))
+	}
+
+	permissions, err := middleware.LoadPermissions(c, d.params.DB)
+	if err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("DeleteTimeRuleHandler: %w", err))
+	}
+
+	err = d.deleteTimeRule(c.Request().Context(), ruleID, permissions.ScopeLocations(rbac.TimekeepingWrite, "time_rules.location_id"))
+	if err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("DeleteTimeRuleHandler: %w", err))
+	}
+
+	return c.JSON(http.StatusOK, API.Response{
+		Message: "Time rule deleted.",
+	})
+}
+
+// ! Helpers ---------------------------------------------------------------
+
+func (d *Domain) transitionTimesheetHandler(c echo.Context, handler string, action string, comment *string, message string) error {
+	actorID, _, err := extractor.ExtractUserAndCompanyIDFromContext(c)
+	if err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("%s: %w: %v", handler, errmgr.ErrInvalidToken, err))
+	}
+
+	var timesheetID uint
+	if _, err := extractor.ExtractFromPathParamAs(c, "id", &timesheetID); err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("%s: %w: %v", handler, errmgr.ErrPayload, err))
+	}
+
+	var scopes []func(*gorm.DB) *gorm.DB
+	if action != actionSubmit {
+		permissions, err := middleware.LoadPermissions(c, d.params.DB)
+		if err != nil {
+			return API.RespondWithError(c, d.logger, fmt.Errorf("%s: %w", handler, err))
+		}
+		scopes = append(scopes, permissions.ScopeUsers(rbac.TimesheetsApprove, "timesheets.user_id"))
+	}
+
+	timesheet, err := d.transitionTimesheet(c.Request().Context(), actorID, timesheetID, action, comment, scopes...)
+	if err != nil {
+		return API.RespondWithError(c, d.logger, fmt.Errorf("%s: %w", handler, err))
+	}
+
+	return c.JSON(http.StatusOK, API.Response{
+		Message: message,
+		Data:    timesheet,
+	})
+}
+
+// Binds and validates the timesheet list query, the from/to dates are optional.
+func (d *Domain) bindTimesheetFilter(c echo.Context) (*TimesheetFilter, error) {
+	var query ListTimesheetsQuery
+	if err := c.Bind(&query); err != nil {
+		return nil, fmt.Errorf("bindTimesheetFilter: %w: %v", errmgr.ErrPayload, err)
+	}
+	if err := c.Validate(&query); err != nil {
+		return nil, fmt.Errorf("bindTimesheetFilter: %w: %v", errmgr.ErrPayload, err)
+	}
+
+	filter := TimesheetFilter{UserID: query.UserID, LocationID: query.LocationID, Status: query.Status}
+	if query.From != "" {
+		start, err := time.ParseInLocation(dateLayout, query.From, time.UTC)
+		if err != nil {
+			return nil, fmt.Errorf("bindTimesheetFilter: %w: invalid from date: %v", errmgr.ErrPayload, err)
+		}
+		filter.Start = &start
+	}
+	if query.To != "" {
+		to, err := time.ParseInLocation(dateLayout, query.To, time.UTC)
+		if err != nil {
+			return nil, fmt.Errorf("bindTimesheetFilter: %w: invalid to date: %v", errmgr.ErrPayload, err)
+		}
+		end := to.AddDate(0, 0, 1)
+		filter.End = &end
+	}
+
+	return &filter, nil
+}
+
+// Rules for a location need the write permission at that location, company default rules need it company wide.
+func (d *Domain) authorizeTimeRuleTarget(c echo.Context, locationID *uint) error {
+	permissions, err := middleware.LoadPermissions(c, d.params.DB)
+	if err != nil {
+		return fmt.Errorf("authorizeTimeRuleTarget: %w", err)
+	}
+
+	if locationID != nil {
+		if !permissions.HasAt(rbac.TimekeepingWrite, *locationID) {
+			return fmt.Errorf("authorizeTimeRuleTarget: %w: missing %s at location %d", errmgr.ErrPermission, rbac.TimekeepingWrite, *locationID)
+		}
+		return nil
+	}
+	if !permissions.HasCompanyWide(rbac.TimekeepingWrite) {
+		return fmt.Errorf("authorizeTimeRuleTarget: %w: missing company wide %s", errmgr.ErrPermission, rbac.TimekeepingWrite)
+	}
+	return nil
+}
+
+func (req *TimeRuleRequest) toTimeRule() schema.TimeRule {
+	return schema.TimeRule{
+		LocationID:                 req.LocationID,
+		PositionID:                 req.PositionID,
+		Name:                       req.Name,
+		DailyOvertimeAfterMinutes:  req.DailyOvertimeAfterMinutes,
+		WeeklyOvertimeAfterMinutes: req.WeeklyOvertimeAfterMinutes,
+		BreakAfterMinutes:          req.BreakAfterMinutes,
+		BreakMinutes:               req.BreakMinutes,
+		RoundingMinutes:            req.RoundingMinutes,
+	}
+}
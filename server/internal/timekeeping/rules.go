@@ -0,0 +1,176 @@
+package timekeeping
+
+import (
+	"sort"
+	"time"
+
+	"github.com/alsey89/people-matter/internal/schema"
+)
+
+// HoursBreakdown splits closed time logs into paid regular and overtime hours.
+type HoursBreakdown struct {
+	Entries       []EntryBreakdown `json:"entries"`
+	RegularHours  float64          `json:"regularHours"`
+	OvertimeHours float64          `json:"overtimeHours"`
+	BreakHours    float64          `json:"breakHours"` // Unpaid
+	TotalHours    float64          `json:"totalHours"` // Regular + overtime
+}
+
+// EntryBreakdown is the breakdown of a single time log, with clock in/out after rounding.
+type EntryBreakdown struct {
+	TimeLogID     uint      `json:"timeLogId"`
+	RuleID        *uint     `json:"ruleId"` // Nil if no rule applies
+	ClockIn       time.Time `json:"clockIn"`
+	ClockOut      time.Time `json:"clockOut"`
+	BreakHours    float64   `json:"breakHours"`
+	RegularHours  float64   `json:"regularHours"`
+	OvertimeHours float64   `json:"overtimeHours"`
+}
+
+/*
+Computes the breakdown of the closed logs clocked in at or after from. Open logs are ignored.
+Logs clocked in before from are only used as context, so that the daily and weekly thresholds
+account for time already worked earlier in the same day or week.
+
+For each log, in clock in order:
+ 1. the rule is resolved, see resolveRule
+ 2. clock in/out are rounded to the nearest RoundingMinutes
+ 3. the unpaid break is deducted if the shift is at least BreakAfterMinutes long
+ 4. paid time beyond the daily threshold is daily overtime
+ 5. regular time beyond the weekly threshold is weekly overtime
+
+A shift belongs to the day and week of its clock in, days are UTC dates and weeks start on Monday.
+*/
+func computeBreakdown(logs []schema.TimeLog, rules []schema.TimeRule, from time.Time) HoursBreakdown {
+	sorted := make([]schema.TimeLog, 0, len(logs))
+	for _, log := range logs {
+		if log.ClockOut != nil {
+			sorted = append(sorted, log)
+		}
+	}
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if !sorted[i].ClockIn.Equal(sorted[j].ClockIn) {
+			return sorted[i].ClockIn.Before(sorted[j].ClockIn)
+		}
+		return sorted[i].ID < sorted[j].ID
+	})
+
+	breakdown := HoursBreakdown{Entries: make([]EntryBreakdown, 0, len(sorted))}
+	paidPerDay := make(map[time.Time]time.Duration)
+	regularPerWeek := make(map[time.Time]time.Duration)
+
+	var totalRegular, totalOvertime, totalBreak time.Duration
+	for _, log := range sorted {
+		rule := resolveRule(rules, log)
+
+		clockIn, clockOut := log.ClockIn, *log.ClockOut
+		if rule != nil && rule.RoundingMinutes > 0 {
+			increment := minutes(rule.RoundingMinutes)
+			clockIn = clockIn.Round(increment)
+			clockOut = clockOut.Round(increment)
+		}
+		worked := clockOut.Sub(clockIn)
+		if worked < 0 {
+			worked = 0
+		}
+
+		var unpaidBreak time.Duration
+		if rule != nil && rule.BreakMinutes > 0 && worked >= minutes(rule.BreakAfterMinutes) {
+			unpaidBreak = min(minutes(rule.BreakMinutes), worked)
+		}
+		paid := worked - unpaidBreak
+
+		day := dateOf(clockIn.UTC())
+		week := weekOf(day)
+
+		var dailyOvertime time.Duration
+		if rule != nil && rule.DailyOvertimeAfterMinutes != nil {
+			room := max(minutes(*rule.DailyOvertimeAfterMinutes)-paidPerDay[day], 0)
+			dailyOvertime = max(paid-room, 0)
+		}
+		paidPerDay[day] += paid
+		regular := paid - dailyOvertime
+
+		var weeklyOvertime time.Duration
+		if rule != nil && rule.WeeklyOvertimeAfterMinutes != nil {
+			room := max(minutes(*rule.WeeklyOvertimeAfterMinutes)-regularPerWeek[week], 0)
+			weeklyOvertime = max(regular-room, 0)
+		}
+		regular -= weeklyOvertime
+		regularPerWeek[week] += regular
+
+		if log.ClockIn.Before(from) {
+			continue // context only
+		}
+
+		overtime := dailyOvertime + weeklyOvertime
+		totalRegular += regular
+		totalOvertime += overtime
+		totalBreak += unpaidBreak
+
+		entry := EntryBreakdown{
+			TimeLogID:     log.ID,
+			ClockIn:       clockIn,
+			ClockOut:      clockOut,
+			BreakHours:    toHours(unpaidBreak),
+			RegularHours:  toHours(regular),
+			OvertimeHours: toHours(overtime),
+		}
+		if rule != nil {
+			ruleID := rule.ID
+			entry.RuleID = &ruleID
+		}
+		breakdown.Entries = append(breakdown.Entries, entry)
+	}
+
+	// rounded once from the exact totals so that entries rounding does not accumulate
+	breakdown.RegularHours = toHours(totalRegular)
+	breakdown.OvertimeHours = toHours(totalOvertime)
+	breakdown.BreakHours = toHours(totalBreak)
+	breakdown.TotalHours = toHours(totalRegular + totalOvertime)
+
+	return breakdown
+}
+
+/*
+Returns the most specific rule matching the location and position of the log, or nil if none matches.
+Specificity: location and position > position only > location only > company default.
+Ties are broken by the lowest ID, so the result does not depend on the order of rules.
+*/
+func resolveRule(rules []schema.TimeRule, log schema.TimeLog) *schema.TimeRule {
+	var best *schema.TimeRule
+	bestScore := -1
+	for i := range rules {
+		rule := &rules[i]
+		if rule.LocationID != nil && (log.LocationID == nil || *rule.LocationID != *log.LocationID) {
+			continue
+		}
+		if rule.PositionID != nil && (log.PositionID == nil || *rule.PositionID != *log.PositionID) {
+			continue
+		}
+
+		score := 0
+		if rule.PositionID != nil {
+			score += 2
+		}
+		if rule.LocationID != nil {
+			score += 1
+		}
+
+		if score > bestScore || (score == bestScore && rule.ID < best.ID) {
+			best = rule
+			bestScore = score
+		}
+	}
+	return best
+}
+
+// Returns the Monday of the week of the date.
+func weekOf(day time.Time) time.Time {
+	offset := (int(day.Weekday()) + 6) % 7 // days since Monday
+	return day.AddDate(0, 0, -offset)
+}
+
+func minutes(m int) time.Duration {
+	return time.Duration(m) * time.Minute
+}
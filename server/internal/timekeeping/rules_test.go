@@ -0,0 +1,157 @@
+package timekeeping
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alsey89/people-matter/internal/schema"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Returns a closed log, times are on 2024-03-<day> UTC. 2024-03-04 is a Monday.
+func shift(id uint, day int, inHour int, inMinute int, outHour int, outMinute int) schema.TimeLog {
+	clockIn := time.Date(2024, 3, day, inHour, inMinute, 0, 0, time.UTC)
+	clockOut := time.Date(2024, 3, day, outHour, outMinute, 0, 0, time.UTC)
+	log := schema.TimeLog{ClockIn: clockIn, ClockOut: &clockOut}
+	log.ID = id
+	return log
+}
+
+func intPtr(i int) *int    { return &i }
+func uintPtr(u uint) *uint { return &u }
+
+func TestComputeBreakdownWithoutRules(t *testing.T) {
+	logs := []schema.TimeLog{shift(1, 4, 9, 0, 17, 7)}
+
+	breakdown := computeBreakdown(logs, nil, time.Time{})
+
+	require.Len(t, breakdown.Entries, 1)
+	assert.Nil(t, breakdown.Entries[0].RuleID)
+	assert.Equal(t, 8.12, breakdown.RegularHours)
+	assert.Equal(t, 0.0, breakdown.OvertimeHours)
+	assert.Equal(t, 8.12, breakdown.TotalHours)
+}
+
+func TestComputeBreakdownRoundingAndBreak(t *testing.T) {
+	rules := []schema.TimeRule{{RoundingMinutes: 15, BreakAfterMinutes: 360, BreakMinutes: 30}}
+	rules[0].ID = 1
+
+	logs := []schema.TimeLog{
+		shift(1, 4, 8, 53, 17, 8), // rounded to 09:00-17:15, 8.25h worked, 30 min break
+		shift(2, 5, 9, 7, 14, 53), // rounded to 09:00-15:00, exactly 6h, break applies
+		shift(3, 6, 9, 0, 13, 0),  // 4h, below the break threshold
+	}
+
+	breakdown := computeBreakdown(logs, rules, time.Time{})
+
+	require.Len(t, breakdown.Entries, 3)
+	assert.Equal(t, time.Date(2024, 3, 4, 9, 0, 0, 0, time.UTC), breakdown.Entries[0].ClockIn)
+	assert.Equal(t, time.Date(2024, 3, 4, 17, 15, 0, 0, time.UTC), breakdown.Entries[0].ClockOut)
+	assert.Equal(t, 7.75, breakdown.Entries[0].RegularHours)
+	assert.Equal(t, 0.5, breakdown.Entries[0].BreakHours)
+	assert.Equal(t, 5.5, breakdown.Entries[1].RegularHours)
+	assert.Equal(t, 4.0, breakdown.Entries[2].RegularHours)
+	assert.Equal(t, 0.0, breakdown.Entries[2].BreakHours)
+	assert.Equal(t, uintPtr(1), breakdown.Entries[0].RuleID)
+
+	assert.Equal(t, 17.25, breakdown.RegularHours)
+	assert.Equal(t, 1.0, breakdown.BreakHours)
+	assert.Equal(t, 17.25, breakdown.TotalHours)
+}
+
+func TestComputeBreakdownDailyOvertime(t *testing.T) {
+	rules := []schema.TimeRule{{DailyOvertimeAfterMinutes: intPtr(8 * 60)}}
+
+	logs := []schema.TimeLog{
+		shift(2, 4, 14, 0, 20, 0), // split shift, second part crosses the threshold
+		shift(1, 4, 6, 0, 10, 0),  // given out of order
+	}
+
+	breakdown := computeBreakdown(logs, rules, time.Time{})
+
+	require.Len(t, breakdown.Entries, 2)
+	assert.Equal(t, uint(1), breakdown.Entries[0].TimeLogID)
+	assert.Equal(t, 4.0, breakdown.Entries[0].RegularHours)
+	assert.Equal(t, 4.0, breakdown.Entries[1].RegularHours)
+	assert.Equal(t, 2.0, breakdown.Entries[1].OvertimeHours)
+	assert.Equal(t, 8.0, breakdown.RegularHours)
+	assert.Equal(t, 2.0, breakdown.OvertimeHours)
+	assert.Equal(t, 10.0, breakdown.TotalHours)
+}
+
+func TestComputeBreakdownWeeklyOvertime(t *testing.T) {
+	rules := []schema.TimeRule{{
+		DailyOvertimeAfterMinutes:  intPtr(8 * 60),
+		WeeklyOvertimeAfterMinutes: intPtr(40 * 60),
+	}}
+
+	// Monday to Saturday, 9h each: 1h daily overtime per day, and Saturday's regular hours exceed the week
+	var logs []schema.TimeLog
+	for day := 4; day <= 9; day++ {
+		logs = append(logs, shift(uint(day), day, 8, 0, 17, 0))
+	}
+	// the next Monday starts a new week
+	logs = append(logs, shift(11, 11, 8, 0, 16, 0))
+
+	breakdown := computeBreakdown(logs, rules, time.Time{})
+
+	require.Len(t, breakdown.Entries, 7)
+	assert.Equal(t, 8.0, breakdown.Entries[4].RegularHours) // Friday, 40h regular reached
+	assert.Equal(t, 1.0, breakdown.Entries[4].OvertimeHours)
+	assert.Equal(t, 0.0, breakdown.Entries[5].RegularHours) // Saturday, all overtime
+	assert.Equal(t, 9.0, breakdown.Entries[5].OvertimeHours)
+	assert.Equal(t, 8.0, breakdown.Entries[6].RegularHours)
+	assert.Equal(t, 0.0, breakdown.Entries[6].OvertimeHours)
+
+	assert.Equal(t, 48.0, breakdown.RegularHours)
+	assert.Equal(t, 14.0, breakdown.OvertimeHours)
+}
+
+func TestComputeBreakdownContextLogs(t *testing.T) {
+	rules := []schema.TimeRule{{WeeklyOvertimeAfterMinutes: intPtr(10 * 60)}}
+
+	logs := []schema.TimeLog{
+		shift(1, 4, 9, 0, 17, 0),                               // Monday, before the period
+		shift(2, 5, 9, 0, 17, 0),                               // Tuesday, in the period
+		{ClockIn: time.Date(2024, 3, 6, 9, 0, 0, 0, time.UTC)}, // open, ignored
+	}
+
+	breakdown := computeBreakdown(logs, rules, time.Date(2024, 3, 5, 0, 0, 0, 0, time.UTC))
+
+	require.Len(t, breakdown.Entries, 1)
+	assert.Equal(t, uint(2), breakdown.Entries[0].TimeLogID)
+	assert.Equal(t, 2.0, breakdown.RegularHours)
+	assert.Equal(t, 6.0, breakdown.OvertimeHours)
+}
+
+func TestResolveRule(t *testing.T) {
+	rules := []schema.TimeRule{
+		{LocationID: uintPtr(1), PositionID: uintPtr(2)},
+		{PositionID: uintPtr(2)},
+		{LocationID: uintPtr(1)},
+		{},
+		{}, // duplicate default, lower ID wins
+	}
+	for i := range rules {
+		rules[i].ID = uint(10 - i)
+	}
+
+	log := func(locationID *uint, positionID *uint) schema.TimeLog {
+		return schema.TimeLog{LocationID: locationID, PositionID: positionID}
+	}
+
+	assert.Equal(t, uint(10), resolveRule(rules, log(uintPtr(1), uintPtr(2))).ID)
+	assert.Equal(t, uint(9), resolveRule(rules, log(uintPtr(3), uintPtr(2))).ID)
+	assert.Equal(t, uint(8), resolveRule(rules, log(uintPtr(1), uintPtr(4))).ID)
+	assert.Equal(t, uint(6), resolveRule(rules, log(uintPtr(3), nil)).ID)
+	assert.Nil(t, resolveRule(rules[:3], log(uintPtr(3), uintPtr(4))))
+}
+
+func TestWeekOf(t *testing.T) {
+	monday := time.Date(2024, 3, 4, 0, 0, 0, 0, time.UTC)
+	assert.Equal(t, monday, weekOf(monday))
+	assert.Equal(t, monday, weekOf(time.Date(2024, 3, 10, 0, 0, 0, 0, time.UTC)))
+	assert.Equal(t, monday.AddDate(0, 0, 7), weekOf(time.Date(2024, 3, 11, 0, 0, 0, 0, time.UTC)))
+}
@@ -0,0 +1,741 @@
+package timekeeping
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/alsey89/people-matter/internal/common/errmgr"
+	"github.com/alsey89/people-matter/internal/schema"
+	"github.com/alsey89/people-matter/pkg/pgconn"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Name of the partial unique index that allows at most one open time log per user.
+const openLogIndex = "idx_time_logs_open_per_user"
+
+func (d *Domain) clockIn(ctx context.Context, userID uint, positionID uint, locationID uint, notes *string) (*schema.TimeLog, error) {
+	db, err := d.params.DB.GetScopedDB(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("clockIn: %w: %v", errmgr.ErrTenant, err)
+	}
+
+	now := time.Now()
+	timeLog := schema.TimeLog{
+		UserID:     userID,
+		PositionID: &positionID,
+		LocationID: &locationID,
+		ClockIn:    now,
+		Notes:      notes,
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		held, err := holdsPosition(tx, userID, positionID, locationID, now)
+		if err != nil {
+			return err
+		}
+		if !held {
+			return errmgr.ErrPositionNotHeld
+		}
+
+		locked, err := lockedTimesheetCovers(tx, userID, now)
+		if err != nil {
+			return err
+		}
+		if locked {
+			return errmgr.ErrTimesheetLocked
+		}
+
+		var openLogs int64
+		err = tx.Model(&schema.TimeLog{}).
+			Where("user_id = ? AND clock_out IS NULL", userID).
+			Count(&openLogs).Error
+		if err != nil {
+			return err
+		}
+		if openLogs > 0 {
+			return errmgr.ErrAlreadyClockedIn
+		}
+
+		return tx.Create(&timeLog).Error
+	})
+	if err != nil {
+		// a concurrent clock in won the race between the count and the insert
+		if pgconn.IsUniqueViolation(err, openLogIndex) {
+			return nil, fmt.Errorf("clockIn: %w", errmgr.ErrAlreadyClockedIn)
+		}
+		return nil, fmt.Errorf("clockIn: %w", err)
+	}
+
+	return &timeLog, nil
+}
+
+func (d *Domain) clockOut(ctx context.Context, userID uint, notes *string) (*schema.TimeLog, error) {
+	db, err := d.params.DB.GetScopedDB(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("clockOut: %w: %v", errmgr.ErrTenant, err)
+	}
+
+	var timeLog schema.TimeLog
+	err = db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Where("user_id = ? AND clock_out IS NULL", userID).First(&timeLog).Error
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errmgr.ErrNotClockedIn
+			}
+			return err
+		}
+
+		updates := map[string]interface{}{"clock_out": time.Now()}
+		if notes != nil {
+			updates["notes"] = *notes
+		}
+
+		// conditional update so that concurrent requests cannot both close the same log
+		result := tx.Model(&timeLog).Where("clock_out IS NULL").Updates(updates)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected != 1 {
+			return errmgr.ErrNotClockedIn
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("clockOut: %w", err)
+	}
+
+	return &timeLog, nil
+}
+
+// Returns the timesheet summary of a user for the [start, end) period.
+func (d *Domain) getTimesheetSummary(ctx context.Context, userID uint, start time.Time, end time.Time) (*TimesheetSummary, error) {
+	db, err := d.params.DB.GetScopedDB(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("getTimesheetSummary: %w: %v", errmgr.ErrTenant, err)
+	}
+
+	var logs []schema.TimeLog
+	err = db.
+		Where("user_id = ? AND clock_in < ? AND (clock_out IS NULL OR clock_out > ?)", userID, end, start).
+		Order("clock_in").
+		Find(&logs).Error
+	if err != nil {
+		return nil, fmt.Errorf("getTimesheetSummary: %w", err)
+	}
+
+	summary := buildTimesheetSummary(userID, logs, start, end)
+
+	return &summary, nil
+}
+
+/*
+Corrects the clock in/out times and notes of a closed time log of the user.
+Logs attached to a submitted or approved timesheet cannot be edited, the timesheet must be rejected or reopened first.
+*/
+func (d *Domain) updateTimeLog(ctx context.Context, userID uint, timeLogID uint, clockIn time.Time, clockOut time.Time, notes *string) (*schema.TimeLog, error) {
+	db, err := d.params.DB.GetScopedDB(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("updateTimeLog: %w: %v", errmgr.ErrTenant, err)
+	}
+
+	if !clockOut.After(clockIn) {
+		return nil, fmt.Errorf("updateTimeLog: %w: clock out must be after clock in", errmgr.ErrPayload)
+	}
+	if clockOut.After(time.Now()) {
+		return nil, fmt.Errorf("updateTimeLog: %w: clock out cannot be in the future", errmgr.ErrPayload)
+	}
+
+	var timeLog schema.TimeLog
+	err = db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Where("id = ? AND user_id = ?", timeLogID, userID).First(&timeLog).Error
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errmgr.ErrTimeLogNotFound
+			}
+			return err
+		}
+		if timeLog.ClockOut == nil {
+			return fmt.Errorf("%w: clock out before editing", errmgr.ErrAlreadyClockedIn)
+		}
+
+		// moving a log into a locked period would change hours that are already under review
+		locked, err := lockedTimesheetCovers(tx, userID, clockIn)
+		if err != nil {
+			return err
+		}
+		if locked {
+			return errmgr.ErrTimesheetLocked
+		}
+
+		var overlapping int64
+		err = tx.Model(&schema.TimeLog{}).
+			Where("user_id = ? AND id <> ?", userID, timeLog.ID).
+			Where("clock_in < ? AND (clock_out IS NULL OR clock_out > ?)", clockOut, clockIn).
+			Count(&overlapping).Error
+		if err != nil {
+			return err
+		}
+		if overlapping > 0 {
+			return errmgr.ErrTimeLogOverlap
+		}
+
+		// conditional update so that a concurrent submit or approval cannot slip in between the checks
+		result := tx.Model(&timeLog).
+			Where("timesheet_id IS NULL OR timesheet_id IN (?)",
+				tx.Model(&schema.Timesheet{}).Select("id").Where("status IN ?", editableTimesheetStatuses),
+			).
+			Updates(map[string]interface{}{
+				"clock_in":  clockIn,
+				"clock_out": clockOut,
+				"notes":     notes,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected != 1 {
+			return errmgr.ErrTimesheetLocked
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("updateTimeLog: %w", err)
+	}
+
+	return &timeLog, nil
+}
+
+/*
+Creates a draft timesheet of the user for the [start, end) period and attaches the user's closed,
+unattached time logs that were clocked in during the period.
+Periods of the same user cannot overlap.
+*/
+func (d *Domain) createTimesheet(ctx context.Context, userID uint, start time.Time, end time.Time) (*schema.Timesheet, error) {
+	db, err := d.params.DB.GetScopedDB(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("createTimesheet: %w: %v", errmgr.ErrTenant, err)
+	}
+
+	timesheet := schema.Timesheet{
+		UserID:      userID,
+		PeriodStart: start,
+		PeriodEnd:   end.AddDate(0, 0, -1),
+		Status:      schema.TimesheetStatusDraft,
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		// serializes timesheet creation per user, so the overlap check cannot race
+		var user schema.User
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", userID).First(&user).Error
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errmgr.ErrUserNotFound
+			}
+			return err
+		}
+
+		var overlapping int64
+		err = tx.Model(&schema.Timesheet{}).
+			Where("user_id = ? AND period_start <= ? AND period_end >= ?", userID, timesheet.PeriodEnd, timesheet.PeriodStart).
+			Count(&overlapping).Error
+		if err != nil {
+			return err
+		}
+		if overlapping > 0 {
+			return errmgr.ErrTimesheetOverlap
+		}
+
+		err = tx.Create(&timesheet).Error
+		if err != nil {
+			return err
+		}
+
+		return syncTimeLogs(tx, &timesheet)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("createTimesheet: %w", err)
+	}
+
+	return d.getTimesheet(ctx, timesheet.ID, &userID)
+}
+
+// Returns the timesheets matching the filter and scopes, most recent period first.
+func (d *Domain) listTimesheets(ctx context.Context, filter TimesheetFilter, scopes ...func(*gorm.DB) *gorm.DB) ([]schema.Timesheet, error) {
+	db, err := d.params.DB.GetScopedDB(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("listTimesheets: %w: %v", errmgr.ErrTenant, err)
+	}
+
+	query := db.Model(&schema.Timesheet{}).Scopes(scopes...)
+	if filter.UserID != nil {
+		query = query.Where("user_id = ?", *filter.UserID)
+	}
+	if filter.LocationID != nil {
+		query = query.Where("user_id IN (SELECT user_id FROM user_positions WHERE location_id = ? AND ? AND deleted_at IS NULL)",
+			*filter.LocationID, schema.ActiveUserPositions("user_positions", time.Now()))
+	}
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+	if filter.Start != nil {
+		query = query.Where("period_end >= ?", *filter.Start)
+	}
+	if filter.End != nil {
+		query = query.Where("period_start < ?", *filter.End)
+	}
+
+	var timesheets []schema.Timesheet
+	err = query.Order("period_start DESC, id DESC").Find(&timesheets).Error
+	if err != nil {
+		return nil, fmt.Errorf("listTimesheets: %w", err)
+	}
+
+	return timesheets, nil
+}
+
+// Returns a timesheet with its time logs and events. If userID is set, only that user's timesheet is returned.
+func (d *Domain) getTimesheet(ctx context.Context, timesheetID uint, userID *uint, scopes ...func(*gorm.DB) *gorm.DB) (*schema.Timesheet, error) {
+	db, err := d.params.DB.GetScopedDB(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("getTimesheet: %w: %v", errmgr.ErrTenant, err)
+	}
+
+	query := db.
+		Preload("TimeLogs", func(db *gorm.DB) *gorm.DB { return db.Order("clock_in") }).
+		Preload("Events", func(db *gorm.DB) *gorm.DB { return db.Order("created_at, id") }).
+		Scopes(scopes...).
+		Where("id = ?", timesheetID)
+	if userID != nil {
+		query = query.Where("user_id = ?", *userID)
+	}
+
+	var timesheet schema.Timesheet
+	err = query.First(&timesheet).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("getTimesheet: %w", errmgr.ErrTimesheetNotFound)
+		}
+		return nil, fmt.Errorf("getTimesheet: %w", err)
+	}
+
+	return &timesheet, nil
+}
+
+/*
+Applies an action to a timesheet and records it in the audit trail.
+Only the owner can submit, and only someone else can approve, reject or reopen.
+Submitting re-attaches the logs of the period and fails while the user is clocked in during it.
+Approving snapshots the total hours so that payroll reads a value that cannot drift.
+Timesheets outside the scopes are reported as not found.
+*/
+func (d *Domain) transitionTimesheet(ctx context.Context, actorID uint, timesheetID uint, action string, comment *string, scopes ...func(*gorm.DB) *gorm.DB) (*schema.Timesheet, error) {
+	db, err := d.params.DB.GetScopedDB(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("transitionTimesheet: %w: %v", errmgr.ErrTenant, err)
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		var timesheet schema.Timesheet
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Scopes(scopes...).Where("id = ?", timesheetID).First(&timesheet).Error
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errmgr.ErrTimesheetNotFound
+			}
+			return err
+		}
+
+		isOwner := timesheet.UserID == actorID
+		if action == actionSubmit && !isOwner {
+			return fmt.Errorf("%w: only the owner can submit a timesheet", errmgr.ErrPermission)
+		}
+		if action != actionSubmit && isOwner {
+			return fmt.Errorf("%w: cannot review own timesheet", errmgr.ErrPermission)
+		}
+
+		nextStatus, err := nextTimesheetStatus(timesheet.Status, action)
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		updates := map[string]interface{}{"status": nextStatus}
+
+		switch action {
+		case actionSubmit:
+			start, end := timesheetPeriod(&timesheet)
+			var openLogs int64
+			err := tx.Model(&schema.TimeLog{}).
+				Where("user_id = ? AND clock_out IS NULL AND clock_in >= ? AND clock_in < ?", timesheet.UserID, start, end).
+				Count(&openLogs).Error
+			if err != nil {
+				return err
+			}
+			if openLogs > 0 {
+				return fmt.Errorf("%w: clock out before submitting", errmgr.ErrAlreadyClockedIn)
+			}
+			err = syncTimeLogs(tx, &timesheet)
+			if err != nil {
+				return err
+			}
+			updates["submitted_at"] = now
+
+		case actionApprove:
+			breakdown, err := timesheetBreakdown(tx, &timesheet)
+			if err != nil {
+				return err
+			}
+			updates["approved_hours"] = breakdown.TotalHours
+			updates["approved_regular_hours"] = breakdown.RegularHours
+			updates["approved_overtime_hours"] = breakdown.OvertimeHours
+			updates["reviewer_id"] = actorID
+			updates["reviewed_at"] = now
+			updates["review_comment"] = comment
+
+		case actionReject:
+			updates["reviewer_id"] = actorID
+			updates["reviewed_at"] = now
+			updates["review_comment"] = comment
+
+		case actionReopen:
+			updates["approved_hours"] = 0
+			updates["approved_regular_hours"] = 0
+			updates["approved_overtime_hours"] = 0
+		}
+
+		err = tx.Model(&timesheet).Updates(updates).Error
+		if err != nil {
+			return err
+		}
+
+		return tx.Create(&schema.TimesheetEvent{
+			TimesheetID: timesheet.ID,
+			ActorID:     actorID,
+			FromStatus:  timesheet.Status,
+			ToStatus:    nextStatus,
+			Comment:     comment,
+		}).Error
+	})
+	if err != nil {
+		return nil, fmt.Errorf("transitionTimesheet: %w", err)
+	}
+
+	return d.getTimesheet(ctx, timesheetID, nil)
+}
+
+// ApprovedHours is the approved time of one timesheet, as consumed by payroll.
+type ApprovedHours struct {
+	TimesheetID   uint    `json:"timesheetId"`
+	UserID        uint    `json:"userId"`
+	PeriodStart   string  `json:"periodStart"`
+	PeriodEnd     string  `json:"periodEnd"`
+	Hours         float64 `json:"hours"` // Regular + overtime
+	RegularHours  float64 `json:"regularHours"`
+	OvertimeHours float64 `json:"overtimeHours"`
+}
+
+// Returns the approved hours of all timesheets whose period lies within [start, end), ordered by user and period.
+func (d *Domain) ApprovedHours(ctx context.Context, start time.Time, end time.Time, scopes ...func(*gorm.DB) *gorm.DB) ([]ApprovedHours, error) {
+	db, err := d.params.DB.GetScopedDB(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("ApprovedHours: %w: %v", errmgr.ErrTenant, err)
+	}
+
+	var timesheets []schema.Timesheet
+	err = db.
+		Scopes(scopes...).
+		Where("status = ? AND period_start >= ? AND period_end < ?", schema.TimesheetStatusApproved, start, end).
+		Order("user_id, period_start").
+		Find(&timesheets).Error
+	if err != nil {
+		return nil, fmt.Errorf("ApprovedHours: %w", err)
+	}
+
+	approved := make([]ApprovedHours, 0, len(timesheets))
+	for _, timesheet := range timesheets {
+		approved = append(approved, ApprovedHours{
+			TimesheetID:   timesheet.ID,
+			UserID:        timesheet.UserID,
+			PeriodStart:   timesheet.PeriodStart.Format(dateLayout),
+			PeriodEnd:     timesheet.PeriodEnd.Format(dateLayout),
+			Hours:         timesheet.ApprovedHours,
+			RegularHours:  timesheet.ApprovedRegularHours,
+			OvertimeHours: timesheet.ApprovedOvertimeHours,
+		})
+	}
+
+	return approved, nil
+}
+
+// Returns the rules engine breakdown of a timesheet. If userID is set, only that user's timesheet is used.
+func (d *Domain) getTimesheetBreakdown(ctx context.Context, timesheetID uint, userID *uint, scopes ...func(*gorm.DB) *gorm.DB) (*HoursBreakdown, error) {
+	db, err := d.params.DB.GetScopedDB(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("getTimesheetBreakdown: %w: %v", errmgr.ErrTenant, err)
+	}
+
+	query := db.Scopes(scopes...).Where("id = ?", timesheetID)
+	if userID != nil {
+		query = query.Where("user_id = ?", *userID)
+	}
+
+	var timesheet schema.Timesheet
+	err = query.First(&timesheet).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("getTimesheetBreakdown: %w", errmgr.ErrTimesheetNotFound)
+		}
+		return nil, fmt.Errorf("getTimesheetBreakdown: %w", err)
+	}
+
+	breakdown, err := timesheetBreakdown(db, &timesheet)
+	if err != nil {
+		return nil, fmt.Errorf("getTimesheetBreakdown: %w", err)
+	}
+
+	return breakdown, nil
+}
+
+// Returns the time rules of the company, company defaults first.
+func (d *Domain) listTimeRules(ctx context.Context) ([]schema.TimeRule, error) {
+	db, err := d.params.DB.GetScopedDB(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("listTimeRules: %w: %v", errmgr.ErrTenant, err)
+	}
+
+	var rules []schema.TimeRule
+	err = db.Order("location_id NULLS FIRST, position_id NULLS FIRST, id").Find(&rules).Error
+	if err != nil {
+		return nil, fmt.Errorf("listTimeRules: %w", err)
+	}
+
+	return rules, nil
+}
+
+// Creates a time rule, at most one rule can exist per location and position combination.
+func (d *Domain) createTimeRule(ctx context.Context, rule schema.TimeRule) (*schema.TimeRule, error) {
+	db, err := d.params.DB.GetScopedDB(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("createTimeRule: %w: %v", errmgr.ErrTenant, err)
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		err := validateTimeRuleTargets(tx, &rule)
+		if err != nil {
+			return err
+		}
+		return tx.Create(&rule).Error
+	})
+	if err != nil {
+		return nil, fmt.Errorf("createTimeRule: %w", err)
+	}
+
+	return &rule, nil
+}
+
+// Replaces the settings and targets of a time rule. Approved timesheets keep their snapshot.
+// Rules outside the scopes are reported as not found.
+func (d *Domain) updateTimeRule(ctx context.Context, ruleID uint, rule schema.TimeRule, scopes ...func(*gorm.DB) *gorm.DB) (*schema.TimeRule, error) {
+	db, err := d.params.DB.GetScopedDB(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("updateTimeRule: %w: %v", errmgr.ErrTenant, err)
+	}
+
+	var existing schema.TimeRule
+	err = db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Scopes(scopes...).Where("id = ?", ruleID).First(&existing).Error
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errmgr.ErrTimeRuleNotFound
+			}
+			return err
+		}
+
+		rule.ID = existing.ID
+		err = validateTimeRuleTargets(tx, &rule)
+		if err != nil {
+			return err
+		}
+
+		return tx.Model(&existing).Updates(map[string]interface{}{
+			"location_id":                   rule.LocationID,
+			"position_id":                   rule.PositionID,
+			"name":                          rule.Name,
+			"daily_overtime_after_minutes":  rule.DailyOvertimeAfterMinutes,
+			"weekly_overtime_after_minutes": rule.WeeklyOvertimeAfterMinutes,
+			"break_after_minutes":           rule.BreakAfterMinutes,
+			"break_minutes":                 rule.BreakMinutes,
+			"rounding_minutes":              rule.RoundingMinutes,
+		}).Error
+	})
+	if err != nil {
+		return nil, fmt.Errorf("updateTimeRule: %w", err)
+	}
+
+	return &existing, nil
+}
+
+// Deletes a time rule. Rules outside the scopes are reported as not found.
+func (d *Domain) deleteTimeRule(ctx context.Context, ruleID uint, scopes ...func(*gorm.DB) *gorm.DB) error {
+	db, err := d.params.DB.GetScopedDB(ctx)
+	if err != nil {
+		return fmt.Errorf("deleteTimeRule: %w: %v", errmgr.ErrTenant, err)
+	}
+
+	result := db.Scopes(scopes...).Where("id = ?", ruleID).Delete(&schema.TimeRule{})
+	if result.Error != nil {
+		return fmt.Errorf("deleteTimeRule: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("deleteTimeRule: %w", errmgr.ErrTimeRuleNotFound)
+	}
+
+	return nil
+}
+
+// ! Helpers ---------------------------------------------------------------
+
+// Reports whether the user holds the position at the location at the given time.
+func holdsPosition(db *gorm.DB, userID uint, positionID uint, locationID uint, at time.Time) (bool, error) {
+	var count int64
+	err := db.Model(&schema.UserPosition{}).
+		Where("user_id = ? AND position_id = ? AND location_id = ?", userID, positionID, locationID).
+		Where(schema.ActiveUserPositions("user_positions", at)).
+		Count(&count).Error
+	if err != nil {
+		return false, fmt.Errorf("holdsPosition: %w", err)
+	}
+
+	return count > 0, nil
+}
+
+// Reports whether a submitted or approved timesheet of the user covers the date of the given time.
+func lockedTimesheetCovers(db *gorm.DB, userID uint, at time.Time) (bool, error) {
+	date := at.UTC().Format(dateLayout)
+
+	var count int64
+	err := db.Model(&schema.Timesheet{}).
+		Where("user_id = ? AND status IN ?", userID, lockedTimesheetStatuses).
+		Where("period_start <= ? AND period_end >= ?", date, date).
+		Count(&count).Error
+	if err != nil {
+		return false, fmt.Errorf("lockedTimesheetCovers: %w", err)
+	}
+
+	return count > 0, nil
+}
+
+// Attaches the user's closed, unattached logs clocked in during the timesheet period,
+// and detaches logs that were edited out of it.
+func syncTimeLogs(db *gorm.DB, timesheet *schema.Timesheet) error {
+	start, end := timesheetPeriod(timesheet)
+
+	err := db.Model(&schema.TimeLog{}).
+		Where("timesheet_id = ? AND (clock_in < ? OR clock_in >= ?)", timesheet.ID, start, end).
+		Update("timesheet_id", nil).Error
+	if err != nil {
+		return fmt.Errorf("syncTimeLogs: %w", err)
+	}
+
+	err = db.Model(&schema.TimeLog{}).
+		Where("user_id = ? AND timesheet_id IS NULL AND clock_out IS NOT NULL", timesheet.UserID).
+		Where("clock_in >= ? AND clock_in < ?", start, end).
+		Update("timesheet_id", timesheet.ID).Error
+	if err != nil {
+		return fmt.Errorf("syncTimeLogs: %w", err)
+	}
+
+	return nil
+}
+
+// Returns the [start, end) period of a timesheet.
+func timesheetPeriod(timesheet *schema.Timesheet) (time.Time, time.Time) {
+	start := dateOf(timesheet.PeriodStart)
+	end := dateOf(timesheet.PeriodEnd).AddDate(0, 0, 1)
+	return start, end
+}
+
+// Returns midnight UTC of the calendar date of t, as stored in date columns.
+func dateOf(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+/*
+Computes the rules engine breakdown of the logs attached to a timesheet.
+Earlier logs of the same week are loaded as context, so that weekly overtime is correct
+for periods that do not start on a Monday.
+*/
+func timesheetBreakdown(db *gorm.DB, timesheet *schema.Timesheet) (*HoursBreakdown, error) {
+	start, _ := timesheetPeriod(timesheet)
+
+	var logs []schema.TimeLog
+	err := db.
+		Where("user_id = ? AND clock_out IS NOT NULL", timesheet.UserID).
+		Where("timesheet_id = ? OR (clock_in >= ? AND clock_in < ?)", timesheet.ID, weekOf(start), start).
+		Find(&logs).Error
+	if err != nil {
+		return nil, fmt.Errorf("timesheetBreakdown: %w", err)
+	}
+
+	var rules []schema.TimeRule
+	err = db.Find(&rules).Error
+	if err != nil {
+		return nil, fmt.Errorf("timesheetBreakdown: %w", err)
+	}
+
+	breakdown := computeBreakdown(logs, rules, start)
+
+	return &breakdown, nil
+}
+
+// Checks that the location and position of a rule belong to the company,
+// and that no other rule targets the same combination.
+func validateTimeRuleTargets(db *gorm.DB, rule *schema.TimeRule) error {
+	if rule.LocationID != nil {
+		var count int64
+		err := db.Model(&schema.Location{}).Where("id = ?", *rule.LocationID).Count(&count).Error
+		if err != nil {
+			return fmt.Errorf("validateTimeRuleTargets: %w", err)
+		}
+		if count == 0 {
+			return fmt.Errorf("validateTimeRuleTargets: %w: unknown location", errmgr.ErrPayload)
+		}
+	}
+	if rule.PositionID != nil {
+		var count int64
+		err := db.Model(&schema.Position{}).Where("id = ?", *rule.PositionID).Count(&count).Error
+		if err != nil {
+			return fmt.Errorf("validateTimeRuleTargets: %w", err)
+		}
+		if count == 0 {
+			return fmt.Errorf("validateTimeRuleTargets: %w: unknown position", errmgr.ErrPayload)
+		}
+	}
+
+	query := db.Model(&schema.TimeRule{}).Where("id <> ?", rule.ID)
+	if rule.LocationID != nil {
+		query = query.Where("location_id = ?", *rule.LocationID)
+	} else {
+		query = query.Where("location_id IS NULL")
+	}
+	if rule.PositionID != nil {
+		query = query.Where("position_id = ?", *rule.PositionID)
+	} else {
+		query = query.Where("position_id IS NULL")
+	}
+
+	var conflicts int64
+	err := query.Count(&conflicts).Error
+	if err != nil {
+		return fmt.Errorf("validateTimeRuleTargets: %w", err)
+	}
+	if conflicts > 0 {
+		return fmt.Errorf("validateTimeRuleTargets: %w", errmgr.ErrTimeRuleConflict)
+	}
+
+	return nil
+}
@@ -0,0 +1,123 @@
+package timekeeping
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/alsey89/people-matter/internal/schema"
+)
+
+const dateLayout = "2006-01-02"
+
+type TimesheetSummary struct {
+	UserID     uint             `json:"userId"`
+	From       string           `json:"from"`
+	To         string           `json:"to"`
+	Entries    []TimesheetEntry `json:"entries"`
+	TotalHours float64          `json:"totalHours"`
+	// True if the user is still clocked in within the period, the open log is not counted in TotalHours.
+	HasOpenLog bool `json:"hasOpenLog"`
+}
+
+// A time log clipped to the timesheet period.
+type TimesheetEntry struct {
+	TimeLogID  uint       `json:"timeLogId"`
+	LocationID *uint      `json:"locationId"`
+	PositionID *uint      `json:"positionId"`
+	ClockIn    time.Time  `json:"clockIn"`
+	ClockOut   *time.Time `json:"clockOut"`
+	Hours      float64    `json:"hours"`
+}
+
+/*
+Parses an inclusive from/to date range (YYYY-MM-DD, UTC) into a half open [start, end) period.
+Fails if the range is reversed or longer than maxDays.
+*/
+func parsePeriod(from string, to string, maxDays int) (start time.Time, end time.Time, err error) {
+	start, err = time.ParseInLocation(dateLayout, from, time.UTC)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("parsePeriod: invalid from date: %w", err)
+	}
+	toDate, err := time.ParseInLocation(dateLayout, to, time.UTC)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("parsePeriod: invalid to date: %w", err)
+	}
+	if toDate.Before(start) {
+		return time.Time{}, time.Time{}, fmt.Errorf("parsePeriod: %s", "to date is before from date")
+	}
+
+	end = toDate.AddDate(0, 0, 1)
+	if maxDays > 0 && end.Sub(start) > time.Duration(maxDays)*24*time.Hour {
+		return time.Time{}, time.Time{}, fmt.Errorf("parsePeriod: period is longer than %d days", maxDays)
+	}
+
+	return start, end, nil
+}
+
+/*
+Builds a timesheet summary from logs overlapping the [start, end) period, ordered by clock in.
+Logs crossing the period boundaries are clipped, so a night shift is split between the two days' timesheets.
+Open logs are listed but not counted, since their duration is not known yet.
+*/
+func buildTimesheetSummary(userID uint, logs []schema.TimeLog, start time.Time, end time.Time) TimesheetSummary {
+	summary := TimesheetSummary{
+		UserID:  userID,
+		From:    start.Format(dateLayout),
+		To:      end.AddDate(0, 0, -1).Format(dateLayout),
+		Entries: make([]TimesheetEntry, 0, len(logs)),
+	}
+
+	var total time.Duration
+	for _, log := range logs {
+		entry := TimesheetEntry{
+			TimeLogID:  log.ID,
+			LocationID: log.LocationID,
+			PositionID: log.PositionID,
+			ClockIn:    log.ClockIn,
+			ClockOut:   log.ClockOut,
+		}
+
+		if log.ClockOut == nil {
+			summary.HasOpenLog = true
+			summary.Entries = append(summary.Entries, entry)
+			continue
+		}
+
+		clockIn := log.ClockIn
+		if clockIn.Before(start) {
+			clockIn = start
+		}
+		clockOut := *log.ClockOut
+		if clockOut.After(end) {
+			clockOut = end
+		}
+		if !clockOut.After(clockIn) {
+			continue // outside the period
+		}
+
+		duration := clockOut.Sub(clockIn)
+		total += duration
+		entry.Hours = toHours(duration)
+		summary.Entries = append(summary.Entries, entry)
+	}
+
+	// rounded once from the exact total so that entries rounding does not accumulate
+	summary.TotalHours = toHours(total)
+
+	return summary
+}
+
+// Converts a duration to hours rounded to two decimals.
+func toHours(duration time.Duration) float64 {
+	return math.Round(duration.Hours()*100) / 100
+}
+
+// Filters for listing timesheets, zero values are ignored.
+type TimesheetFilter struct {
+	UserID     *uint
+	LocationID *uint // Users currently assigned to the location
+	Status     string
+	Start      *time.Time // Periods ending on or after this date
+	End        *time.Time // Periods starting before this date
+}
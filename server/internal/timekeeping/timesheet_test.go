@@ -0,0 +1,62 @@
+package timekeeping
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alsey89/people-matter/internal/schema"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePeriod(t *testing.T) {
+	start, end, err := parsePeriod("2024-03-01", "2024-03-07", 31)
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), start)
+	assert.Equal(t, time.Date(2024, 3, 8, 0, 0, 0, 0, time.UTC), end)
+
+	_, _, err = parsePeriod("2024-03-07", "2024-03-01", 31)
+	assert.Error(t, err, "reversed range")
+
+	_, _, err = parsePeriod("2024-01-01", "2024-12-31", 31)
+	assert.Error(t, err, "range too long")
+
+	_, _, err = parsePeriod("03/01/2024", "2024-03-07", 31)
+	assert.Error(t, err, "invalid date")
+}
+
+func TestBuildTimesheetSummary(t *testing.T) {
+	start := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	end := time.Date(2024, 3, 2, 0, 0, 0, 0, time.UTC)
+	at := func(day, hour, minute int) *time.Time {
+		tm := time.Date(2024, 3, day, hour, minute, 0, 0, time.UTC)
+		return &tm
+	}
+
+	logs := []schema.TimeLog{
+		{ClockIn: *at(1, 9, 0), ClockOut: at(1, 12, 30)},                                 // 3.5h
+		{ClockIn: *at(1, 22, 0), ClockOut: at(2, 6, 0)},                                  // clipped to 2h
+		{ClockIn: time.Date(2024, 2, 29, 22, 0, 0, 0, time.UTC), ClockOut: at(1, 1, 15)}, // clipped to 1.25h
+		{ClockIn: *at(2, 0, 0), ClockOut: at(2, 1, 0)},                                   // outside the period
+		{ClockIn: *at(1, 23, 0)},                                                         // open
+	}
+	for i := range logs {
+		logs[i].ID = uint(i + 1)
+	}
+
+	summary := buildTimesheetSummary(7, logs, start, end)
+
+	assert.Equal(t, uint(7), summary.UserID)
+	assert.Equal(t, "2024-03-01", summary.From)
+	assert.Equal(t, "2024-03-01", summary.To)
+	assert.True(t, summary.HasOpenLog)
+	assert.Equal(t, 6.75, summary.TotalHours)
+
+	require.Len(t, summary.Entries, 4)
+	assert.Equal(t, 3.5, summary.Entries[0].Hours)
+	assert.Equal(t, 2.0, summary.Entries[1].Hours)
+	assert.Equal(t, 1.25, summary.Entries[2].Hours)
+	assert.Equal(t, uint(5), summary.Entries[3].TimeLogID)
+	assert.Nil(t, summary.Entries[3].ClockOut)
+}
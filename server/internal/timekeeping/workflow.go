@@ -0,0 +1,48 @@
+package timekeeping
+
+import (
+	"fmt"
+
+	"github.com/alsey89/people-matter/internal/common/errmgr"
+	"github.com/alsey89/people-matter/internal/schema"
+)
+
+// Timesheet actions
+const (
+	actionSubmit  = "submit"
+	actionApprove = "approve"
+	actionReject  = "reject"
+	actionReopen  = "reopen"
+)
+
+// Allowed status transitions per action, from status -> to status.
+var timesheetTransitions = map[string]map[string]string{
+	actionSubmit: {
+		schema.TimesheetStatusDraft:    schema.TimesheetStatusSubmitted,
+		schema.TimesheetStatusRejected: schema.TimesheetStatusSubmitted,
+	},
+	actionApprove: {
+		schema.TimesheetStatusSubmitted: schema.TimesheetStatusApproved,
+	},
+	actionReject: {
+		schema.TimesheetStatusSubmitted: schema.TimesheetStatusRejected,
+	},
+	actionReopen: {
+		schema.TimesheetStatusApproved: schema.TimesheetStatusDraft,
+	},
+}
+
+// Returns the status a timesheet moves to when the action is applied, or ErrTimesheetStatus if it is not allowed.
+func nextTimesheetStatus(status string, action string) (string, error) {
+	next, ok := timesheetTransitions[action][status]
+	if !ok {
+		return "", fmt.Errorf("nextTimesheetStatus: %w: cannot %s a %s timesheet", errmgr.ErrTimesheetStatus, action, status)
+	}
+	return next, nil
+}
+
+// Statuses whose time logs cannot be edited.
+var lockedTimesheetStatuses = []string{schema.TimesheetStatusSubmitted, schema.TimesheetStatusApproved}
+
+// Statuses whose time logs can be edited by the owner.
+var editableTimesheetStatuses = []string{schema.TimesheetStatusDraft, schema.TimesheetStatusRejected}
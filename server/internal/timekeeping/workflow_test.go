@@ -0,0 +1,42 @@
+package timekeeping
+
+import (
+	"testing"
+
+	"github.com/alsey89/people-matter/internal/common/errmgr"
+	"github.com/alsey89/people-matter/internal/schema"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNextTimesheetStatus(t *testing.T) {
+	tests := []struct {
+		status string
+		action string
+		want   string
+	}{
+		{schema.TimesheetStatusDraft, actionSubmit, schema.TimesheetStatusSubmitted},
+		{schema.TimesheetStatusRejected, actionSubmit, schema.TimesheetStatusSubmitted},
+		{schema.TimesheetStatusSubmitted, actionApprove, schema.TimesheetStatusApproved},
+		{schema.TimesheetStatusSubmitted, actionReject, schema.TimesheetStatusRejected},
+		{schema.TimesheetStatusApproved, actionReopen, schema.TimesheetStatusDraft},
+
+		{schema.TimesheetStatusDraft, actionApprove, ""},
+		{schema.TimesheetStatusApproved, actionSubmit, ""},
+		{schema.TimesheetStatusApproved, actionReject, ""},
+		{schema.TimesheetStatusSubmitted, actionReopen, ""},
+		{schema.TimesheetStatusDraft, "delete", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.status+"/"+tt.action, func(t *testing.T) {
+			got, err := nextTimesheetStatus(tt.status, tt.action)
+			if tt.want == "" {
+				assert.ErrorIs(t, err, errmgr.ErrTimesheetStatus)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
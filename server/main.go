@@ -1,13 +1,29 @@
 package main
 
 import (
+	"context"
+	"log"
+	"os"
+
+	"github.com/alsey89/people-matter/internal/common/money"
+	"github.com/alsey89/people-matter/internal/common/rbac"
+	"github.com/alsey89/people-matter/internal/company"
+	"github.com/alsey89/people-matter/internal/compensation"
+	"github.com/alsey89/people-matter/internal/employee"
+	"github.com/alsey89/people-matter/internal/identity"
+	"github.com/alsey89/people-matter/internal/location"
+	"github.com/alsey89/people-matter/internal/onboarding"
+	"github.com/alsey89/people-matter/internal/payroll"
 	"github.com/alsey89/people-matter/internal/schema"
+	"github.com/alsey89/people-matter/internal/timekeeping"
 	"github.com/alsey89/people-matter/internal/transmail"
 	"github.com/alsey89/people-matter/pkg/config"
 	"github.com/alsey89/people-matter/pkg/logger"
 	"github.com/alsey89/people-matter/pkg/pgconn"
 	"github.com/alsey89/people-matter/pkg/server"
+	"github.com/alsey89/people-matter/pkg/token"
 
+	"github.com/spf13/viper"
 	"go.uber.org/fx"
 )
 
@@ -20,35 +36,84 @@ func init() {
 	config.SetUpConfig("SERVER", "yaml", "./")
 }
 
+// Models managed by the database, used for AutoMigrate and the schema drift check.
+var models = []interface{}{
+	schema.Adjustment{},
+	schema.Bonus{},
+	schema.Company{},
+	schema.Compensation{},
+	schema.Document{},
+	schema.ExchangeRate{},
+	schema.Expense{},
+	schema.Location{},
+	schema.PasswordReset{},
+	schema.Payment{},
+	schema.PayrollRun{},
+	schema.Permission{},
+	schema.Position{},
+	schema.PositionPermission{},
+	schema.User{},
+	schema.UserPosition{},
+	schema.TimeLog{},
+	schema.Timesheet{},
+	schema.TimesheetEvent{},
+	schema.TimeRule{},
+	schema.Invitation{},
+}
+
 func main() {
+	// server migrate up|down|status
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		err := runMigrateCommand(os.Args[2:])
+		if err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
 	app := fx.New(
 		//* Modules ---------------------------------------------------------------
 		logger.InjectModule("logger"),
 		pgconn.InjectModule("database"),
 		server.InjectModule("server"),
+		token.InjectModule("token", identity.AuthTokenScope, identity.EmailVerificationTokenScope, identity.PasswordResetTokenScope, employee.InvitationTokenScope),
 		//* Domains ---------------------------------------------------------------
 		transmail.InjectDomain("transmail"),
+		identity.InjectDomain("identity"),
+		timekeeping.InjectDomain("timekeeping"),
+		onboarding.InjectDomain("onboarding"),
+		company.InjectDomain("company"),
+		location.InjectDomain("location"),
+		employee.InjectDomain("employee"),
+		compensation.InjectDomain("compensation"),
+		payroll.InjectDomain("payroll"),
+		//* Callbacks -------------------------------------------------------------
+		// Fills the currency of money amounts from their currency column.
+		fx.Invoke(func(m *pgconn.Module) error {
+			return money.RegisterCallbacks(m.GetDB())
+		}),
 		//* Migration -------------------------------------------------------------
-		fx.Invoke(func(m *pgconn.Module) {
-			m.ApplySchema(
-				true,
-				schema.Adjustment{},
-				schema.Bonus{},
-				schema.Company{},
-				schema.Compensation{},
-				schema.Document{},
-				schema.Expense{},
-				schema.Location{},
-				schema.Payment{},
-				schema.Permission{},
-				schema.Position{},
-				schema.PositionPermission{},
-				schema.User{},
-				schema.UserPosition{},
-			)
+		// AutoMigrate is for development only, deployments run "server migrate up".
+		// Returning the error aborts startup.
+		fx.Invoke(func(m *pgconn.Module) error {
+			return m.ApplySchema(viper.GetBool("database.auto_migrate"), models...)
+		}),
+		// Seeds permissions added to the catalog into existing companies.
+		fx.Invoke(func(m *pgconn.Module) error {
+			return rbac.SeedAllCompanies(context.Background(), m)
+		}),
+		//* Health ----------------------------------------------------------------
+		fx.Invoke(func(s *server.Module, m *pgconn.Module) {
+			s.RegisterReadinessCheck("database", func(ctx context.Context) error {
+				return m.CheckReadiness(ctx, models...)
+			})
 		}),
 		//* fx logs ---------------------------------------------------------------
 		fx.NopLogger,
 	)
+	// fx errors are otherwise swallowed by the NopLogger
+	if err := app.Err(); err != nil {
+		log.Fatal(err)
+	}
 	app.Run()
 }
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/alsey89/people-matter/internal/schema"
+	"github.com/alsey89/people-matter/pkg/logger"
+	"github.com/alsey89/people-matter/pkg/pgconn"
+)
+
+const migrateUsage = `usage: server migrate <up|down|status> [flags]
+
+  up      apply all pending migrations
+  down    revert the most recently applied migrations (see -steps)
+  status  list migrations and whether they are applied
+
+flags:
+`
+
+// Runs the migrate subcommand against the "database" config scope.
+func runMigrateCommand(args []string) error {
+	flags := flag.NewFlagSet("migrate", flag.ContinueOnError)
+	dryRun := flags.Bool("dry-run", false, "print the SQL of the migrations that would run, without changing the database")
+	steps := flags.Int("steps", 1, "number of migrations to revert with down")
+	flags.Usage = func() {
+		fmt.Fprint(flags.Output(), migrateUsage)
+		flags.PrintDefaults()
+	}
+
+	if len(args) == 0 {
+		flags.Usage()
+		return fmt.Errorf("runMigrateCommand: missing action")
+	}
+	action := args[0]
+	if err := flags.Parse(args[1:]); err != nil {
+		return fmt.Errorf("runMigrateCommand: %w", err)
+	}
+
+	migrations, err := pgconn.LoadMigrations(schema.Migrations, schema.MigrationsDir)
+	if err != nil {
+		return fmt.Errorf("runMigrateCommand: %w", err)
+	}
+
+	db := pgconn.NewPGConn("database", logger.NewLogger())
+
+	switch action {
+	case "up":
+		applied, err := db.MigrateUp(migrations, *dryRun)
+		if err != nil {
+			return fmt.Errorf("runMigrateCommand: %w", err)
+		}
+		printMigrations(applied, "up", *dryRun)
+	case "down":
+		reverted, err := db.MigrateDown(migrations, *steps, *dryRun)
+		if err != nil {
+			return fmt.Errorf("runMigrateCommand: %w", err)
+		}
+		printMigrations(reverted, "down", *dryRun)
+	case "status":
+		statuses, err := db.MigrationStatus(migrations)
+		if err != nil {
+			return fmt.Errorf("runMigrateCommand: %w", err)
+		}
+		printMigrationStatus(statuses)
+	default:
+		flags.Usage()
+		return fmt.Errorf("runMigrateCommand: unknown action %s", action)
+	}
+
+	return nil
+}
+
+func printMigrations(migrations []pgconn.Migration, direction string, dryRun bool) {
+	if len(migrations) == 0 {
+		fmt.Println("Nothing to migrate.")
+		return
+	}
+
+	for _, migration := range migrations {
+		if !dryRun {
+			fmt.Printf("%s %04d_%s\n", direction, migration.Version, migration.Name)
+			continue
+		}
+
+		fmt.Printf("-- %04d_%s.%s.sql\n", migration.Version, migration.Name, direction)
+		if direction == "up" {
+			fmt.Println(migration.Up)
+		} else {
+			fmt.Println(migration.Down)
+		}
+	}
+}
+
+func printMigrationStatus(statuses []pgconn.MigrationStatus) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tNAME\tSTATUS")
+	for _, status := range statuses {
+		state := "pending"
+		if status.AppliedAt != nil {
+			state = "applied " + status.AppliedAt.Format("2006-01-02 15:04:05")
+		}
+		if status.Missing {
+			state += " (no migration file)"
+		}
+		fmt.Fprintf(w, "%04d\t%s\t%s\n", status.Version, status.Name, state)
+	}
+	w.Flush()
+}
@@ -0,0 +1,157 @@
+package pgconn
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// Sentinel errors
+var (
+	ErrSchemaDrift = errors.New("schema drift detected")
+)
+
+// SchemaDrift lists the objects the registered models expect but the live database lacks.
+// Columns and indexes are reported as "table.name".
+type SchemaDrift struct {
+	MissingTables  []string `json:"missingTables"`
+	MissingColumns []string `json:"missingColumns"`
+	MissingIndexes []string `json:"missingIndexes"`
+}
+
+func (d *SchemaDrift) HasDrift() bool {
+	return len(d.MissingTables) > 0 || len(d.MissingColumns) > 0 || len(d.MissingIndexes) > 0
+}
+
+func (d *SchemaDrift) String() string {
+	var parts []string
+	if len(d.MissingTables) > 0 {
+		parts = append(parts, "missing tables: "+strings.Join(d.MissingTables, ", "))
+	}
+	if len(d.MissingColumns) > 0 {
+		parts = append(parts, "missing columns: "+strings.Join(d.MissingColumns, ", "))
+	}
+	if len(d.MissingIndexes) > 0 {
+		parts = append(parts, "missing indexes: "+strings.Join(d.MissingIndexes, ", "))
+	}
+	return strings.Join(parts, "; ")
+}
+
+/*
+Compares the live database with the passed in models and reports missing tables, columns and indexes.
+Extra objects in the database are not reported, so columns kept for backwards compatibility are fine.
+Uses two catalog queries regardless of the number of models, so it is cheap enough for readiness probes.
+*/
+func (m *Module) CheckSchemaDrift(ctx context.Context, models ...interface{}) (*SchemaDrift, error) {
+	db := m.db.WithContext(ctx)
+
+	var columnRows []struct {
+		TableName  string
+		ColumnName string
+	}
+	err := db.Raw(`
+		SELECT table_name, column_name
+		FROM information_schema.columns
+		WHERE table_schema = current_schema()
+	`).Scan(&columnRows).Error
+	if err != nil {
+		return nil, fmt.Errorf("CheckSchemaDrift: %w", err)
+	}
+
+	var indexRows []struct {
+		Tablename string
+		Indexname string
+	}
+	err = db.Raw(`
+		SELECT tablename, indexname
+		FROM pg_indexes
+		WHERE schemaname = current_schema()
+	`).Scan(&indexRows).Error
+	if err != nil {
+		return nil, fmt.Errorf("CheckSchemaDrift: %w", err)
+	}
+
+	tables := make(map[string]bool)
+	columns := make(map[string]bool)
+	for _, row := range columnRows {
+		tables[row.TableName] = true
+		columns[row.TableName+"."+row.ColumnName] = true
+	}
+	indexes := make(map[string]bool)
+	for _, row := range indexRows {
+		indexes[row.Tablename+"."+row.Indexname] = true
+	}
+
+	drift, err := diffSchema(m.db, models, tables, columns, indexes)
+	if err != nil {
+		return nil, fmt.Errorf("CheckSchemaDrift: %w", err)
+	}
+
+	return drift, nil
+}
+
+// Compares the models with the existing tables, "table.column" columns and "table.index" indexes.
+func diffSchema(db *gorm.DB, models []interface{}, tables map[string]bool, columns map[string]bool, indexes map[string]bool) (*SchemaDrift, error) {
+	drift := &SchemaDrift{}
+	for _, model := range models {
+		stmt := &gorm.Statement{DB: db}
+		err := stmt.Parse(model)
+		if err != nil {
+			return nil, fmt.Errorf("diffSchema: %w", err)
+		}
+		table := stmt.Schema.Table
+
+		if !tables[table] {
+			drift.MissingTables = append(drift.MissingTables, table)
+			continue
+		}
+
+		for _, field := range stmt.Schema.Fields {
+			if field.DBName == "" || field.IgnoreMigration {
+				continue
+			}
+			if !columns[table+"."+field.DBName] {
+				drift.MissingColumns = append(drift.MissingColumns, table+"."+field.DBName)
+			}
+		}
+
+		for _, index := range stmt.Schema.ParseIndexes() {
+			if !indexes[table+"."+index.Name] {
+				drift.MissingIndexes = append(drift.MissingIndexes, table+"."+index.Name)
+			}
+		}
+	}
+
+	sort.Strings(drift.MissingTables)
+	sort.Strings(drift.MissingColumns)
+	sort.Strings(drift.MissingIndexes)
+
+	return drift, nil
+}
+
+// Returns nil if the database is reachable and matches the passed in models.
+// Intended as a readiness check, wraps ErrSchemaDrift with the drift details otherwise.
+func (m *Module) CheckReadiness(ctx context.Context, models ...interface{}) error {
+	sqlDB, err := m.db.DB()
+	if err != nil {
+		return fmt.Errorf("CheckReadiness: %w", err)
+	}
+	err = sqlDB.PingContext(ctx)
+	if err != nil {
+		return fmt.Errorf("CheckReadiness: %w", err)
+	}
+
+	drift, err := m.CheckSchemaDrift(ctx, models...)
+	if err != nil {
+		return fmt.Errorf("CheckReadiness: %w", err)
+	}
+	if drift.HasDrift() {
+		return fmt.Errorf("CheckReadiness: %w: %s", ErrSchemaDrift, drift.String())
+	}
+
+	return nil
+}
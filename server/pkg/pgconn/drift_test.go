@@ -0,0 +1,49 @@
+package pgconn
+
+import (
+	"testing"
+
+	"github.com/alsey89/people-matter/pkg/pgconn/pgtest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type driftRecord struct {
+	ID        uint
+	CompanyID uint   `gorm:"index"`
+	Email     string `gorm:"uniqueIndex:idx_company_email"`
+	Name      string
+	Ignored   string `gorm:"-:migration"`
+}
+
+func TestDiffSchema(t *testing.T) {
+	db := pgtest.NewDryRunDB(t)
+	models := []interface{}{&driftRecord{}, &unscopedRecord{}}
+
+	t.Run("NoDrift", func(t *testing.T) {
+		drift, err := diffSchema(db, models,
+			map[string]bool{"drift_records": true, "unscoped_records": true},
+			map[string]bool{
+				"drift_records.id": true, "drift_records.company_id": true, "drift_records.email": true, "drift_records.name": true,
+				"unscoped_records.id": true, "unscoped_records.name": true,
+			},
+			map[string]bool{"drift_records.idx_drift_records_company_id": true, "drift_records.idx_company_email": true},
+		)
+		require.NoError(t, err)
+		assert.False(t, drift.HasDrift())
+	})
+
+	t.Run("ReportsMissingObjects", func(t *testing.T) {
+		drift, err := diffSchema(db, models,
+			map[string]bool{"drift_records": true},
+			map[string]bool{"drift_records.id": true, "drift_records.company_id": true, "drift_records.email": true},
+			map[string]bool{"drift_records.idx_drift_records_company_id": true},
+		)
+		require.NoError(t, err)
+		assert.True(t, drift.HasDrift())
+		assert.Equal(t, []string{"unscoped_records"}, drift.MissingTables)
+		assert.Equal(t, []string{"drift_records.name"}, drift.MissingColumns)
+		assert.Equal(t, []string{"drift_records.idx_company_email"}, drift.MissingIndexes)
+	})
+}
@@ -0,0 +1,39 @@
+package pgconn
+
+import (
+	"errors"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// Postgres error codes, see https://www.postgresql.org/docs/current/errcodes-appendix.html
+const (
+	uniqueViolationCode = "23505"
+	checkViolationCode  = "23514"
+)
+
+// Reports whether err is a unique constraint violation, optionally on a specific constraint or index.
+// Pass an empty constraint to match any unique violation.
+func IsUniqueViolation(err error, constraint string) bool {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return false
+	}
+	if pgErr.Code != uniqueViolationCode {
+		return false
+	}
+	return constraint == "" || pgErr.ConstraintName == constraint
+}
+
+// Reports whether err is a check violation, optionally of a specific constraint.
+// Triggers raising check_violation can name the constraint with USING CONSTRAINT.
+func IsCheckViolation(err error, constraint string) bool {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return false
+	}
+	if pgErr.Code != checkViolationCode {
+		return false
+	}
+	return constraint == "" || pgErr.ConstraintName == constraint
+}
@@ -0,0 +1,351 @@
+package pgconn
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// Sentinel errors
+var (
+	ErrIrreversibleMigration = errors.New("migration has no down script")
+)
+
+// Key of the session level advisory lock held while migrating,
+// so that replicas starting at the same time do not migrate concurrently.
+const migrationLockKey int64 = 0x706d5f6d6967 // "pm_mig"
+
+const migrationsTable = "schema_migrations"
+
+// Migration is a versioned pair of SQL scripts.
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+// MigrationStatus reports whether a migration has been applied.
+// Missing is true for versions recorded in the database without a matching migration file.
+type MigrationStatus struct {
+	Version   int64
+	Name      string
+	AppliedAt *time.Time
+	Missing   bool
+}
+
+type appliedMigration struct {
+	Version   int64
+	Name      string
+	AppliedAt time.Time
+}
+
+/*
+Loads migrations from dir in fsys, ordered by version.
+Files are named <version>_<name>.up.sql and <version>_<name>.down.sql, e.g. 0001_baseline.up.sql.
+Every version needs an up script, the down script is optional.
+*/
+func LoadMigrations(fsys fs.FS, dir string) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, fmt.Errorf("LoadMigrations: %w", err)
+	}
+
+	byVersion := make(map[int64]*Migration)
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".sql") {
+			continue
+		}
+
+		version, name, direction, err := parseMigrationFilename(entry.Name())
+		if err != nil {
+			return nil, fmt.Errorf("LoadMigrations: %w", err)
+		}
+
+		content, err := fs.ReadFile(fsys, path.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("LoadMigrations: %w", err)
+		}
+
+		migration, exists := byVersion[version]
+		if !exists {
+			migration = &Migration{Version: version, Name: name}
+			byVersion[version] = migration
+		}
+		if migration.Name != name {
+			return nil, fmt.Errorf("LoadMigrations: version %d has conflicting names %s and %s", version, migration.Name, name)
+		}
+
+		switch direction {
+		case "up":
+			migration.Up = string(content)
+		case "down":
+			migration.Down = string(content)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if strings.TrimSpace(migration.Up) == "" {
+			return nil, fmt.Errorf("LoadMigrations: version %d has no up script", migration.Version)
+		}
+		migrations = append(migrations, *migration)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+
+	return migrations, nil
+}
+
+func parseMigrationFilename(filename string) (version int64, name string, direction string, err error) {
+	base := strings.TrimSuffix(filename, ".sql")
+
+	switch {
+	case strings.HasSuffix(base, ".up"):
+		direction = "up"
+	case strings.HasSuffix(base, ".down"):
+		direction = "down"
+	default:
+		return 0, "", "", fmt.Errorf("parseMigrationFilename: %s must end in .up.sql or .down.sql", filename)
+	}
+	base = strings.TrimSuffix(base, "."+direction)
+
+	versionPart, name, found := strings.Cut(base, "_")
+	if !found || name == "" {
+		return 0, "", "", fmt.Errorf("parseMigrationFilename: %s must be named <version>_<name>", filename)
+	}
+
+	version, err = strconv.ParseInt(versionPart, 10, 64)
+	if err != nil || version <= 0 {
+		return 0, "", "", fmt.Errorf("parseMigrationFilename: %s has an invalid version", filename)
+	}
+
+	return version, name, direction, nil
+}
+
+//! EXTERNAL ---------------------------------------------------------------
+
+// Returns the status of every migration, followed by applied versions that have no migration file.
+func (m *Module) MigrationStatus(migrations []Migration) ([]MigrationStatus, error) {
+	applied, err := m.appliedMigrations(m.db)
+	if err != nil {
+		return nil, fmt.Errorf("MigrationStatus: %w", err)
+	}
+
+	statuses := make([]MigrationStatus, 0, len(migrations))
+	known := make(map[int64]bool)
+	for _, migration := range migrations {
+		status := MigrationStatus{Version: migration.Version, Name: migration.Name}
+		if record, ok := applied[migration.Version]; ok {
+			status.AppliedAt = &record.AppliedAt
+		}
+		statuses = append(statuses, status)
+		known[migration.Version] = true
+	}
+
+	for version, record := range applied {
+		if known[version] {
+			continue
+		}
+		appliedAt := record.AppliedAt
+		statuses = append(statuses, MigrationStatus{Version: version, Name: record.Name, AppliedAt: &appliedAt, Missing: true})
+	}
+	sort.SliceStable(statuses, func(i, j int) bool { return statuses[i].Version < statuses[j].Version })
+
+	return statuses, nil
+}
+
+/*
+Applies all pending migrations in version order, each in its own transaction.
+Returns the migrations that were applied, or that would be applied if dryRun is true.
+A dry run only reads the migrations table and never changes the database.
+If row_level_security is enabled, company isolation policies are reapplied afterwards.
+*/
+func (m *Module) MigrateUp(migrations []Migration, dryRun bool) ([]Migration, error) {
+	if dryRun {
+		applied, err := m.appliedMigrations(m.db)
+		if err != nil {
+			return nil, fmt.Errorf("MigrateUp: %w", err)
+		}
+		return pendingMigrations(migrations, applied), nil
+	}
+
+	var pending []Migration
+	err := m.withMigrationLock(func(conn *gorm.DB) error {
+		applied, err := m.appliedMigrations(conn)
+		if err != nil {
+			return err
+		}
+
+		pending = pendingMigrations(migrations, applied)
+		for _, migration := range pending {
+			m.logger.Info("Applying migration.", zap.Int64("version", migration.Version), zap.String("name", migration.Name))
+
+			err := conn.Transaction(func(tx *gorm.DB) error {
+				if err := tx.Exec(migration.Up).Error; err != nil {
+					return err
+				}
+				return tx.Table(migrationsTable).Create(&appliedMigration{
+					Version:   migration.Version,
+					Name:      migration.Name,
+					AppliedAt: time.Now(),
+				}).Error
+			})
+			if err != nil {
+				return fmt.Errorf("version %d (%s): %w", migration.Version, migration.Name, err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("MigrateUp: %w", err)
+	}
+
+	if m.config.RowLevelSecurity {
+		err = m.applyRowLevelSecurity()
+		if err != nil {
+			return pending, fmt.Errorf("MigrateUp: %w", err)
+		}
+	}
+
+	return pending, nil
+}
+
+/*
+Reverts the last steps applied migrations in reverse version order, each in its own transaction.
+Returns the migrations that were reverted, or that would be reverted if dryRun is true.
+Fails with ErrIrreversibleMigration before reverting anything if one of them has no down script.
+*/
+func (m *Module) MigrateDown(migrations []Migration, steps int, dryRun bool) ([]Migration, error) {
+	if steps <= 0 {
+		return nil, fmt.Errorf("MigrateDown: steps must be positive, got %d", steps)
+	}
+
+	if dryRun {
+		applied, err := m.appliedMigrations(m.db)
+		if err != nil {
+			return nil, fmt.Errorf("MigrateDown: %w", err)
+		}
+		revertible, err := revertibleMigrations(migrations, applied, steps)
+		if err != nil {
+			return nil, fmt.Errorf("MigrateDown: %w", err)
+		}
+		return revertible, nil
+	}
+
+	var revertible []Migration
+	err := m.withMigrationLock(func(conn *gorm.DB) error {
+		applied, err := m.appliedMigrations(conn)
+		if err != nil {
+			return err
+		}
+
+		revertible, err = revertibleMigrations(migrations, applied, steps)
+		if err != nil {
+			return err
+		}
+
+		for _, migration := range revertible {
+			m.logger.Info("Reverting migration.", zap.Int64("version", migration.Version), zap.String("name", migration.Name))
+
+			err := conn.Transaction(func(tx *gorm.DB) error {
+				if err := tx.Exec(migration.Down).Error; err != nil {
+					return err
+				}
+				return tx.Exec("DELETE FROM "+migrationsTable+" WHERE version = ?", migration.Version).Error
+			})
+			if err != nil {
+				return fmt.Errorf("version %d (%s): %w", migration.Version, migration.Name, err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("MigrateDown: %w", err)
+	}
+
+	return revertible, nil
+}
+
+//! INTERNAL ---------------------------------------------------------------
+
+// Runs fn on a single pinned connection holding the migration advisory lock.
+// Blocks until any other migrating process releases the lock.
+func (m *Module) withMigrationLock(fn func(conn *gorm.DB) error) error {
+	return m.db.Connection(func(conn *gorm.DB) error {
+		m.logger.Info("Acquiring migration lock.")
+		err := conn.Exec("SELECT pg_advisory_lock(?)", migrationLockKey).Error
+		if err != nil {
+			return fmt.Errorf("withMigrationLock: %w", err)
+		}
+		defer func() {
+			err := conn.Exec("SELECT pg_advisory_unlock(?)", migrationLockKey).Error
+			if err != nil {
+				m.logger.Error("Error releasing migration lock.", zap.Error(err))
+			}
+		}()
+
+		err = conn.Exec(`CREATE TABLE IF NOT EXISTS ` + migrationsTable + ` (
+			version    bigint      PRIMARY KEY,
+			name       text        NOT NULL,
+			applied_at timestamptz NOT NULL DEFAULT now()
+		)`).Error
+		if err != nil {
+			return fmt.Errorf("withMigrationLock: %w", err)
+		}
+
+		return fn(conn)
+	})
+}
+
+// Returns applied migrations by version. A missing migrations table means nothing is applied.
+func (m *Module) appliedMigrations(db *gorm.DB) (map[int64]appliedMigration, error) {
+	applied := make(map[int64]appliedMigration)
+
+	if !db.Migrator().HasTable(migrationsTable) {
+		return applied, nil
+	}
+
+	var records []appliedMigration
+	err := db.Table(migrationsTable).Order("version").Find(&records).Error
+	if err != nil {
+		return nil, fmt.Errorf("appliedMigrations: %w", err)
+	}
+	for _, record := range records {
+		applied[record.Version] = record
+	}
+
+	return applied, nil
+}
+
+func pendingMigrations(migrations []Migration, applied map[int64]appliedMigration) []Migration {
+	var pending []Migration
+	for _, migration := range migrations {
+		if _, ok := applied[migration.Version]; !ok {
+			pending = append(pending, migration)
+		}
+	}
+	return pending
+}
+
+func revertibleMigrations(migrations []Migration, applied map[int64]appliedMigration, steps int) ([]Migration, error) {
+	var revertible []Migration
+	for i := len(migrations) - 1; i >= 0 && len(revertible) < steps; i-- {
+		migration := migrations[i]
+		if _, ok := applied[migration.Version]; !ok {
+			continue
+		}
+		if strings.TrimSpace(migration.Down) == "" {
+			return nil, fmt.Errorf("revertibleMigrations: version %d (%s): %w", migration.Version, migration.Name, ErrIrreversibleMigration)
+		}
+		revertible = append(revertible, migration)
+	}
+	return revertible, nil
+}
@@ -0,0 +1,82 @@
+package pgconn
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadMigrations(t *testing.T) {
+	t.Run("OrdersByVersion", func(t *testing.T) {
+		fsys := fstest.MapFS{
+			"migrations/0010_add_time_logs.up.sql":   {Data: []byte("CREATE TABLE time_logs ();")},
+			"migrations/0002_add_users.up.sql":       {Data: []byte("CREATE TABLE users ();")},
+			"migrations/0002_add_users.down.sql":     {Data: []byte("DROP TABLE users;")},
+			"migrations/0001_baseline.up.sql":        {Data: []byte("CREATE TABLE companies ();")},
+			"migrations/README.md":                   {Data: []byte("ignored")},
+			"migrations/0010_add_time_logs.down.sql": {Data: []byte("DROP TABLE time_logs;")},
+		}
+
+		migrations, err := LoadMigrations(fsys, "migrations")
+		require.NoError(t, err)
+		require.Len(t, migrations, 3)
+
+		assert.Equal(t, int64(1), migrations[0].Version)
+		assert.Equal(t, "baseline", migrations[0].Name)
+		assert.Empty(t, migrations[0].Down)
+
+		assert.Equal(t, int64(2), migrations[1].Version)
+		assert.Equal(t, "add_users", migrations[1].Name)
+		assert.Equal(t, "DROP TABLE users;", migrations[1].Down)
+
+		assert.Equal(t, int64(10), migrations[2].Version)
+	})
+
+	t.Run("RejectsMissingUpScript", func(t *testing.T) {
+		fsys := fstest.MapFS{
+			"migrations/0001_baseline.down.sql": {Data: []byte("DROP TABLE companies;")},
+		}
+		_, err := LoadMigrations(fsys, "migrations")
+		assert.Error(t, err)
+	})
+
+	t.Run("RejectsConflictingNames", func(t *testing.T) {
+		fsys := fstest.MapFS{
+			"migrations/0001_baseline.up.sql": {Data: []byte("CREATE TABLE companies ();")},
+			"migrations/0001_other.down.sql":  {Data: []byte("DROP TABLE companies;")},
+		}
+		_, err := LoadMigrations(fsys, "migrations")
+		assert.Error(t, err)
+	})
+
+	t.Run("RejectsMalformedNames", func(t *testing.T) {
+		for _, name := range []string{"baseline.up.sql", "0001_baseline.sql", "0000_zero.up.sql", "v1_baseline.up.sql"} {
+			fsys := fstest.MapFS{"migrations/" + name: {Data: []byte("SELECT 1;")}}
+			_, err := LoadMigrations(fsys, "migrations")
+			assert.Error(t, err, name)
+		}
+	})
+}
+
+func TestRevertibleMigrations(t *testing.T) {
+	migrations := []Migration{
+		{Version: 1, Name: "baseline", Up: "up", Down: ""},
+		{Version: 2, Name: "second", Up: "up", Down: "down"},
+		{Version: 3, Name: "third", Up: "up", Down: "down"},
+	}
+	applied := map[int64]appliedMigration{1: {Version: 1}, 2: {Version: 2}}
+
+	revertible, err := revertibleMigrations(migrations, applied, 1)
+	require.NoError(t, err)
+	require.Len(t, revertible, 1)
+	assert.Equal(t, int64(2), revertible[0].Version)
+
+	_, err = revertibleMigrations(migrations, applied, 2)
+	assert.ErrorIs(t, err, ErrIrreversibleMigration)
+
+	pending := pendingMigrations(migrations, applied)
+	require.Len(t, pending, 1)
+	assert.Equal(t, int64(3), pending[0].Version)
+}
@@ -37,6 +37,8 @@ type Config struct {
 	Port     int
 	SSLMode  string
 	User     string
+
+	RowLevelSecurity bool
 }
 
 const (
@@ -47,6 +49,8 @@ const (
 	DefaultPassword = "postgres"
 	DefaultSSLMode  = "allow"
 	DefaultLogLevel = "info"
+
+	DefaultRowLevelSecurity = false
 )
 
 //! Module ---------------------------------------------------------------
@@ -98,6 +102,7 @@ func (m *Module) setupConfig(scope string) *Config {
 	viper.SetDefault(util.GetConfigPath(scope, "password"), DefaultPassword)
 	viper.SetDefault(util.GetConfigPath(scope, "sslmode"), DefaultSSLMode)
 	viper.SetDefault(util.GetConfigPath("global", "log_level"), DefaultLogLevel)
+	viper.SetDefault(util.GetConfigPath(scope, "row_level_security"), DefaultRowLevelSecurity)
 
 	return &Config{
 		Host:     viper.GetString(util.GetConfigPath(scope, "host")),
@@ -107,6 +112,8 @@ func (m *Module) setupConfig(scope string) *Config {
 		Password: viper.GetString(util.GetConfigPath(scope, "password")),
 		SSLMode:  viper.GetString(util.GetConfigPath(scope, "sslmode")),
 		LogLevel: viper.GetString(util.GetConfigPath("global", "log_level")),
+
+		RowLevelSecurity: viper.GetBool(util.GetConfigPath(scope, "row_level_security")),
 	}
 }
 
@@ -125,6 +132,11 @@ func (m *Module) setUpDB() *gorm.DB {
 		m.logger.Fatal("Error connecting to database", zap.Error(err))
 	}
 
+	err = registerTenantCallbacks(db)
+	if err != nil {
+		m.logger.Fatal("Error registering tenant callbacks", zap.Error(err))
+	}
+
 	return db
 }
 
@@ -183,6 +195,7 @@ func (m *Module) logConfigurations() {
 	m.logger.Debug("User", zap.String("user", m.config.User))
 	m.logger.Debug("SSLMode", zap.String("sslmode", m.config.SSLMode))
 	m.logger.Debug("LogLevel", zap.String("log_level", m.config.LogLevel))
+	m.logger.Debug("RowLevelSecurity", zap.Bool("row_level_security", m.config.RowLevelSecurity))
 }
 
 //! EXTERNAL ---------------------------------------------------------------
@@ -190,21 +203,67 @@ func (m *Module) logConfigurations() {
 // Applies the passed in schema.
 // If autoMigrate is true, it will automatically migrate the schema at startup.
 // If autoMigrate is false, it will skip the migration.
-func (m *Module) ApplySchema(autoMigrate bool, schema ...interface{}) {
+// If row_level_security is enabled, company isolation policies are applied after migrating.
+// Returns an error if migrating fails, so that startup can be aborted instead of serving
+// against a half-migrated database. Schema drift is only logged, see CheckReadiness.
+func (m *Module) ApplySchema(autoMigrate bool, schema ...interface{}) error {
 	if !autoMigrate {
 		m.logger.Info("Skipping auto migration.")
-		return
+	} else {
+		m.logger.Info("Migration started.")
+		err := m.db.AutoMigrate(schema...)
+		if err != nil {
+			m.logger.Error("Error with migration.", zap.Error(err))
+			return fmt.Errorf("ApplySchema: %w", err)
+		}
+
+		if m.config.RowLevelSecurity {
+			err = m.applyRowLevelSecurity()
+			if err != nil {
+				m.logger.Error("Error applying row level security.", zap.Error(err))
+				return fmt.Errorf("ApplySchema: %w", err)
+			}
+		}
+
+		m.logger.Info("Migration completed.")
 	}
-	m.logger.Info("Migration started.")
-	err := m.db.AutoMigrate(schema...)
+
+	drift, err := m.CheckSchemaDrift(context.Background(), schema...)
 	if err != nil {
-		m.logger.Error("Error with migration.", zap.Error(err))
+		return fmt.Errorf("ApplySchema: %w", err)
+	}
+	if drift.HasDrift() {
+		m.logger.Warn("Database schema does not match the models.", zap.String("drift", drift.String()))
 	}
 
-	m.logger.Info("Migration completed.")
+	return nil
 }
 
-// Returns the GORM DB instance
+// Returns the GORM DB instance.
+// The instance is not scoped to a company, use GetScopedDB for tenant data.
 func (m *Module) GetDB() *gorm.DB {
 	return m.db
 }
+
+// Returns a GORM DB instance that is not scoped to any company.
+// Joins the transaction started by WithTransaction or WithCompanyTransaction if ctx carries one.
+func (m *Module) GetUnscopedDB(ctx context.Context) *gorm.DB {
+	if tx, ok := transactionFromContext(ctx); ok {
+		return tx.WithContext(ctx)
+	}
+	return m.db.WithContext(ctx)
+}
+
+// Returns a GORM DB instance scoped to the company carried by ctx (see WithCompanyID).
+// Queries, updates and deletes on company-owned models are filtered by company_id and
+// creates have CompanyID assigned. Fails closed with ErrNoCompanyInContext if ctx carries no company.
+// Joins the transaction started by WithCompanyTransaction if ctx carries one.
+func (m *Module) GetScopedDB(ctx context.Context) (*gorm.DB, error) {
+	if _, ok := CompanyIDFromContext(ctx); !ok {
+		return nil, fmt.Errorf("GetScopedDB: %w", ErrNoCompanyInContext)
+	}
+	if tx, ok := transactionFromContext(ctx); ok {
+		return tx.WithContext(ctx), nil
+	}
+	return m.db.WithContext(ctx), nil
+}
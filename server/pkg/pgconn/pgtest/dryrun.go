@@ -0,0 +1,67 @@
+// Package pgtest holds helpers for tests running GORM statements against postgres, or rendering them without it.
+package pgtest
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+// Opens a dry run DB that renders SQL without connecting to postgres.
+func NewDryRunDB(t testing.TB) *gorm.DB {
+	t.Helper()
+
+	db, err := gorm.Open(postgres.New(postgres.Config{DSN: "host=localhost"}), &gorm.Config{
+		DryRun:                 true,
+		SkipDefaultTransaction: true,
+		DisableAutomaticPing:   true,
+	})
+	require.NoError(t, err)
+	return db
+}
+
+/*
+Returns the SQL of the statement run on tx with its values inlined, e.g. users.id = 7 rather than users.id = $8,
+so that tests assert on the conditions rather than on how GORM numbers placeholders.
+e.g. pgtest.SQL(db.Where("id = ?", 7).Find(&users))
+*/
+func SQL(tx *gorm.DB) string {
+	return tx.Dialector.Explain(tx.Statement.SQL.String(), tx.Statement.Vars...)
+}
+
+// SQL of the statements run on a DB, recorded by Capture.
+type Statements struct {
+	sql []string
+}
+
+// Returns the SQL of the last statement run, "" if there was none.
+func (s *Statements) Last() string {
+	if len(s.sql) == 0 {
+		return ""
+	}
+	return s.sql[len(s.sql)-1]
+}
+
+/*
+Records the SQL of every statement run on db from now on, see SQL. Useful for functions that run their
+statements internally. Must be called once per DB.
+*/
+func Capture(t testing.TB, db *gorm.DB) *Statements {
+	t.Helper()
+
+	statements := &Statements{}
+	record := func(tx *gorm.DB) {
+		statements.sql = append(statements.sql, SQL(tx))
+	}
+
+	callbacks := db.Callback()
+	require.NoError(t, callbacks.Create().After("gorm:create").Register("pgtest:capture", record))
+	require.NoError(t, callbacks.Query().After("gorm:query").Register("pgtest:capture", record))
+	require.NoError(t, callbacks.Update().After("gorm:update").Register("pgtest:capture", record))
+	require.NoError(t, callbacks.Delete().After("gorm:delete").Register("pgtest:capture", record))
+	require.NoError(t, callbacks.Row().After("gorm:row").Register("pgtest:capture", record))
+	require.NoError(t, callbacks.Raw().After("gorm:raw").Register("pgtest:capture", record))
+	return statements
+}
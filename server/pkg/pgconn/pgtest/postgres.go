@@ -0,0 +1,40 @@
+//go:build integration
+
+package pgtest
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	embeddedpostgres "github.com/fergusstrange/embedded-postgres"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/require"
+)
+
+const postgresPassword = "postgres"
+
+/*
+Starts an ephemeral postgres binary downloaded by embedded-postgres on the port, stopped when the test ends,
+and points the config scope at it, so that pgconn.NewPGConn(scope, logger) connects to it as the superuser.
+Tests running in parallel packages need different ports. Postgres refuses to start as root, so run as a regular user.
+*/
+func StartPostgres(t *testing.T, port uint32, scope string) {
+	t.Helper()
+
+	runtimePath := filepath.Join(os.TempDir(), "people-matter-embedded-postgres-"+scope)
+	pg := embeddedpostgres.NewDatabase(embeddedpostgres.DefaultConfig().
+		Port(port).
+		Password(postgresPassword).
+		RuntimePath(runtimePath))
+	require.NoError(t, pg.Start())
+	t.Cleanup(func() { _ = pg.Stop() })
+
+	viper.Set(scope+".host", "localhost")
+	viper.Set(scope+".port", port)
+	viper.Set(scope+".user", "postgres")
+	viper.Set(scope+".password", postgresPassword)
+	viper.Set(scope+".dbname", "postgres")
+	viper.Set(scope+".sslmode", "disable")
+	t.Cleanup(viper.Reset)
+}
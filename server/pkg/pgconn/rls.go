@@ -0,0 +1,171 @@
+package pgconn
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Session setting read by the row level security policies.
+// It is set with SET LOCAL semantics, so it never outlives the transaction.
+const (
+	companySetting    = "app.company_id"
+	companyPolicyName = "company_isolation"
+)
+
+type (
+	txKey          struct{}
+	afterCommitKey struct{}
+)
+
+/*
+Enables row level security on every table in the current schema that has a company_id column,
+and (re)creates a policy that only exposes rows of the company in the app.company_id setting.
+FORCE is used so that the policies also apply to the table owner, which is usually the
+connecting role. Superusers and roles with BYPASSRLS are never subject to policies, so the
+application must connect as a regular role for this to take effect.
+Statements outside WithCompanyTransaction see no company rows at all.
+*/
+func (m *Module) applyRowLevelSecurity() error {
+	var tables []string
+	err := m.db.Raw(`
+		SELECT c.table_name
+		FROM information_schema.columns c
+		JOIN information_schema.tables t
+			ON t.table_schema = c.table_schema AND t.table_name = c.table_name
+		WHERE c.table_schema = current_schema()
+			AND c.column_name = 'company_id'
+			AND t.table_type = 'BASE TABLE'
+		ORDER BY c.table_name
+	`).Scan(&tables).Error
+	if err != nil {
+		return fmt.Errorf("applyRowLevelSecurity: %w", err)
+	}
+
+	policy := fmt.Sprintf(
+		"company_id = NULLIF(current_setting('%s', true), '')::bigint",
+		companySetting,
+	)
+
+	return m.db.Transaction(func(tx *gorm.DB) error {
+		for _, table := range tables {
+			statements := []string{
+				"ALTER TABLE ? ENABLE ROW LEVEL SECURITY",
+				"ALTER TABLE ? FORCE ROW LEVEL SECURITY",
+				"DROP POLICY IF EXISTS " + companyPolicyName + " ON ?",
+				"CREATE POLICY " + companyPolicyName + " ON ? USING (" + policy + ") WITH CHECK (" + policy + ")",
+			}
+			for _, statement := range statements {
+				err := tx.Exec(statement, clause.Table{Name: table}).Error
+				if err != nil {
+					return fmt.Errorf("applyRowLevelSecurity: %s: %w", table, err)
+				}
+			}
+			m.logger.Debug("Row level security applied.", zap.String("table", table))
+		}
+		return nil
+	})
+}
+
+/*
+Runs fn in a transaction with the app.company_id session setting set to the company carried by ctx.
+The ctx passed to fn carries the transaction, so GetScopedDB(ctx) joins it, and transactions started on
+it are savepoints. The transaction is committed if fn returns nil and rolled back otherwise, see AfterCommit
+for work that must wait for the commit.
+If row level security is disabled, fn is called directly without a transaction.
+*/
+func (m *Module) WithCompanyTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	companyID, ok := CompanyIDFromContext(ctx)
+	if !ok {
+		return fmt.Errorf("WithCompanyTransaction: %w", ErrNoCompanyInContext)
+	}
+
+	if !m.config.RowLevelSecurity {
+		return fn(ctx)
+	}
+
+	return m.transaction(ctx, func(ctx context.Context, tx *gorm.DB) error {
+		err := tx.Exec("SELECT set_config(?, ?, true)", companySetting, strconv.FormatUint(uint64(companyID), 10)).Error
+		if err != nil {
+			return fmt.Errorf("WithCompanyTransaction: %w", err)
+		}
+
+		return fn(ctx)
+	})
+}
+
+/*
+Runs fn in a transaction that is not bound to a company yet, for work that creates one.
+Inside fn, GetUnscopedDB(ctx) returns the transaction, and BindCompany scopes the rest of it
+to the new company so that GetScopedDB can write company rows.
+The transaction is committed if fn returns nil and rolled back otherwise, see AfterCommit.
+*/
+func (m *Module) WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	return m.transaction(ctx, func(ctx context.Context, tx *gorm.DB) error {
+		return fn(ctx)
+	})
+}
+
+/*
+Runs fn in a transaction carried by the ctx passed to it, then runs the functions registered with
+AfterCommit if it committed.
+*/
+func (m *Module) transaction(ctx context.Context, fn func(ctx context.Context, tx *gorm.DB) error) error {
+	var hooks []func()
+	err := m.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		txCtx := context.WithValue(context.WithValue(ctx, txKey{}, tx), afterCommitKey{}, &hooks)
+		return fn(txCtx, tx)
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, hook := range hooks {
+		hook()
+	}
+	return nil
+}
+
+/*
+Runs fn once the transaction carried by ctx, see WithCompanyTransaction and WithTransaction, has committed,
+or right away if ctx carries none. fn is dropped if the transaction rolls back.
+For side effects that must not happen for changes that are rolled back, e.g. emails about them.
+*/
+func AfterCommit(ctx context.Context, fn func()) {
+	hooks, ok := ctx.Value(afterCommitKey{}).(*[]func())
+	if !ok {
+		fn()
+		return
+	}
+	*hooks = append(*hooks, fn)
+}
+
+/*
+Returns a copy of ctx carrying the company, see WithCompanyID.
+If ctx carries a transaction and row level security is enabled, the app.company_id setting is set
+for the rest of the transaction.
+*/
+func (m *Module) BindCompany(ctx context.Context, companyID uint) (context.Context, error) {
+	ctx = WithCompanyID(ctx, companyID)
+
+	tx, ok := transactionFromContext(ctx)
+	if !ok || !m.config.RowLevelSecurity {
+		return ctx, nil
+	}
+
+	err := tx.Exec("SELECT set_config(?, ?, true)", companySetting, strconv.FormatUint(uint64(companyID), 10)).Error
+	if err != nil {
+		return nil, fmt.Errorf("BindCompany: %w", err)
+	}
+
+	return ctx, nil
+}
+
+func transactionFromContext(ctx context.Context) (*gorm.DB, bool) {
+	tx, ok := ctx.Value(txKey{}).(*gorm.DB)
+	return tx, ok
+}
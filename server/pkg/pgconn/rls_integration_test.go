@@ -0,0 +1,170 @@
+//go:build integration
+
+package pgconn
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	embeddedpostgres "github.com/fergusstrange/embedded-postgres"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+/*
+Runs against an ephemeral postgres binary downloaded by embedded-postgres, no container needed.
+Run with: go test -tags integration ./pkg/pgconn/
+Postgres refuses to start as root, so run as a regular user.
+*/
+
+const (
+	integrationPort     = 54329
+	integrationScope    = "rls_integration"
+	integrationAppRole  = "app"
+	integrationPassword = "postgres"
+)
+
+type rlsRecord struct {
+	ID        uint
+	CompanyID uint `gorm:"not null;index"`
+	Name      string
+}
+
+func startEmbeddedPostgres(t *testing.T) {
+	t.Helper()
+
+	runtimePath := filepath.Join(os.TempDir(), "people-matter-embedded-postgres")
+	pg := embeddedpostgres.NewDatabase(embeddedpostgres.DefaultConfig().
+		Port(integrationPort).
+		Password(integrationPassword).
+		RuntimePath(runtimePath))
+	require.NoError(t, pg.Start())
+	t.Cleanup(func() { _ = pg.Stop() })
+
+	// superusers bypass row level security, so the module connects as a regular role
+	superuser, err := gorm.Open(postgres.Open(fmt.Sprintf(
+		"host=localhost port=%d user=postgres password=%s dbname=postgres sslmode=disable",
+		integrationPort, integrationPassword,
+	)), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, superuser.Exec(fmt.Sprintf("CREATE ROLE %s LOGIN PASSWORD '%s'", integrationAppRole, integrationPassword)).Error)
+	require.NoError(t, superuser.Exec(fmt.Sprintf("GRANT ALL ON SCHEMA public TO %s", integrationAppRole)).Error)
+	sqlDB, err := superuser.DB()
+	require.NoError(t, err)
+	require.NoError(t, sqlDB.Close())
+}
+
+func newIntegrationModule(t *testing.T) *Module {
+	t.Helper()
+
+	viper.Set(integrationScope+".host", "localhost")
+	viper.Set(integrationScope+".port", integrationPort)
+	viper.Set(integrationScope+".user", integrationAppRole)
+	viper.Set(integrationScope+".password", integrationPassword)
+	viper.Set(integrationScope+".dbname", "postgres")
+	viper.Set(integrationScope+".sslmode", "disable")
+	viper.Set(integrationScope+".row_level_security", true)
+	t.Cleanup(viper.Reset)
+
+	return NewPGConn(integrationScope, zap.NewNop())
+}
+
+func TestRowLevelSecurityIntegration(t *testing.T) {
+	startEmbeddedPostgres(t)
+	m := newIntegrationModule(t)
+
+	require.NoError(t, m.ApplySchema(true, &rlsRecord{}))
+
+	companyA := WithCompanyID(context.Background(), 1)
+	companyB := WithCompanyID(context.Background(), 2)
+
+	for ctx, name := range map[context.Context]string{companyA: "a", companyB: "b"} {
+		err := m.WithCompanyTransaction(ctx, func(ctx context.Context) error {
+			db, err := m.GetScopedDB(ctx)
+			if err != nil {
+				return err
+			}
+			return db.Create(&rlsRecord{Name: name}).Error
+		})
+		require.NoError(t, err)
+	}
+
+	t.Run("CrossCompanyReadsReturnNothing", func(t *testing.T) {
+		err := m.WithCompanyTransaction(companyA, func(ctx context.Context) error {
+			db, err := m.GetScopedDB(ctx)
+			if err != nil {
+				return err
+			}
+
+			// Raw bypasses the GORM callbacks, so only the policies filter these rows
+			var names []string
+			err = db.Raw("SELECT name FROM rls_records ORDER BY name").Scan(&names).Error
+			assert.NoError(t, err)
+			assert.Equal(t, []string{"a"}, names)
+
+			var count int64
+			err = db.Raw("SELECT count(*) FROM rls_records WHERE company_id = 2").Scan(&count).Error
+			assert.NoError(t, err)
+			assert.Zero(t, count)
+
+			return nil
+		})
+		require.NoError(t, err)
+	})
+
+	t.Run("UnsetCompanyReadsNothing", func(t *testing.T) {
+		var count int64
+		err := m.GetDB().Raw("SELECT count(*) FROM rls_records").Scan(&count).Error
+		assert.NoError(t, err)
+		assert.Zero(t, count)
+	})
+
+	t.Run("CrossCompanyWritesAreRejected", func(t *testing.T) {
+		err := m.WithCompanyTransaction(companyA, func(ctx context.Context) error {
+			db, err := m.GetScopedDB(ctx)
+			if err != nil {
+				return err
+			}
+			return db.Exec("INSERT INTO rls_records (company_id, name) VALUES (2, 'c')").Error
+		})
+		assert.Error(t, err)
+	})
+
+	t.Run("AfterCommit", func(t *testing.T) {
+		var events []string
+		err := m.WithCompanyTransaction(companyA, func(ctx context.Context) error {
+			AfterCommit(ctx, func() { events = append(events, "committed") })
+			events = append(events, "returned")
+			return nil
+		})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"returned", "committed"}, events)
+
+		ran := false
+		err = m.WithCompanyTransaction(companyA, func(ctx context.Context) error {
+			db, err := m.GetScopedDB(ctx)
+			if err != nil {
+				return err
+			}
+			// savepoints of the domains commit with the outer transaction, not on their own
+			err = db.Transaction(func(tx *gorm.DB) error {
+				return tx.Create(&rlsRecord{Name: "rolled back"}).Error
+			})
+			if err != nil {
+				return err
+			}
+			AfterCommit(ctx, func() { ran = true })
+			return errors.New("rollback")
+		})
+		assert.Error(t, err)
+		assert.False(t, ran, "dropped on rollback")
+	})
+}
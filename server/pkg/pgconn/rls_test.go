@@ -0,0 +1,27 @@
+package pgconn
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAfterCommitWithoutTransaction(t *testing.T) {
+	ran := false
+	AfterCommit(context.Background(), func() { ran = true })
+	assert.True(t, ran, "nothing to wait for")
+}
+
+func TestWithCompanyTransactionWithoutRowLevelSecurity(t *testing.T) {
+	m := &Module{config: &Config{RowLevelSecurity: false}}
+	ctx := WithCompanyID(context.Background(), 1)
+
+	ran := false
+	err := m.WithCompanyTransaction(ctx, func(ctx context.Context) error {
+		AfterCommit(ctx, func() { ran = true })
+		assert.True(t, ran, "without a transaction the work is already committed")
+		return nil
+	})
+	assert.NoError(t, err)
+}
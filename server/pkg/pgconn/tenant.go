@@ -0,0 +1,124 @@
+package pgconn
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// Sentinel errors
+var (
+	ErrNoCompanyInContext = errors.New("no company in context")
+	ErrCrossCompanyWrite  = errors.New("record belongs to a different company")
+)
+
+// Name of the field that marks a model as company-owned.
+const companyIDField = "CompanyID"
+
+type companyIDKey struct{}
+
+// Returns a copy of ctx that carries the company ID used by GetScopedDB.
+func WithCompanyID(ctx context.Context, companyID uint) context.Context {
+	return context.WithValue(ctx, companyIDKey{}, companyID)
+}
+
+// Returns the company ID carried by ctx, if any.
+func CompanyIDFromContext(ctx context.Context) (uint, bool) {
+	if ctx == nil {
+		return 0, false
+	}
+	companyID, ok := ctx.Value(companyIDKey{}).(uint)
+	if !ok || companyID == 0 {
+		return 0, false
+	}
+	return companyID, true
+}
+
+/*
+Registers callbacks that scope every statement whose context carries a company ID.
+For models with a CompanyID field:
+  - queries, updates and deletes get "company_id = ?" added to their WHERE clause
+  - creates get CompanyID filled in, and fail if it is set to a different company
+
+Models without a CompanyID field, Raw and Exec are not scoped.
+*/
+func registerTenantCallbacks(db *gorm.DB) error {
+	callbacks := []error{
+		db.Callback().Query().Before("gorm:query").Register("pgconn:scope_company", scopeToCompany),
+		db.Callback().Row().Before("gorm:row").Register("pgconn:scope_company", scopeToCompany),
+		db.Callback().Update().Before("gorm:update").Register("pgconn:scope_company", scopeToCompany),
+		db.Callback().Delete().Before("gorm:delete").Register("pgconn:scope_company", scopeToCompany),
+		db.Callback().Create().Before("gorm:create").Register("pgconn:assign_company", assignCompany),
+	}
+	for _, err := range callbacks {
+		if err != nil {
+			return fmt.Errorf("registerTenantCallbacks: %w", err)
+		}
+	}
+	return nil
+}
+
+func scopeToCompany(db *gorm.DB) {
+	companyID, ok := CompanyIDFromContext(db.Statement.Context)
+	if !ok || db.Statement.Schema == nil {
+		return
+	}
+
+	field := db.Statement.Schema.LookUpField(companyIDField)
+	if field == nil {
+		return
+	}
+
+	db.Statement.AddClause(clause.Where{Exprs: []clause.Expression{
+		clause.Eq{
+			Column: clause.Column{Table: clause.CurrentTable, Name: field.DBName},
+			Value:  companyID,
+		},
+	}})
+}
+
+func assignCompany(db *gorm.DB) {
+	companyID, ok := CompanyIDFromContext(db.Statement.Context)
+	if !ok || db.Statement.Schema == nil {
+		return
+	}
+
+	field := db.Statement.Schema.LookUpField(companyIDField)
+	if field == nil {
+		return
+	}
+
+	rv := db.Statement.ReflectValue
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < rv.Len(); i++ {
+			if err := assignCompanyToRecord(db.Statement.Context, field, reflect.Indirect(rv.Index(i)), companyID); err != nil {
+				db.AddError(err)
+				return
+			}
+		}
+	case reflect.Struct:
+		if err := assignCompanyToRecord(db.Statement.Context, field, rv, companyID); err != nil {
+			db.AddError(err)
+		}
+	default:
+		// e.g. creating from a map, which cannot be checked reliably
+		db.AddError(fmt.Errorf("assignCompany: unsupported value kind %s for company scoped create", rv.Kind()))
+	}
+}
+
+func assignCompanyToRecord(ctx context.Context, field *schema.Field, rv reflect.Value, companyID uint) error {
+	value, isZero := field.ValueOf(ctx, rv)
+	if isZero {
+		return field.Set(ctx, rv, companyID)
+	}
+	if existing, ok := value.(uint); !ok || existing != companyID {
+		return fmt.Errorf("assignCompany: %w", ErrCrossCompanyWrite)
+	}
+	return nil
+}
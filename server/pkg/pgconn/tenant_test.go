@@ -0,0 +1,99 @@
+package pgconn
+
+import (
+	"context"
+	"testing"
+
+	"github.com/alsey89/people-matter/pkg/pgconn/pgtest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+type scopedRecord struct {
+	ID        uint
+	CompanyID uint
+	Name      string
+}
+
+type unscopedRecord struct {
+	ID   uint
+	Name string
+}
+
+// Opens a dry run DB with the tenant callbacks, see pgtest.NewDryRunDB.
+func newDryRunDB(t *testing.T) *gorm.DB {
+	db := pgtest.NewDryRunDB(t)
+	require.NoError(t, registerTenantCallbacks(db))
+	return db
+}
+
+func TestCompanyIDFromContext(t *testing.T) {
+	_, ok := CompanyIDFromContext(context.Background())
+	assert.False(t, ok)
+
+	_, ok = CompanyIDFromContext(WithCompanyID(context.Background(), 0))
+	assert.False(t, ok)
+
+	companyID, ok := CompanyIDFromContext(WithCompanyID(context.Background(), 7))
+	assert.True(t, ok)
+	assert.Equal(t, uint(7), companyID)
+}
+
+func TestGetScopedDBFailsClosed(t *testing.T) {
+	m := &Module{db: newDryRunDB(t)}
+
+	db, err := m.GetScopedDB(context.Background())
+	assert.ErrorIs(t, err, ErrNoCompanyInContext)
+	assert.Nil(t, db)
+}
+
+func TestScopeToCompany(t *testing.T) {
+	db := newDryRunDB(t)
+	ctx := WithCompanyID(context.Background(), 7)
+
+	t.Run("ScopesQueries", func(t *testing.T) {
+		var records []scopedRecord
+		sql := pgtest.SQL(db.WithContext(ctx).Where("name = ?", "a").Find(&records))
+		assert.Contains(t, sql, `name = 'a'`)
+		assert.Contains(t, sql, `"scoped_records"."company_id" = 7`)
+	})
+
+	t.Run("ScopesUpdatesAndDeletes", func(t *testing.T) {
+		sql := pgtest.SQL(db.WithContext(ctx).Model(&scopedRecord{}).Where("id = ?", 1).Update("name", "b"))
+		assert.Contains(t, sql, `"scoped_records"."company_id" = 7`)
+
+		sql = pgtest.SQL(db.WithContext(ctx).Where("id = ?", 1).Delete(&scopedRecord{}))
+		assert.Contains(t, sql, `"scoped_records"."company_id" = 7`)
+	})
+
+	t.Run("SkipsModelsWithoutCompany", func(t *testing.T) {
+		var records []unscopedRecord
+		assert.NotContains(t, pgtest.SQL(db.WithContext(ctx).Find(&records)), "company_id")
+	})
+
+	t.Run("SkipsUnscopedContext", func(t *testing.T) {
+		var records []scopedRecord
+		assert.NotContains(t, pgtest.SQL(db.Find(&records)), "company_id")
+	})
+}
+
+func TestAssignCompany(t *testing.T) {
+	db := newDryRunDB(t)
+	ctx := WithCompanyID(context.Background(), 7)
+
+	t.Run("AssignsMissingCompany", func(t *testing.T) {
+		records := []scopedRecord{{Name: "a"}, {Name: "b"}}
+		err := db.WithContext(ctx).Create(&records).Error
+		assert.NoError(t, err)
+		assert.Equal(t, uint(7), records[0].CompanyID)
+		assert.Equal(t, uint(7), records[1].CompanyID)
+	})
+
+	t.Run("RejectsOtherCompany", func(t *testing.T) {
+		record := scopedRecord{CompanyID: 8, Name: "a"}
+		err := db.WithContext(ctx).Create(&record).Error
+		assert.ErrorIs(t, err, ErrCrossCompanyWrite)
+	})
+}
@@ -6,6 +6,7 @@ import (
 	"log"
 	"net/http"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/alsey89/people-matter/pkg/util"
@@ -23,8 +24,14 @@ type Module struct {
 	logger *zap.Logger
 	scope  string
 	server *echo.Echo
+
+	readinessMu     sync.RWMutex
+	readinessChecks map[string]ReadinessCheck
 }
 
+// ReadinessCheck returns nil if the dependency it checks is ready to serve traffic.
+type ReadinessCheck func(ctx context.Context) error
+
 type Params struct {
 	fx.In
 	Lifecycle fx.Lifecycle
@@ -57,6 +64,8 @@ const (
 	DefaultHost           = "localhost"
 	DefaultPort           = 3001
 	DefaultServerLogLevel = "PROD"
+
+	ReadinessCheckTimeout = 5 * time.Second
 )
 
 // Custom validator for Echo using go-playground/validator.
@@ -146,12 +155,51 @@ func (m *Module) setupLogger(scope string, p Params) *zap.Logger {
 func (m *Module) setupServer() *echo.Echo {
 	e := echo.New()
 	e.Validator = &CustomValidator{validator: validator.New()}
+
+	m.readinessChecks = make(map[string]ReadinessCheck)
+	e.GET("/api/v1/health/live", m.livenessHandler)
+	e.GET("/api/v1/health/ready", m.readinessHandler)
+
 	return e
 }
 
+// Reports that the process is up, without checking any dependencies.
+func (m *Module) livenessHandler(c echo.Context) error {
+	return c.JSON(http.StatusOK, map[string]string{"status": "ok"})
+}
+
+// Runs every registered readiness check and responds with 503 if any of them fails.
+func (m *Module) readinessHandler(c echo.Context) error {
+	ctx, cancel := context.WithTimeout(c.Request().Context(), ReadinessCheckTimeout)
+	defer cancel()
+
+	m.readinessMu.RLock()
+	defer m.readinessMu.RUnlock()
+
+	status := http.StatusOK
+	results := make(map[string]string, len(m.readinessChecks))
+	for name, check := range m.readinessChecks {
+		err := check(ctx)
+		if err != nil {
+			m.logger.Warn("readiness check failed", zap.String("check", name), zap.Error(err))
+			status = http.StatusServiceUnavailable
+			// details are logged only, the endpoint is publicly reachable
+			results[name] = "failed"
+			continue
+		}
+		results[name] = "ok"
+	}
+
+	return c.JSON(status, map[string]interface{}{
+		"ready":  status == http.StatusOK,
+		"checks": results,
+	})
+}
+
 func (m *Module) onStart(context.Context) error {
 	m.logger.Info("Starting server")
 
+	m.setUpRequestIDMiddleware()
 	m.setUpCorsMiddleware()
 	m.setUpCSRFMiddleware()
 
@@ -184,6 +232,11 @@ func (m *Module) onStop(context.Context) error {
 	return nil
 }
 
+// request IDs are echoed in the response header and used as trace IDs in error responses
+func (m *Module) setUpRequestIDMiddleware() {
+	m.server.Use(middleware.RequestID())
+}
+
 func (m *Module) setUpCorsMiddleware() {
 
 	// Helper function to split and trim spaces
@@ -365,3 +418,12 @@ func (m *Module) logConfigurations() {
 func (m *Module) GetServer() *echo.Echo {
 	return m.server
 }
+
+// Registers a check that must pass for /api/v1/health/ready to report the server as ready.
+// Registering a check under an existing name replaces it.
+func (m *Module) RegisterReadinessCheck(name string, check ReadinessCheck) {
+	m.readinessMu.Lock()
+	defer m.readinessMu.Unlock()
+
+	m.readinessChecks[name] = check
+}
@@ -161,7 +161,8 @@ func (m *Module) GenerateToken(tokenScope string, additionalClaims jwt.MapClaims
 	}
 
 	claims := jwt.MapClaims{
-		"exp": jwt.NewNumericDate(time.Now().Add(time.Hour * time.Duration(scopeConfig.ExpInHours))),
+		"exp":   jwt.NewNumericDate(time.Now().Add(time.Hour * time.Duration(scopeConfig.ExpInHours))),
+		"scope": tokenScope,
 	}
 
 	for key, value := range additionalClaims {
@@ -189,7 +190,8 @@ func (m *Module) GenerateTokenAndHTTPonlyCookie(tokenScope string, additionalCla
 	}
 
 	claims := jwt.MapClaims{
-		"exp": jwt.NewNumericDate(time.Now().Add(time.Hour * time.Duration(scopeConfig.ExpInHours))),
+		"exp":   jwt.NewNumericDate(time.Now().Add(time.Hour * time.Duration(scopeConfig.ExpInHours))),
+		"scope": tokenScope,
 	}
 
 	for key, value := range additionalClaims {
@@ -237,6 +239,46 @@ func (m *Module) GetJWTMiddleware(tokenScope string) echo.MiddlewareFunc {
 	})
 }
 
+/*
+Parses and validates a JWT token string for a specific scope and returns its claims.
+Tokens signed for a different scope are rejected, even if they share a signing key.
+*/
+func (m *Module) ParseToken(tokenScope string, tokenString string) (jwt.MapClaims, error) {
+	scopeConfig, err := m.getConfigHelper(tokenScope)
+	if err != nil {
+		m.logger.Error("Config not found", zap.String("Scope:", tokenScope))
+		return nil, err
+	}
+
+	claims := jwt.MapClaims{}
+	_, err = jwt.ParseWithClaims(tokenString, claims, func(t *jwt.Token) (interface{}, error) {
+		return []byte(scopeConfig.SigningKey), nil
+	}, jwt.WithValidMethods([]string{scopeConfig.SigningMethod}))
+	if err != nil {
+		return nil, fmt.Errorf("ParseToken: %w", err)
+	}
+
+	if claims["scope"] != tokenScope {
+		return nil, fmt.Errorf("ParseToken: token scope mismatch, expected %s", tokenScope)
+	}
+
+	return claims, nil
+}
+
+// Returns an expired cookie that overwrites the HTTP-only JWT cookie on the client.
+func (m *Module) GenerateExpiredHTTPonlyCookie() *http.Cookie {
+	return &http.Cookie{
+		Name:     "jwt",
+		Value:    "",
+		Expires:  time.Unix(0, 0),
+		MaxAge:   -1,
+		Path:     "/",
+		HttpOnly: true,
+		Secure:   true,
+		SameSite: http.SameSiteNoneMode,
+	}
+}
+
 func (m *Module) getConfigHelper(scope string) (*Config, error) {
 	config, exists := m.configs[scope]
 	if !exists {
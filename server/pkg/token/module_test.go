@@ -173,6 +173,48 @@ func TestGenerateToken(t *testing.T) {
 	//todo: need to check if token is correct?
 }
 
+func TestParseToken(t *testing.T) {
+	m := Module{
+		configs: map[string]*Config{
+			"scope1": {
+				TokenLookup:   "header:Authorization",
+				SigningKey:    "my_secret",
+				SigningMethod: "HS512",
+				ExpInHours:    24,
+			},
+			"scope2": {
+				TokenLookup:   "query:token",
+				SigningKey:    "my_secret",
+				SigningMethod: "HS512",
+				ExpInHours:    24,
+			},
+		},
+		logger: zap.NewExample(),
+	}
+
+	token, err := m.GenerateToken("scope1", jwt.MapClaims{"sub": "user123"})
+	assert.NoError(t, err)
+
+	t.Run("MatchingScope", func(t *testing.T) {
+		claims, err := m.ParseToken("scope1", *token)
+		assert.NoError(t, err)
+		assert.Equal(t, "user123", claims["sub"])
+		assert.Equal(t, "scope1", claims["scope"])
+	})
+
+	t.Run("MismatchedScope", func(t *testing.T) {
+		claims, err := m.ParseToken("scope2", *token)
+		assert.Error(t, err)
+		assert.Nil(t, claims)
+	})
+
+	t.Run("TamperedToken", func(t *testing.T) {
+		claims, err := m.ParseToken("scope1", *token+"x")
+		assert.Error(t, err)
+		assert.Nil(t, claims)
+	})
+}
+
 func TestGetJWTMiddleware(t *testing.T) {
 	m := Module{
 		configs: map[string]*Config{